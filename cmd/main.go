@@ -11,8 +11,13 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	_ "go-backend/docs"
 
@@ -40,12 +45,78 @@ func main() {
 	// if err := config.DB.AutoMigrate(&models.User{}); err != nil {
 	// 	config.Logger.Fatalf("AutoMigrate failed: %v", err)
 	// }
+	// Domain events are also published on Redis for other services
+	utils.Events.Redis = config.Cache
+	// Media storage backend (local disk or S3)
+	blobs, err := utils.NewBlobStoreFromEnv()
+	if err != nil {
+		config.Logger.Fatalf("Media storage: %v", err)
+	}
+	utils.Blobs = blobs
+	if err := utils.CheckMediaURLSecret(); err != nil {
+		config.Logger.Fatalf("Media storage: %v", err)
+	}
+	// Strip, resize and convert uploaded images in the background
+	utils.MediaJobs = utils.NewMediaProcessor(config.Mongo.Database("crm"), blobs)
+	utils.MediaJobs.Start()
+	// Resumable uploads keep their progress in Redis
+	utils.Tus = utils.NewTusUploads(config.Cache, blobs)
+	jobs, stopJobs := context.WithCancel(context.Background())
+	tusExpiry := utils.StartTusExpiryJob(jobs, utils.Tus)
+	// Delete uploads no post has used for the grace period
+	utils.MediaCollector = utils.NewMediaGC(config.Mongo.Database("crm"), blobs)
+	mediaGC := utils.StartMediaGCJob(jobs, utils.MediaCollector)
+	// Post views are counted in Redis and rolled up into post_stats
+	utils.Views = utils.NewViewCounter(config.Cache, config.Mongo.Database("crm"))
+	viewRollup := utils.StartViewRollupJob(jobs, utils.Views)
+	// Trending rankings are rebuilt from recent views
+	utils.Trending = utils.NewTrendingRanker(config.Cache, config.Mongo.Database("crm"))
+	trending := utils.StartTrendingJob(jobs, utils.Trending)
+	// Home feeds follow publishing through the event bus
+	utils.Feeds = utils.NewHomeFeed(config.Cache, config.Mongo.Database("crm"))
+	utils.Feeds.Listen(utils.Events)
+	// Posts are screened against the admin-managed content filter
+	utils.Content = utils.NewContentPolicy(config.Mongo.Database("crm"), config.PublicBaseURL())
+
 	// Start post scheduler
-	utils.StartPostScheduler(config.Mongo.Database("crm"))
+	scheduler := utils.NewPostScheduler(config.Mongo.Database("crm"))
+	scheduler.Start()
+	// Nightly author leaderboard
+	leaderboard := utils.StartLeaderboardJob(jobs, config.Mongo.Database("crm"), &utils.RedisLock{Client: config.Cache})
+	// Auto-resolve trade posts when a market data source is configured
+	var tradeResolver <-chan struct{}
+	if dir := os.Getenv("MARKET_DATA_DIR"); dir != "" {
+		tradeResolver = utils.StartTradeResolver(jobs, config.Mongo.Database("crm"), utils.NewCSVMarketData(dir), &utils.RedisLock{Client: config.Cache})
+	}
 
 	// Initialize all routes
 	router := routes.InitRoutes()
 
-	log.Println("Server running on port 8080")
-	log.Fatal(http.ListenAndServe(":8080", router))
+	server := &http.Server{Addr: ":8080", Handler: router}
+	go func() {
+		log.Println("Server running on port 8080")
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	// Stop accepting requests, then let background jobs finish their run
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+	log.Println("Shutting down...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("HTTP shutdown: %v", err)
+	}
+	scheduler.Stop()
+	utils.MediaJobs.Stop()
+	stopJobs()
+	for _, done := range []<-chan struct{}{tusExpiry, mediaGC, viewRollup, trending, leaderboard, tradeResolver} {
+		if done != nil {
+			<-done
+		}
+	}
 }
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"go-backend/config"
+	"go-backend/models"
+	"go-backend/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// runBackfill gives posts without a slug and users without a handle one, as
+// the API does for new documents, so that posts from before permalinks can be
+// reached by slug. It is safe to run again.
+func runBackfill(args []string) error {
+	flags := flag.NewFlagSet("backfill", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "report what would change without writing anything")
+	flags.Parse(args)
+	if flags.NArg() != 0 {
+		return errUsage
+	}
+
+	connect()
+	ctx := context.Background()
+
+	users, err := backfillHandles(ctx, *dryRun)
+	if err != nil {
+		return err
+	}
+	posts, err := backfillSlugs(ctx, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	prefix := ""
+	if *dryRun {
+		prefix = "dry run: "
+	}
+	fmt.Printf("%s%d handles, %d slugs\n", prefix, users, posts)
+	return nil
+}
+
+func backfillHandles(ctx context.Context, dryRun bool) (int, error) {
+	missing := bson.M{"$or": []bson.M{{"handle": bson.M{"$exists": false}}, {"handle": ""}}}
+	cursor, err := config.UserCollection.Find(ctx, missing)
+	if err != nil {
+		return 0, err
+	}
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, user := range users {
+		// Leave users that were given a handle in the meantime alone.
+		filter := bson.M{"_id": user.ID}
+		for k, v := range missing {
+			filter[k] = v
+		}
+		err := utils.WriteWithSlug(ctx, func(ctx context.Context) (string, error) {
+			return utils.UniqueHandle(ctx, &user)
+		}, func(handle string) error {
+			fmt.Printf("%-9s %s @%s\n", "handle", user.Email, handle)
+			if dryRun {
+				return nil
+			}
+			_, err := config.UserCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"handle": handle}, "$inc": bson.M{"version": 1}})
+			return err
+		})
+		if err != nil {
+			return count, fmt.Errorf("user %s: %w", user.ID.Hex(), err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+func backfillSlugs(ctx context.Context, dryRun bool) (int, error) {
+	missing := bson.M{"$or": []bson.M{{"slug": bson.M{"$exists": false}}, {"slug": ""}}}
+	cursor, err := config.PostCollection.Find(ctx, missing, options.Find().SetProjection(bson.M{"title": 1}))
+	if err != nil {
+		return 0, err
+	}
+	var posts []models.Post
+	if err := cursor.All(ctx, &posts); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, post := range posts {
+		// Leave posts that were given a slug in the meantime alone.
+		filter := bson.M{"_id": post.ID}
+		for k, v := range missing {
+			filter[k] = v
+		}
+		err := utils.WriteWithSlug(ctx, func(ctx context.Context) (string, error) {
+			return utils.UniquePostSlug(ctx, post.Title, post.ID)
+		}, func(slug string) error {
+			fmt.Printf("%-9s %s %s\n", "slug", post.ID.Hex(), slug)
+			if dryRun {
+				return nil
+			}
+			_, err := config.PostCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"slug": slug}, "$inc": bson.M{"version": 1}})
+			return err
+		})
+		if err != nil {
+			return count, fmt.Errorf("post %s: %w", post.ID.Hex(), err)
+		}
+		count++
+	}
+	return count, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"go-backend/config"
+	"go-backend/models"
+	"go-backend/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// runExport writes posts as markdown files named after their slugs into a
+// gzipped tarball, in the format runImport reads.
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	author := flags.String("author", "", "only export posts by this user: ID, handle or email")
+	status := flags.String("status", "", "only export posts with this status, e.g. published")
+	out := flags.String("out", "posts.tar.gz", "tarball to write, or - for stdout")
+	flags.Parse(args)
+	if flags.NArg() != 0 {
+		return errUsage
+	}
+
+	connect()
+	ctx := context.Background()
+	filter := bson.M{}
+	if *author != "" {
+		user, err := findAuthor(ctx, *author)
+		if err != nil {
+			return err
+		}
+		filter["author_id"] = user.ID
+	}
+	if *status != "" {
+		filter["status"] = *status
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	n, err := exportPosts(ctx, w, filter)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "exported %d posts\n", n)
+	return nil
+}
+
+func exportPosts(ctx context.Context, w io.Writer, filter bson.M) (int, error) {
+	cursor, err := config.PostCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	authors := map[primitive.ObjectID]string{}
+	n := 0
+	for cursor.Next(ctx) {
+		var post models.Post
+		if err := cursor.Decode(&post); err != nil {
+			return n, err
+		}
+		author, ok := authors[post.AuthorID]
+		if !ok {
+			var user models.User
+			if err := config.UserCollection.FindOne(ctx, bson.M{"_id": post.AuthorID}).Decode(&user); err == nil {
+				author = authorRef(user)
+			}
+			authors[post.AuthorID] = author
+		}
+
+		data, err := utils.NewPostDocument(post, author).Markdown()
+		if err != nil {
+			return n, fmt.Errorf("post %s: %w", post.ID.Hex(), err)
+		}
+		name := post.Slug
+		if name == "" {
+			name = post.ID.Hex()
+		}
+		err = tw.WriteHeader(&tar.Header{
+			Name:    "posts/" + name + ".md",
+			Mode:    0o644,
+			Size:    int64(len(data)),
+			ModTime: post.UpdatedAt,
+		})
+		if err != nil {
+			return n, err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if err := cursor.Err(); err != nil {
+		return n, err
+	}
+	if err := tw.Close(); err != nil {
+		return n, err
+	}
+	return n, gz.Close()
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go-backend/config"
+	"go-backend/models"
+	"go-backend/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type importOptions struct {
+	dryRun  bool
+	publish bool
+	now     time.Time
+}
+
+// runImport upserts every markdown file under a directory by slug. A file
+// that fails is reported and skipped; the rest are still imported.
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	author := flags.String("author", "", "author of files without an author in their front matter: user ID, handle or email")
+	dryRun := flags.Bool("dry-run", false, "report what would change without writing anything")
+	publish := flags.Bool("publish", false, "publish new posts, or schedule those with a future scheduled_at, instead of creating drafts")
+	flags.Parse(args)
+	if flags.NArg() != 1 || *author == "" {
+		return errUsage
+	}
+	dir := flags.Arg(0)
+
+	connect()
+	if !*dryRun {
+		connectFeeds()
+	}
+	ctx := context.Background()
+	defaultAuthor, err := findAuthor(ctx, *author)
+	if err != nil {
+		return err
+	}
+	authors := map[string]models.User{"": defaultAuthor}
+	opts := importOptions{dryRun: *dryRun, publish: *publish, now: time.Now()}
+
+	counts := map[string]int{}
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !isMarkdown(path) {
+			return nil
+		}
+		result, err := importFile(ctx, path, authors, opts)
+		if err != nil {
+			counts["failed"]++
+			fmt.Fprintf(os.Stderr, "%-9s %s: %v\n", "failed", path, err)
+			return nil
+		}
+		counts[result]++
+		fmt.Printf("%-9s %s\n", result, path)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	prefix := ""
+	if opts.dryRun {
+		prefix = "dry run: "
+	}
+	fmt.Printf("%s%d created, %d updated, %d unchanged, %d failed\n",
+		prefix, counts["created"], counts["updated"], counts["unchanged"], counts["failed"])
+	if counts["failed"] > 0 {
+		return fmt.Errorf("%d files failed to import", counts["failed"])
+	}
+	return nil
+}
+
+func isMarkdown(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".md" || ext == ".markdown"
+}
+
+// importFile creates or updates the post of one file and says which it did.
+// authors caches the users named in front matter.
+func importFile(ctx context.Context, path string, authors map[string]models.User, opts importOptions) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	doc, err := utils.ParsePostMarkdown(data)
+	if err != nil {
+		return "", err
+	}
+	if doc.Slug == "" {
+		doc.Slug = utils.Slugify(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	}
+	if doc.Slug == "" {
+		return "", errors.New("no slug in front matter or file name")
+	}
+	author, ok := authors[doc.Author]
+	if !ok {
+		if author, err = findAuthor(ctx, doc.Author); err != nil {
+			return "", err
+		}
+		authors[doc.Author] = author
+	}
+
+	var existing models.Post
+	err = config.PostCollection.FindOne(ctx, bson.M{"slug": doc.Slug}).Decode(&existing)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "created", createPost(ctx, doc, author, opts)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if existing.AuthorID != author.ID {
+		return "", fmt.Errorf("slug %s belongs to a post by another author", doc.Slug)
+	}
+	update, err := doc.Update(existing)
+	if err != nil {
+		return "", err
+	}
+	if update == nil {
+		return "unchanged", nil
+	}
+	if opts.dryRun {
+		return "updated", nil
+	}
+	update["$set"].(bson.M)["updated_at"] = opts.now
+	// The version guard keeps edits made through the API meanwhile.
+	result, err := config.PostCollection.UpdateOne(ctx,
+		bson.M{"_id": existing.ID, "version": utils.VersionFilter(existing.Version)}, update)
+	if err != nil {
+		return "", err
+	}
+	if result.MatchedCount == 0 {
+		return "", errors.New("post was changed while importing, try again")
+	}
+	if e, ok := visibilityEvent(existing, doc.Visibility, opts.now); ok {
+		announce(ctx, e)
+	}
+	return "updated", nil
+}
+
+// createPost inserts doc as a new post by author: a draft, or with publish
+// set, a published or scheduled post.
+func createPost(ctx context.Context, doc utils.PostDocument, author models.User, opts importOptions) error {
+	n, err := config.PostCollection.CountDocuments(ctx, bson.M{"slug_history": doc.Slug}, options.Count().SetLimit(1))
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return fmt.Errorf("slug %s redirects to another post", doc.Slug)
+	}
+
+	post := models.Post{
+		ID:          primitive.NewObjectID(),
+		AuthorID:    author.ID,
+		Title:       doc.Title,
+		Slug:        doc.Slug,
+		Content:     doc.Content,
+		Tags:        doc.Tags,
+		Visibility:  doc.Visibility,
+		Type:        doc.Type,
+		Status:      models.PostStatusDraft,
+		ScheduledAt: doc.ScheduledAt,
+		CreatedAt:   opts.now,
+		UpdatedAt:   opts.now,
+	}
+	if doc.Type == models.PostTypeTrade {
+		if doc.Trade == nil {
+			return errors.New("trade posts require a trade setup")
+		}
+		if post.Trade, err = doc.Trade.NewTrade(); err != nil {
+			return err
+		}
+	}
+
+	if opts.publish {
+		change := models.StatusChange{
+			Action: models.ActionPublish,
+			From:   models.PostStatusDraft,
+			To:     models.PostStatusPublished,
+			By:     author.ID,
+			Role:   "system",
+			Reason: "imported",
+			At:     opts.now,
+		}
+		if post.ScheduledAt != nil && post.ScheduledAt.After(opts.now) {
+			change.Action, change.To = models.ActionSchedule, models.PostStatusScheduled
+		} else {
+			published := opts.now
+			if doc.PublishedAt != nil {
+				published = *doc.PublishedAt
+			}
+			post.PublishedAt = &published
+		}
+		post.Status = change.To
+		post.StatusHistory = []models.StatusChange{change}
+	}
+
+	if opts.dryRun {
+		return nil
+	}
+	if _, err := config.PostCollection.InsertOne(ctx, post); err != nil {
+		return err
+	}
+	// Scheduled posts are announced by the API's scheduler when they go live.
+	if post.Status == models.PostStatusPublished {
+		announce(ctx, utils.PostPublishedEvent(post, *post.PublishedAt))
+	}
+	return nil
+}
+
+// visibilityEvent is the event for a published post whose visibility an
+// import changes to or from private, which takes it out of or brings it back
+// into readers' feeds.
+func visibilityEvent(post models.Post, visibility models.Visibility, now time.Time) (utils.Event, bool) {
+	wasPrivate, private := post.Visibility == models.VisibilityPrivate, visibility == models.VisibilityPrivate
+	if post.Status != models.PostStatusPublished || wasPrivate == private {
+		return utils.Event{}, false
+	}
+	post.Visibility = visibility
+	if private {
+		return utils.PostUnpublishedEvent(post, string(models.VisibilityPrivate), now), true
+	}
+	at := now
+	if post.PublishedAt != nil {
+		at = *post.PublishedAt
+	}
+	return utils.PostPublishedEvent(post, at), true
+}
@@ -0,0 +1,138 @@
+// Command sitegen renders public published posts into a static site, for a
+// read-only mirror served from a CDN without exposing the API:
+//
+//	sitegen [-out public] [-base-url https://ideas.example.com] [-templates dir] [-full]
+//
+// Each post, tag and author gets a page, next to a paged index, a sitemap
+// and RSS, Atom and JSON feeds. Only pages whose content changed since the
+// last run in the same output directory are written again, and pages of
+// posts that are no longer public are removed, so the directory can be
+// synced to the CDN as is.
+package main
+
+import (
+	"context"
+	"embed"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"time"
+
+	"go-backend/config"
+	"go-backend/models"
+	"go-backend/utils"
+
+	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//go:embed templates/*.html
+var defaultTemplates embed.FS
+
+func init() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("⚠️ .env file not found, using system environment variables")
+	}
+}
+
+func main() {
+	out := flag.String("out", "public", "directory to write the site to")
+	baseURL := flag.String("base-url", "", "origin the site is served from (default SITE_BASE_URL)")
+	templateDir := flag.String("templates", "", "directory with post.html and list.html replacing the built-in templates")
+	title := flag.String("title", "Latest posts", "site title")
+	description := flag.String("description", "Public trade ideas and posts", "site description")
+	full := flag.Bool("full", false, "rewrite every page, e.g. after changing the output by hand")
+	flag.Parse()
+
+	if err := run(*out, *baseURL, *templateDir, *title, *description, *full); err != nil {
+		fmt.Fprintln(os.Stderr, "sitegen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(out, baseURL, templateDir, title, description string, full bool) error {
+	var templates fs.FS
+	if templateDir != "" {
+		templates = os.DirFS(templateDir)
+	} else {
+		var err error
+		if templates, err = fs.Sub(defaultTemplates, "templates"); err != nil {
+			return err
+		}
+	}
+	if baseURL == "" {
+		baseURL = config.SiteBaseURL()
+	}
+	site, err := utils.NewStaticSite(title, description, baseURL, templates)
+	if err != nil {
+		return err
+	}
+
+	config.InitLogger()
+	config.InitDB()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	posts, err := loadPosts(ctx)
+	if err != nil {
+		return err
+	}
+	build, err := utils.BuildSite(out, site.Pages(posts), full)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d posts: %d pages written, %d unchanged, %d removed\n",
+		len(posts), build.Written, build.Unchanged, build.Removed)
+	return nil
+}
+
+// loadPosts reads the posts anyone may read, with their authors and
+// co-authors.
+func loadPosts(ctx context.Context) ([]utils.SitePost, error) {
+	filter := bson.M{"status": models.PostStatusPublished, "visibility": models.VisibilityPublic, "hidden": bson.M{"$ne": true}}
+	cursor, err := config.PostCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var posts []models.Post
+	if err := cursor.All(ctx, &posts); err != nil {
+		return nil, err
+	}
+
+	ids := []primitive.ObjectID{}
+	for _, post := range posts {
+		ids = append(ids, post.AuthorID)
+		ids = append(ids, post.CoAuthorIDs()...)
+	}
+	authors := map[primitive.ObjectID]utils.SiteAuthor{}
+	cursor, err = config.UserCollection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		authors[user.ID] = utils.SiteAuthor{ID: user.ID, Name: user.Name, Handle: user.Handle}
+	}
+	author := func(id primitive.ObjectID) utils.SiteAuthor {
+		if a, ok := authors[id]; ok {
+			return a
+		}
+		return utils.SiteAuthor{ID: id, Name: "Unknown author"}
+	}
+
+	site := make([]utils.SitePost, 0, len(posts))
+	for _, post := range posts {
+		p := utils.SitePost{Post: post, Author: author(post.AuthorID)}
+		for _, id := range post.CoAuthorIDs() {
+			p.CoAuthors = append(p.CoAuthors, author(id))
+		}
+		site = append(site, p)
+	}
+	return site, nil
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// PublicBaseURL is the externally visible origin used when building absolute
+// links (feeds, permalinks, signed URLs). Set PUBLIC_BASE_URL in production.
+func PublicBaseURL() string {
+	base := os.Getenv("PUBLIC_BASE_URL")
+	if base == "" {
+		base = "http://localhost:8080"
+	}
+	return strings.TrimRight(base, "/")
+}
+
+// SiteBaseURL is the origin the static mirror of public posts is served
+// from. Set SITE_BASE_URL to the CDN; it defaults to PublicBaseURL.
+func SiteBaseURL() string {
+	if base := os.Getenv("SITE_BASE_URL"); base != "" {
+		return strings.TrimRight(base, "/")
+	}
+	return PublicBaseURL()
+}
+
+// TrustedProxies are the load balancers whose X-Forwarded-For and X-Real-IP
+// headers are believed. Set TRUSTED_PROXIES to a comma separated list of IPs
+// or CIDR ranges; without it the headers are ignored.
+func TrustedProxies() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, entry := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		if _, n, err := net.ParseCIDR(entry); err == nil {
+			nets = append(nets, n)
+		}
+	}
+	return nets
+}
+
+// ModerationHideThreshold is how many readers must report a post before it is
+// hidden pending review. Set MODERATION_HIDE_THRESHOLD to change it.
+func ModerationHideThreshold() int {
+	if n, err := strconv.Atoi(os.Getenv("MODERATION_HIDE_THRESHOLD")); err == nil && n > 0 {
+		return n
+	}
+	return 5
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -13,6 +14,14 @@ import (
 var Mongo *mongo.Client
 var UserCollection *mongo.Collection
 var PostCollection *mongo.Collection
+var MediaCollection *mongo.Collection
+var BookmarkCollection *mongo.Collection
+var ReadingListCollection *mongo.Collection
+var FollowCollection *mongo.Collection
+var ReportCollection *mongo.Collection
+var ModerationCollection *mongo.Collection
+var SeriesCollection *mongo.Collection
+var CollectionCollection *mongo.Collection
 
 func InitDB() {
     uri := os.Getenv("MONGO_URI")
@@ -31,5 +40,136 @@ func InitDB() {
     Mongo = client
     UserCollection = Mongo.Database("crm").Collection("users")
     PostCollection = Mongo.Database("crm").Collection("posts")
+    MediaCollection = Mongo.Database("crm").Collection("media")
+    BookmarkCollection = Mongo.Database("crm").Collection("bookmarks")
+    ReadingListCollection = Mongo.Database("crm").Collection("reading_lists")
+    FollowCollection = Mongo.Database("crm").Collection("follows")
+    ReportCollection = Mongo.Database("crm").Collection("reports")
+    ModerationCollection = Mongo.Database("crm").Collection("moderation_cases")
+    SeriesCollection = Mongo.Database("crm").Collection("series")
+    CollectionCollection = Mongo.Database("crm").Collection("collections")
+    ensureIndexes(ctx)
     Logger.Info("📦 Connected to MongoDB!")
 }
+
+// ensureIndexes creates the indexes lookups and uniqueness checks rely on.
+// Failures are logged, not fatal, so the API still boots against a locked
+// down database.
+func ensureIndexes(ctx context.Context) {
+	_, err := PostCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "slug", Value: 1}}, Options: options.Index().SetUnique(true).SetSparse(true)},
+		{Keys: bson.D{{Key: "slug_history", Value: 1}}},
+		{Keys: bson.D{{Key: "media_urls", Value: 1}}},
+	})
+	if err != nil {
+		Logger.Warnf("Could not create post indexes: %v", err)
+	}
+
+	_, err = UserCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "handle", Value: 1}},
+		Options: options.Index().SetUnique(true).SetSparse(true),
+	})
+	if err != nil {
+		Logger.Warnf("Could not create user indexes: %v", err)
+	}
+
+	_, err = MediaCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "owner_id", Value: 1}, {Key: "sha256", Value: 1}}},
+		{Keys: bson.D{{Key: "key", Value: 1}}},
+		{Keys: bson.D{{Key: "url", Value: 1}}},
+		{Keys: bson.D{{Key: "variants.key", Value: 1}}},
+		{Keys: bson.D{{Key: "variants.url", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}}},
+		{Keys: bson.D{{Key: "post_ids", Value: 1}}},
+		{Keys: bson.D{{Key: "unreferenced_at", Value: 1}}, Options: options.Index().SetSparse(true)},
+	})
+	if err != nil {
+		Logger.Warnf("Could not create media indexes: %v", err)
+	}
+
+	_, err = BookmarkCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "post_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "post_id", Value: 1}}},
+	})
+	if err != nil {
+		Logger.Warnf("Could not create bookmark indexes: %v", err)
+	}
+
+	_, err = ReadingListCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "owner_id", Value: 1}}},
+		{Keys: bson.D{{Key: "share_token", Value: 1}}, Options: options.Index().SetUnique(true).SetSparse(true)},
+		{Keys: bson.D{{Key: "post_ids", Value: 1}}},
+	})
+	if err != nil {
+		Logger.Warnf("Could not create reading list indexes: %v", err)
+	}
+
+	_, err = Mongo.Database("crm").Collection("post_stats").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "post_id", Value: 1}, {Key: "granularity", Value: 1}, {Key: "start", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "granularity", Value: 1}, {Key: "start", Value: 1}}},
+	})
+	if err != nil {
+		Logger.Warnf("Could not create post stats indexes: %v", err)
+	}
+
+	_, err = FollowCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "follower_id", Value: 1}, {Key: "author_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "author_id", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "follower_id", Value: 1}, {Key: "_id", Value: -1}}},
+	})
+	if err != nil {
+		Logger.Warnf("Could not create follow indexes: %v", err)
+	}
+
+	_, err = PostCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "author_id", Value: 1}, {Key: "status", Value: 1}, {Key: "published_at", Value: -1}},
+	})
+	if err != nil {
+		Logger.Warnf("Could not create feed index: %v", err)
+	}
+
+	_, err = ReportCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "case_id", Value: 1}, {Key: "reporter_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "post_id", Value: 1}}},
+	})
+	if err != nil {
+		Logger.Warnf("Could not create report indexes: %v", err)
+	}
+
+	// One unresolved case per post.
+	_, err = ModerationCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "post_id", Value: 1}}, Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"active": true})},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "report_count", Value: -1}, {Key: "created_at", Value: 1}}},
+	})
+	if err != nil {
+		Logger.Warnf("Could not create moderation indexes: %v", err)
+	}
+
+	_, err = PostCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "collaborators.user_id", Value: 1}},
+	})
+	if err != nil {
+		Logger.Warnf("Could not create collaborator index: %v", err)
+	}
+
+	// A post belongs to at most one series. Empty series are left out, they
+	// would all collide on the index. It replaces a plain post_ids index.
+	SeriesCollection.Indexes().DropOne(ctx, "post_ids_1")
+	_, err = SeriesCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "author_id", Value: 1}, {Key: "updated_at", Value: -1}}},
+		{Keys: bson.D{{Key: "post_ids", Value: 1}}, Options: options.Index().SetName("post_ids_unique").SetUnique(true).
+			SetPartialFilterExpression(bson.M{"post_ids.0": bson.M{"$exists": true}})},
+	})
+	if err != nil {
+		Logger.Warnf("Could not create series indexes: %v", err)
+	}
+
+	_, err = CollectionCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "owner_id", Value: 1}, {Key: "updated_at", Value: -1}}},
+		{Keys: bson.D{{Key: "post_ids", Value: 1}}},
+	})
+	if err != nil {
+		Logger.Warnf("Could not create collection indexes: %v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"go-backend/config"
 	"go-backend/models"
+	"go-backend/utils"
 	"net/http"
 	"strconv"
 	"strings"
@@ -146,6 +147,7 @@ func GetUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set("ETag", utils.VersionETag(user.Version))
 	json.NewEncoder(w).Encode(user)
 }
 
@@ -178,6 +180,11 @@ func CreateUser(w http.ResponseWriter, r *http.Request) {
 	}
 	user.Password = string(hashedPassword)
 
+	if user.Handle, err = utils.UniqueHandle(r.Context(), &user); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	// Insert user
 	result, err := config.UserCollection.InsertOne(r.Context(), user)
 	if err != nil {
@@ -224,13 +231,18 @@ func UpdateUser(w http.ResponseWriter, r *http.Request) {
 		updateFields["email"] = updates.Email
 	}
 	if updates.Role != "" {
+		updates.Role = strings.ToLower(updates.Role)
+		if !models.ValidRole(updates.Role) {
+			http.Error(w, "role must be one of "+strings.Join(models.Roles, ", "), http.StatusBadRequest)
+			return
+		}
 		updateFields["role"] = updates.Role
 	}
 
 	result, err := config.UserCollection.UpdateOne(
 		r.Context(),
 		bson.M{"_id": id},
-		bson.M{"$set": updateFields},
+		bson.M{"$set": updateFields, "$inc": bson.M{"version": 1}},
 	)
 
 	if err != nil {
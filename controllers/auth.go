@@ -15,6 +15,7 @@ import (
 
 	"go-backend/config"
 	"go-backend/models"
+	"go-backend/utils"
 )
 
 var jwtSecret = []byte(os.Getenv("JWT_SECRET"))
@@ -61,6 +62,11 @@ func RegisterUser(w http.ResponseWriter, r *http.Request) {
 	user.Password = string(hashedPwd)
 	user.CreatedAt = time.Now()
 
+	if user.Handle, err = utils.UniqueHandle(context.TODO(), &user); err != nil {
+		http.Error(w, "Could not create user", http.StatusInternalServerError)
+		return
+	}
+
 	res, err := config.UserCollection.InsertOne(context.TODO(), user)
 	if err != nil {
 		http.Error(w, "Could not create user", http.StatusInternalServerError)
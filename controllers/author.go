@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"encoding/json"
+	"go-backend/config"
+	"go-backend/models"
+	"go-backend/utils"
+	"net/http"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// parseDateParam accepts RFC3339 timestamps or plain YYYY-MM-DD dates.
+// endOfDay moves plain dates to the last instant of that day.
+func parseDateParam(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return &t, nil
+}
+
+// GetAuthorPerformance godoc
+// @Summary Author trade performance
+// @Description Win rate, average R-multiple, expectancy, max drawdown and per-ticker breakdown of an author's closed trade posts
+// @Tags Authors
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Author ID"
+// @Param from query string false "Start date (YYYY-MM-DD or RFC3339)"
+// @Param to query string false "End date (YYYY-MM-DD or RFC3339)"
+// @Success 200 {object} models.AuthorPerformance
+// @Failure 400 {string} string "Invalid input"
+// @Failure 500 {string} string "Internal error"
+// @Router /api/v1/authors/{id}/performance [get]
+func GetAuthorPerformance(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	authorID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid author ID", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	from, err := parseDateParam(query.Get("from"), false)
+	if err != nil {
+		http.Error(w, "Invalid from date", http.StatusBadRequest)
+		return
+	}
+	to, err := parseDateParam(query.Get("to"), true)
+	if err != nil {
+		http.Error(w, "Invalid to date", http.StatusBadRequest)
+		return
+	}
+	if from != nil && to != nil && to.Before(*from) {
+		http.Error(w, "from must be before to", http.StatusBadRequest)
+		return
+	}
+
+	filter := utils.ClosedTradesFilter(from, to)
+	filter["author_id"] = authorID
+
+	cursor, err := config.PostCollection.Find(r.Context(), filter)
+	if err != nil {
+		http.Error(w, "Failed to fetch trades", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(r.Context())
+
+	posts := []models.Post{}
+	if err := cursor.All(r.Context(), &posts); err != nil {
+		http.Error(w, "Failed to parse trades", http.StatusInternalServerError)
+		return
+	}
+
+	perf := utils.ComputePerformance(posts)
+	perf.AuthorID = authorID
+	perf.From = from
+	perf.To = to
+
+	json.NewEncoder(w).Encode(perf)
+}
+
+// GetAuthorLeaderboard godoc
+// @Summary Author leaderboard
+// @Description Returns the nightly precomputed ranking of authors by trade expectancy
+// @Tags Authors
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.Leaderboard
+// @Failure 503 {string} string "Leaderboard not ready"
+// @Router /api/v1/authors/leaderboard [get]
+func GetAuthorLeaderboard(w http.ResponseWriter, r *http.Request) {
+	data, err := config.Cache.Get(r.Context(), utils.LeaderboardCacheKey).Bytes()
+	if err == redis.Nil {
+		http.Error(w, "Leaderboard not ready yet", http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to load leaderboard", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
@@ -0,0 +1,680 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"go-backend/config"
+	"go-backend/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Bookmarks and reading lists only ever show posts the reader may currently
+// see. Posts that are unpublished, made private or deleted drop out of the
+// responses instead of failing them.
+
+const maxReadingListName = 100
+
+// findReadablePost loads the published post in the URL if the caller may see
+// it, writing the error response itself when it cannot.
+func findReadablePost(w http.ResponseWriter, r *http.Request, param string) (*models.Post, bool) {
+	postID, err := primitive.ObjectIDFromHex(mux.Vars(r)[param])
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	var post models.Post
+	err = config.PostCollection.FindOne(r.Context(), bson.M{"_id": postID, "status": "published"}).Decode(&post)
+	if err != nil {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return nil, false
+	}
+	userID, role := requestViewer(r)
+	if !post.VisibleTo(userID, role) {
+		if post.Visibility == models.VisibilityPremium && !post.Hidden {
+			http.Error(w, "This post is for premium members", http.StatusForbidden)
+			return nil, false
+		}
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return nil, false
+	}
+	return &post, true
+}
+
+// readableFilter matches the published posts userID may see.
+func readableFilter(userID primitive.ObjectID, role string) bson.M {
+	filter := models.VisibilityFilter(userID, role)
+	filter["status"] = "published"
+	return filter
+}
+
+// forgetPost removes a deleted post from bookmarks, reading lists, series
+// and collections.
+func forgetPost(ctx context.Context, postID primitive.ObjectID) {
+	if _, err := config.BookmarkCollection.DeleteMany(ctx, bson.M{"post_id": postID}); err != nil {
+		config.Logger.Warnf("Failed to remove bookmarks of post %s: %v", postID.Hex(), err)
+	}
+	lists := map[string]*mongo.Collection{
+		"reading lists": config.ReadingListCollection,
+		"series":        config.SeriesCollection,
+		"collections":   config.CollectionCollection,
+	}
+	for name, coll := range lists {
+		_, err := coll.UpdateMany(ctx, bson.M{"post_ids": postID}, bson.M{"$pull": bson.M{"post_ids": postID}})
+		if err != nil {
+			config.Logger.Warnf("Failed to remove post %s from %s: %v", postID.Hex(), name, err)
+		}
+	}
+}
+
+// BookmarkPost godoc
+// @Summary Bookmark a post
+// @Description Saves a published post the caller may read. Bookmarking twice is a no-op.
+// @Tags Bookmarks
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Post ID"
+// @Success 200 {string} string "Post bookmarked"
+// @Failure 403 {string} string "This post is for premium members"
+// @Failure 404 {string} string "Post not found"
+// @Router /api/v1/posts/{id}/bookmark [put]
+func BookmarkPost(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	post, ok := findReadablePost(w, r, "id")
+	if !ok {
+		return
+	}
+	userID, _ := requestViewer(r)
+
+	_, err := config.BookmarkCollection.UpdateOne(r.Context(),
+		bson.M{"user_id": userID, "post_id": post.ID},
+		bson.M{"$setOnInsert": bson.M{"created_at": time.Now()}},
+		options.Update().SetUpsert(true))
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		http.Error(w, "Failed to bookmark post", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Post bookmarked"})
+}
+
+// RemoveBookmark godoc
+// @Summary Remove a bookmark
+// @Tags Bookmarks
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Post ID"
+// @Success 200 {string} string "Bookmark removed"
+// @Failure 400 {string} string "Invalid post ID"
+// @Router /api/v1/posts/{id}/bookmark [delete]
+func RemoveBookmark(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	postID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := requestViewer(r)
+
+	// Works for posts that no longer exist, so stale bookmarks can be cleared.
+	if _, err := config.BookmarkCollection.DeleteOne(r.Context(), bson.M{"user_id": userID, "post_id": postID}); err != nil {
+		http.Error(w, "Failed to remove bookmark", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Bookmark removed"})
+}
+
+// ListBookmarks godoc
+// @Summary List my bookmarks
+// @Description Newest first. Bookmarks of posts the caller can no longer read are left out.
+// @Tags Bookmarks
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {array} models.Bookmark
+// @Failure 500 {string} string "Failed to fetch bookmarks"
+// @Router /api/v1/users/me/bookmarks [get]
+func ListBookmarks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	page := 1
+	limit := 20
+	if p := query.Get("page"); p != "" {
+		if val, err := strconv.Atoi(p); err == nil && val > 0 {
+			page = val
+		}
+	}
+	if l := query.Get("limit"); l != "" {
+		if val, err := strconv.Atoi(l); err == nil && val > 0 && val <= 100 {
+			limit = val
+		}
+	}
+
+	userID, role := requestViewer(r)
+	postMatch := readableFilter(userID, role)
+	postMatch["$expr"] = bson.M{"$eq": bson.A{"$_id", "$$post_id"}}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": userID}}},
+		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":     "posts",
+			"let":      bson.M{"post_id": "$post_id"},
+			"pipeline": bson.A{bson.M{"$match": postMatch}},
+			"as":       "post",
+		}}},
+		{{Key: "$unwind", Value: "$post"}},
+		{{Key: "$facet", Value: bson.M{
+			"items": bson.A{bson.M{"$skip": (page - 1) * limit}, bson.M{"$limit": limit}},
+			"total": bson.A{bson.M{"$count": "n"}},
+		}}},
+	}
+	cursor, err := config.BookmarkCollection.Aggregate(r.Context(), pipeline)
+	if err != nil {
+		http.Error(w, "Failed to fetch bookmarks", http.StatusInternalServerError)
+		return
+	}
+	var result []struct {
+		Items []models.Bookmark `bson:"items"`
+		Total []struct {
+			N int64 `bson:"n"`
+		} `bson:"total"`
+	}
+	if err := cursor.All(r.Context(), &result); err != nil || len(result) == 0 {
+		http.Error(w, "Failed to parse bookmarks", http.StatusInternalServerError)
+		return
+	}
+
+	bookmarks := result[0].Items
+	if bookmarks == nil {
+		bookmarks = []models.Bookmark{}
+	}
+	posts := make([]models.Post, len(bookmarks))
+	for i := range bookmarks {
+		posts[i] = *bookmarks[i].Post
+	}
+	if err := preparePosts(r, posts); err != nil {
+		http.Error(w, "Failed to render posts", http.StatusInternalServerError)
+		return
+	}
+	for i := range bookmarks {
+		bookmarks[i].Post = &posts[i]
+	}
+
+	var total int64
+	if len(result[0].Total) > 0 {
+		total = result[0].Total[0].N
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"bookmarks": bookmarks,
+		"pagination": map[string]interface{}{
+			"total":       total,
+			"page":        page,
+			"limit":       limit,
+			"total_pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+func newShareToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func readingListShareURL(token string) string {
+	return config.PublicBaseURL() + "/api/v1/lists/shared/" + token
+}
+
+// findReadingList loads the caller's reading list in the URL, writing the
+// error response itself when it cannot. Other users' lists are not found.
+func findReadingList(w http.ResponseWriter, r *http.Request) (*models.ReadingList, bool) {
+	listID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid list ID", http.StatusBadRequest)
+		return nil, false
+	}
+	userID, _ := requestViewer(r)
+
+	var list models.ReadingList
+	err = config.ReadingListCollection.FindOne(r.Context(), bson.M{"_id": listID, "owner_id": userID}).Decode(&list)
+	if err != nil {
+		http.Error(w, "Reading list not found", http.StatusNotFound)
+		return nil, false
+	}
+	if list.ShareToken != "" {
+		list.ShareURL = readingListShareURL(list.ShareToken)
+	}
+	return &list, true
+}
+
+// loadListPosts fills in the posts of list the caller may read, in list
+// order. PostIDs is narrowed to the same posts so hidden ones do not leak.
+func loadListPosts(r *http.Request, list *models.ReadingList) error {
+	posts, err := readablePosts(r, list.PostIDs)
+	if err != nil {
+		return err
+	}
+	list.Posts, list.PostIDs = posts, postIDsOf(posts)
+	return preparePosts(r, list.Posts)
+}
+
+// readablePosts loads the posts in ids the caller may read, keeping the order
+// of ids.
+func readablePosts(r *http.Request, ids []primitive.ObjectID) ([]models.Post, error) {
+	posts := []models.Post{}
+	if len(ids) == 0 {
+		return posts, nil
+	}
+
+	userID, role := requestViewer(r)
+	filter := readableFilter(userID, role)
+	filter["_id"] = bson.M{"$in": ids}
+	cursor, err := config.PostCollection.Find(r.Context(), filter)
+	if err != nil {
+		return nil, err
+	}
+	var found []models.Post
+	if err := cursor.All(r.Context(), &found); err != nil {
+		return nil, err
+	}
+
+	byID := make(map[primitive.ObjectID]models.Post, len(found))
+	for _, p := range found {
+		byID[p.ID] = p
+	}
+	for _, id := range ids {
+		if p, ok := byID[id]; ok {
+			posts = append(posts, p)
+		}
+	}
+	return posts, nil
+}
+
+func postIDsOf(posts []models.Post) []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+	return ids
+}
+
+type readingListInput struct {
+	Name        *string   `json:"name"`
+	Description *string   `json:"description"`
+	PostIDs     *[]string `json:"post_ids"` // replaces the list, e.g. to reorder it
+}
+
+// validate checks the input and converts post_ids, dropping duplicates.
+func (in readingListInput) validate(creating bool) ([]primitive.ObjectID, string) {
+	return validateList("Name", in.Name, maxReadingListName, creating, in.PostIDs, models.MaxReadingListPosts)
+}
+
+// ListReadingLists godoc
+// @Summary List my reading lists
+// @Tags Bookmarks
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.ReadingList
+// @Failure 500 {string} string "Failed to fetch reading lists"
+// @Router /api/v1/users/me/lists [get]
+func ListReadingLists(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, _ := requestViewer(r)
+	cursor, err := config.ReadingListCollection.Find(r.Context(), bson.M{"owner_id": userID},
+		options.Find().SetSort(bson.D{{Key: "updated_at", Value: -1}}))
+	if err != nil {
+		http.Error(w, "Failed to fetch reading lists", http.StatusInternalServerError)
+		return
+	}
+	lists := []models.ReadingList{}
+	if err := cursor.All(r.Context(), &lists); err != nil {
+		http.Error(w, "Failed to parse reading lists", http.StatusInternalServerError)
+		return
+	}
+	for i := range lists {
+		if lists[i].ShareToken != "" {
+			lists[i].ShareURL = readingListShareURL(lists[i].ShareToken)
+		}
+	}
+
+	json.NewEncoder(w).Encode(lists)
+}
+
+// CreateReadingList godoc
+// @Summary Create a reading list
+// @Tags Bookmarks
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param list body readingListInput true "Name, optional description and post IDs"
+// @Success 201 {object} models.ReadingList
+// @Failure 400 {string} string "Invalid input"
+// @Router /api/v1/users/me/lists [post]
+func CreateReadingList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var in readingListInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	postIDs, msg := in.validate(true)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := requestViewer(r)
+	now := time.Now()
+	list := models.ReadingList{
+		ID:        primitive.NewObjectID(),
+		OwnerID:   userID,
+		Name:      *in.Name,
+		PostIDs:   postIDs,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if list.PostIDs == nil {
+		list.PostIDs = []primitive.ObjectID{}
+	}
+	if in.Description != nil {
+		list.Description = *in.Description
+	}
+
+	if _, err := config.ReadingListCollection.InsertOne(r.Context(), list); err != nil {
+		http.Error(w, "Failed to create reading list", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(list)
+}
+
+// GetReadingList godoc
+// @Summary Get one of my reading lists with its posts
+// @Tags Bookmarks
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Reading list ID"
+// @Success 200 {object} models.ReadingList
+// @Failure 404 {string} string "Reading list not found"
+// @Router /api/v1/users/me/lists/{id} [get]
+func GetReadingList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	list, ok := findReadingList(w, r)
+	if !ok {
+		return
+	}
+	if err := loadListPosts(r, list); err != nil {
+		http.Error(w, "Failed to load posts", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(list)
+}
+
+// UpdateReadingList godoc
+// @Summary Rename, describe or reorder a reading list
+// @Description post_ids, when given, replaces the posts of the list in that order.
+// @Tags Bookmarks
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Reading list ID"
+// @Param list body readingListInput true "Fields to change"
+// @Success 200 {object} models.ReadingList
+// @Failure 400 {string} string "Invalid input"
+// @Failure 404 {string} string "Reading list not found"
+// @Router /api/v1/users/me/lists/{id} [put]
+func UpdateReadingList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	list, ok := findReadingList(w, r)
+	if !ok {
+		return
+	}
+	var in readingListInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	postIDs, msg := in.validate(false)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	set := bson.M{"updated_at": time.Now()}
+	if in.Name != nil {
+		set["name"] = *in.Name
+	}
+	if in.Description != nil {
+		set["description"] = *in.Description
+	}
+	if postIDs != nil {
+		set["post_ids"] = postIDs
+	}
+	err := config.ReadingListCollection.FindOneAndUpdate(r.Context(),
+		bson.M{"_id": list.ID, "owner_id": list.OwnerID}, bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(list)
+	if err != nil {
+		http.Error(w, "Failed to update reading list", http.StatusInternalServerError)
+		return
+	}
+	if err := loadListPosts(r, list); err != nil {
+		http.Error(w, "Failed to load posts", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(list)
+}
+
+// DeleteReadingList godoc
+// @Summary Delete a reading list
+// @Tags Bookmarks
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Reading list ID"
+// @Success 200 {string} string "Reading list deleted"
+// @Failure 404 {string} string "Reading list not found"
+// @Router /api/v1/users/me/lists/{id} [delete]
+func DeleteReadingList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	list, ok := findReadingList(w, r)
+	if !ok {
+		return
+	}
+	if _, err := config.ReadingListCollection.DeleteOne(r.Context(), bson.M{"_id": list.ID}); err != nil {
+		http.Error(w, "Failed to delete reading list", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Reading list deleted"})
+}
+
+// AddToReadingList godoc
+// @Summary Add a post to a reading list
+// @Description Appends the post unless it is already in the list.
+// @Tags Bookmarks
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Reading list ID"
+// @Param postId path string true "Post ID"
+// @Success 200 {string} string "Post added"
+// @Failure 404 {string} string "Reading list or post not found"
+// @Failure 409 {string} string "Reading list is full"
+// @Router /api/v1/users/me/lists/{id}/posts/{postId} [put]
+func AddToReadingList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	list, ok := findReadingList(w, r)
+	if !ok {
+		return
+	}
+	post, ok := findReadablePost(w, r, "postId")
+	if !ok {
+		return
+	}
+
+	// The size guard keeps concurrent adds from overfilling the list.
+	result, err := config.ReadingListCollection.UpdateOne(r.Context(),
+		bson.M{"_id": list.ID, "post_ids." + strconv.Itoa(models.MaxReadingListPosts-1): bson.M{"$exists": false}},
+		bson.M{
+			"$addToSet": bson.M{"post_ids": post.ID},
+			"$set":      bson.M{"updated_at": time.Now()},
+		})
+	if err != nil {
+		http.Error(w, "Failed to add post", http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, "Reading list is full", http.StatusConflict)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Post added"})
+}
+
+// RemoveFromReadingList godoc
+// @Summary Remove a post from a reading list
+// @Tags Bookmarks
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Reading list ID"
+// @Param postId path string true "Post ID"
+// @Success 200 {string} string "Post removed"
+// @Failure 404 {string} string "Reading list not found"
+// @Router /api/v1/users/me/lists/{id}/posts/{postId} [delete]
+func RemoveFromReadingList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	list, ok := findReadingList(w, r)
+	if !ok {
+		return
+	}
+	postID, err := primitive.ObjectIDFromHex(mux.Vars(r)["postId"])
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	_, err = config.ReadingListCollection.UpdateOne(r.Context(), bson.M{"_id": list.ID}, bson.M{
+		"$pull": bson.M{"post_ids": postID},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+	if err != nil {
+		http.Error(w, "Failed to remove post", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Post removed"})
+}
+
+// ShareReadingList godoc
+// @Summary Share a reading list by link
+// @Description Creates the share link if the list has none. Anyone with the link can read the list.
+// @Tags Bookmarks
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Reading list ID"
+// @Success 200 {object} models.ReadingList
+// @Failure 404 {string} string "Reading list not found"
+// @Router /api/v1/users/me/lists/{id}/share [post]
+func ShareReadingList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	list, ok := findReadingList(w, r)
+	if !ok {
+		return
+	}
+	if list.ShareToken == "" {
+		token, err := newShareToken()
+		if err != nil {
+			http.Error(w, "Failed to share reading list", http.StatusInternalServerError)
+			return
+		}
+		_, err = config.ReadingListCollection.UpdateOne(r.Context(), bson.M{"_id": list.ID},
+			bson.M{"$set": bson.M{"share_token": token}})
+		if err != nil {
+			http.Error(w, "Failed to share reading list", http.StatusInternalServerError)
+			return
+		}
+		list.ShareToken = token
+		list.ShareURL = readingListShareURL(token)
+	}
+
+	json.NewEncoder(w).Encode(list)
+}
+
+// UnshareReadingList godoc
+// @Summary Revoke the share link of a reading list
+// @Tags Bookmarks
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Reading list ID"
+// @Success 200 {string} string "Share link revoked"
+// @Failure 404 {string} string "Reading list not found"
+// @Router /api/v1/users/me/lists/{id}/share [delete]
+func UnshareReadingList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	list, ok := findReadingList(w, r)
+	if !ok {
+		return
+	}
+	_, err := config.ReadingListCollection.UpdateOne(r.Context(), bson.M{"_id": list.ID},
+		bson.M{"$unset": bson.M{"share_token": ""}})
+	if err != nil {
+		http.Error(w, "Failed to revoke share link", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Share link revoked"})
+}
+
+// GetSharedReadingList godoc
+// @Summary Read a shared reading list
+// @Description Shows the posts the caller may read; signed-in readers may see more than anonymous ones.
+// @Tags Bookmarks
+// @Produce json
+// @Param token path string true "Share token"
+// @Success 200 {object} models.ReadingList
+// @Failure 404 {string} string "Reading list not found"
+// @Router /api/v1/lists/shared/{token} [get]
+func GetSharedReadingList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var list models.ReadingList
+	err := config.ReadingListCollection.FindOne(r.Context(), bson.M{"share_token": mux.Vars(r)["token"]}).Decode(&list)
+	if err != nil {
+		http.Error(w, "Reading list not found", http.StatusNotFound)
+		return
+	}
+	if err := loadListPosts(r, &list); err != nil {
+		http.Error(w, "Failed to load posts", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(list)
+}
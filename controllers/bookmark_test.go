@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"go-backend/config"
+	"go-backend/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func setupTestReadingList(ownerID primitive.ObjectID, postIDs []primitive.ObjectID) models.ReadingList {
+	list := models.ReadingList{
+		ID:        primitive.NewObjectID(),
+		OwnerID:   ownerID,
+		Name:      "Test List",
+		PostIDs:   postIDs,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	_, _ = config.ReadingListCollection.InsertOne(context.TODO(), list)
+	return list
+}
+
+func TestReadingListInput_Validate(t *testing.T) {
+	a, b := primitive.NewObjectID(), primitive.NewObjectID()
+	name := "  Weekend reads  "
+	in := readingListInput{Name: &name, PostIDs: &[]string{a.Hex(), b.Hex(), a.Hex()}}
+
+	ids, msg := in.validate(true)
+	if msg != "" {
+		t.Fatalf("expected valid input, got %q", msg)
+	}
+	if len(ids) != 2 || ids[0] != a || ids[1] != b {
+		t.Errorf("expected duplicates dropped in order, got %v", ids)
+	}
+	if name != "Weekend reads" {
+		t.Errorf("expected the name trimmed, got %q", name)
+	}
+
+	if _, msg := (readingListInput{}).validate(true); msg != "Name is required" {
+		t.Errorf("expected a name to be required on create, got %q", msg)
+	}
+	if ids, msg := (readingListInput{}).validate(false); msg != "" || ids != nil {
+		t.Errorf("expected an empty update to leave the posts alone, got %v %q", ids, msg)
+	}
+	long := strings.Repeat("x", maxReadingListName+1)
+	if _, msg := (readingListInput{Name: &long}).validate(false); msg != "Name is too long" {
+		t.Errorf("expected a long name to be rejected, got %q", msg)
+	}
+	if _, msg := (readingListInput{PostIDs: &[]string{"nope"}}).validate(false); msg != "Invalid post ID nope" {
+		t.Errorf("expected an invalid post ID to be rejected, got %q", msg)
+	}
+
+	full := make([]string, models.MaxReadingListPosts)
+	for i := range full {
+		full[i] = primitive.NewObjectID().Hex()
+	}
+	if _, msg := (readingListInput{PostIDs: &full}).validate(false); msg != "" {
+		t.Errorf("expected %d posts to fit, got %q", models.MaxReadingListPosts, msg)
+	}
+	over := append(full, primitive.NewObjectID().Hex())
+	if _, msg := (readingListInput{PostIDs: &over}).validate(false); msg != "Too many posts" {
+		t.Errorf("expected more than %d posts to be rejected, got %q", models.MaxReadingListPosts, msg)
+	}
+}
+
+func TestAddToReadingList_Full(t *testing.T) {
+	ownerID := primitive.NewObjectID()
+	published := time.Now().Add(-time.Hour)
+	post := setupTestPost(primitive.NewObjectID(), "published", &published)
+	postIDs := make([]primitive.ObjectID, models.MaxReadingListPosts)
+	for i := range postIDs {
+		postIDs[i] = primitive.NewObjectID()
+	}
+	list := setupTestReadingList(ownerID, postIDs)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/users/me/lists/"+list.ID.Hex()+"/posts/"+post.ID.Hex(), nil)
+	req = mux.SetURLVars(req, map[string]string{"id": list.ID.Hex(), "postId": post.ID.Hex()})
+	req = bearerAuth(req, ownerID.Hex(), "user")
+	w := httptest.NewRecorder()
+
+	AddToReadingList(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409 Conflict for a full list, got %d", w.Code)
+	}
+}
+
+func TestShareReadingList_Lifecycle(t *testing.T) {
+	ownerID := primitive.NewObjectID()
+	list := setupTestReadingList(ownerID, []primitive.ObjectID{})
+
+	share := func() string {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/users/me/lists/"+list.ID.Hex()+"/share", nil)
+		req = muxWithParams(req, "id", list.ID.Hex())
+		req = bearerAuth(req, ownerID.Hex(), "user")
+		w := httptest.NewRecorder()
+		ShareReadingList(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200 OK sharing the list, got %d", w.Code)
+		}
+		var shared models.ReadingList
+		_ = json.NewDecoder(w.Body).Decode(&shared)
+		return shared.ShareURL[strings.LastIndex(shared.ShareURL, "/")+1:]
+	}
+	getShared := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/lists/shared/"+token, nil)
+		req = muxWithParams(req, "token", token)
+		w := httptest.NewRecorder()
+		GetSharedReadingList(w, req)
+		return w.Code
+	}
+
+	token := share()
+	if token == "" {
+		t.Fatal("expected a share link")
+	}
+	if again := share(); again != token {
+		t.Errorf("expected sharing twice to keep the link, got %q then %q", token, again)
+	}
+	if code := getShared(token); code != http.StatusOK {
+		t.Errorf("expected 200 OK for the shared list, got %d", code)
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/users/me/lists/"+list.ID.Hex()+"/share", nil)
+	req = muxWithParams(req, "id", list.ID.Hex())
+	req = bearerAuth(req, ownerID.Hex(), "user")
+	w := httptest.NewRecorder()
+	UnshareReadingList(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 OK revoking the link, got %d", w.Code)
+	}
+
+	if code := getShared(token); code != http.StatusNotFound {
+		t.Errorf("expected 404 Not Found for a revoked link, got %d", code)
+	}
+	if again := share(); again == token {
+		t.Error("expected sharing again to issue a new link")
+	}
+}
+
+func TestGetSharedReadingList_NarrowsPostIDs(t *testing.T) {
+	ownerID, authorID := primitive.NewObjectID(), primitive.NewObjectID()
+	published := time.Now().Add(-time.Hour)
+	public := setupTestPost(authorID, "published", &published)
+	draft := setupTestPost(authorID, "draft", nil)
+	list := setupTestReadingList(ownerID, []primitive.ObjectID{draft.ID, public.ID})
+	token := "test-" + list.ID.Hex()
+	_, _ = config.ReadingListCollection.UpdateByID(context.TODO(), list.ID, bson.M{"$set": bson.M{"share_token": token}})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/lists/shared/"+token, nil)
+	req = muxWithParams(req, "token", token)
+	w := httptest.NewRecorder()
+
+	GetSharedReadingList(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 OK, got %d", w.Code)
+	}
+	var shared models.ReadingList
+	_ = json.NewDecoder(w.Body).Decode(&shared)
+	if len(shared.PostIDs) != 1 || shared.PostIDs[0] != public.ID {
+		t.Errorf("expected only the readable post in post_ids, got %v", shared.PostIDs)
+	}
+	if len(shared.Posts) != 1 {
+		t.Errorf("expected only the readable post, got %d posts", len(shared.Posts))
+	}
+}
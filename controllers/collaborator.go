@@ -0,0 +1,246 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"go-backend/config"
+	"go-backend/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Owners share posts with collaborators. Co-authors and editors may edit the
+// post, viewers may read it before it is published, and co-authors are
+// credited next to the owner wherever the post is shown.
+
+// findPostAndCollaborator loads the post and the collaborator user ID in the
+// URL, writing the error response itself when it cannot.
+func findPostAndCollaborator(w http.ResponseWriter, r *http.Request) (*models.Post, primitive.ObjectID, bool) {
+	postID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return nil, primitive.NilObjectID, false
+	}
+	collaboratorID, err := primitive.ObjectIDFromHex(mux.Vars(r)["userId"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return nil, primitive.NilObjectID, false
+	}
+	var post models.Post
+	if err := config.PostCollection.FindOne(r.Context(), bson.M{"_id": postID}).Decode(&post); err != nil {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return nil, primitive.NilObjectID, false
+	}
+	return &post, collaboratorID, true
+}
+
+// findUserSummaries loads the public part of users by ID.
+func findUserSummaries(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]models.UserSummary, error) {
+	users := map[primitive.ObjectID]models.UserSummary{}
+	if len(ids) == 0 {
+		return users, nil
+	}
+	cursor, err := config.UserCollection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}},
+		options.Find().SetProjection(bson.M{"name": 1, "handle": 1, "role": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var found []models.UserSummary
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+	for _, u := range found {
+		users[u.ID] = u
+	}
+	return users, nil
+}
+
+// attachCoAuthors fills in the co-authors of posts.
+func attachCoAuthors(ctx context.Context, posts []models.Post) error {
+	var ids []primitive.ObjectID
+	for _, p := range posts {
+		ids = append(ids, p.CoAuthorIDs()...)
+	}
+	users, err := findUserSummaries(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		posts[i].CoAuthors = nil
+		for _, id := range posts[i].CoAuthorIDs() {
+			if u, ok := users[id]; ok {
+				posts[i].CoAuthors = append(posts[i].CoAuthors, u)
+			}
+		}
+	}
+	return nil
+}
+
+// ListCollaborators godoc
+// @Summary List the collaborators of a post
+// @Description Visible to the owner, the collaborators and admins.
+// @Tags Collaborators
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Post ID"
+// @Success 200 {array} models.Collaborator
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Post not found"
+// @Router /api/v1/posts/{id}/collaborators [get]
+func ListCollaborators(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	postID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+	var post models.Post
+	if err := config.PostCollection.FindOne(r.Context(), bson.M{"_id": postID}).Decode(&post); err != nil {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	}
+	userID, role := requestViewer(r)
+	if post.AuthorID != userID && post.CollaboratorRole(userID) == "" && role != "admin" {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	ids := make([]primitive.ObjectID, len(post.Collaborators))
+	for i, c := range post.Collaborators {
+		ids[i] = c.UserID
+	}
+	users, err := findUserSummaries(r.Context(), ids)
+	if err != nil {
+		http.Error(w, "Failed to fetch collaborators", http.StatusInternalServerError)
+		return
+	}
+	collaborators := []models.Collaborator{}
+	for _, c := range post.Collaborators {
+		if u, ok := users[c.UserID]; ok {
+			c.User = &u
+		}
+		collaborators = append(collaborators, c)
+	}
+
+	json.NewEncoder(w).Encode(collaborators)
+}
+
+// SetCollaborator godoc
+// @Summary Add a collaborator or change their role
+// @Description Only the owner of the post and admins may manage collaborators. Editing goes through the author routes, so co-authors and editors must be authors or admins; anyone can be a viewer.
+// @Tags Collaborators
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param userId path string true "User ID"
+// @Param collaborator body object true "role: co-author, editor or viewer"
+// @Success 200 {string} string "Collaborator saved"
+// @Failure 400 {string} string "Invalid role"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Post or user not found"
+// @Failure 409 {string} string "Too many collaborators"
+// @Router /api/v1/posts/{id}/collaborators/{userId} [put]
+func SetCollaborator(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	post, collaboratorID, ok := findPostAndCollaborator(w, r)
+	if !ok {
+		return
+	}
+	userID, role := requestViewer(r)
+	if post.AuthorID != userID && role != "admin" {
+		http.Error(w, "Only the owner can manage collaborators", http.StatusForbidden)
+		return
+	}
+	if collaboratorID == post.AuthorID {
+		http.Error(w, "The owner cannot be a collaborator", http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		Role models.CollaboratorRole `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if !input.Role.Valid() {
+		http.Error(w, "role must be co-author, editor or viewer", http.StatusBadRequest)
+		return
+	}
+	var collaborator models.User
+	if err := config.UserCollection.FindOne(r.Context(), bson.M{"_id": collaboratorID}).Decode(&collaborator); err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if role := strings.ToLower(collaborator.Role); input.Role.CanEdit() && role != "author" && role != "admin" {
+		http.Error(w, "Only authors can be co-authors or editors", http.StatusBadRequest)
+		return
+	}
+
+	// Change the role of an existing collaborator, or add a new one while
+	// there is room.
+	result, err := config.PostCollection.UpdateOne(r.Context(),
+		bson.M{"_id": post.ID, "collaborators.user_id": collaboratorID},
+		bson.M{"$set": bson.M{"collaborators.$.role": input.Role}, "$inc": bson.M{"version": 1}})
+	if err == nil && result.MatchedCount == 0 {
+		full := "collaborators." + strconv.Itoa(models.MaxCollaborators-1)
+		result, err = config.PostCollection.UpdateOne(r.Context(),
+			bson.M{"_id": post.ID, "collaborators.user_id": bson.M{"$ne": collaboratorID}, full: bson.M{"$exists": false}},
+			bson.M{"$push": bson.M{"collaborators": models.Collaborator{UserID: collaboratorID, Role: input.Role, AddedAt: time.Now()}}, "$inc": bson.M{"version": 1}})
+		if err == nil && result.MatchedCount == 0 {
+			http.Error(w, "Too many collaborators", http.StatusConflict)
+			return
+		}
+	}
+	if err != nil {
+		http.Error(w, "Failed to save collaborator", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Collaborator saved"})
+}
+
+// RemoveCollaborator godoc
+// @Summary Remove a collaborator
+// @Description The owner and admins may remove anyone; collaborators may remove themselves.
+// @Tags Collaborators
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param userId path string true "User ID"
+// @Success 200 {string} string "Collaborator removed"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Post not found"
+// @Router /api/v1/posts/{id}/collaborators/{userId} [delete]
+func RemoveCollaborator(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	post, collaboratorID, ok := findPostAndCollaborator(w, r)
+	if !ok {
+		return
+	}
+	userID, role := requestViewer(r)
+	if post.AuthorID != userID && collaboratorID != userID && role != "admin" {
+		http.Error(w, "Only the owner can manage collaborators", http.StatusForbidden)
+		return
+	}
+
+	_, err := config.PostCollection.UpdateOne(r.Context(), bson.M{"_id": post.ID},
+		bson.M{"$pull": bson.M{"collaborators": bson.M{"user_id": collaboratorID}}, "$inc": bson.M{"version": 1}})
+	if err != nil {
+		http.Error(w, "Failed to remove collaborator", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Collaborator removed"})
+}
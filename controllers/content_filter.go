@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"encoding/json"
+	"go-backend/models"
+	"go-backend/utils"
+	"net/http"
+)
+
+// screenPost runs the content filter over the title and content of post,
+// masking them in place. It writes the error response itself when the post
+// is rejected or cannot be checked.
+func screenPost(w http.ResponseWriter, r *http.Request, post *models.Post) (utils.ContentVerdict, bool) {
+	text := utils.PostText{Title: post.Title, Content: post.Content}
+	verdict, err := utils.Content.Screen(r.Context(), &text)
+	if err != nil {
+		http.Error(w, "Failed to check post content", http.StatusInternalServerError)
+		return verdict, false
+	}
+	if verdict.Action == models.FilterReject {
+		http.Error(w, "Post rejected by the content filter: "+verdict.Reason(), http.StatusUnprocessableEntity)
+		return verdict, false
+	}
+	post.Title, post.Content = text.Title, text.Content
+	return verdict, true
+}
+
+// GetContentFilter godoc
+// @Summary Get the content filter configuration
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.ContentFilterConfig
+// @Failure 500 {string} string "Failed to load content filter"
+// @Router /api/v1/admin/content-filter [get]
+func GetContentFilter(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	cfg, err := utils.Content.Config(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to load content filter", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(cfg)
+}
+
+// UpdateContentFilter godoc
+// @Summary Replace the content filter configuration
+// @Description Blocked terms, link domain allow and deny lists and the link limit, each with a reject, flag or mask action. Applies to posts created or updated from now on.
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param config body models.ContentFilterConfig true "Content filter configuration"
+// @Success 200 {object} models.ContentFilterConfig
+// @Failure 400 {string} string "Invalid configuration"
+// @Router /api/v1/admin/content-filter [put]
+func UpdateContentFilter(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var cfg models.ContentFilterConfig
+	if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if cfg.BlockedTerms == nil {
+		cfg.BlockedTerms = []models.BlockedTerm{}
+	}
+	if cfg.AllowedDomains == nil {
+		cfg.AllowedDomains = []string{}
+	}
+	if cfg.DeniedDomains == nil {
+		cfg.DeniedDomains = []string{}
+	}
+
+	cfg, err := utils.Content.Update(r.Context(), cfg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(cfg)
+}
+
+// CheckContentFilter godoc
+// @Summary Try the content filter on a sample post
+// @Description Returns the findings and the text as it would be stored, without saving anything.
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param post body object true "title and content"
+// @Success 200 {object} utils.ContentVerdict
+// @Router /api/v1/admin/content-filter/check [post]
+func CheckContentFilter(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var text utils.PostText
+	if err := json.NewDecoder(r.Body).Decode(&text); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	verdict, err := utils.Content.Screen(r.Context(), &text)
+	if err != nil {
+		http.Error(w, "Failed to check post content", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"action":   verdict.Action,
+		"findings": verdict.Findings,
+		"title":    text.Title,
+		"content":  text.Content,
+	})
+}
@@ -0,0 +1,222 @@
+package controllers
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"go-backend/config"
+	"go-backend/models"
+	"go-backend/utils"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const feedLimit = 50
+
+var feedContentTypes = map[string]string{
+	"rss":  "application/rss+xml; charset=utf-8",
+	"atom": "application/atom+xml; charset=utf-8",
+	"json": "application/feed+json; charset=utf-8",
+}
+
+// PostFeed godoc
+// @Summary Post feed
+// @Description Latest public published posts as RSS 2.0, Atom 1.0 or JSON Feed 1.1, optionally scoped to an author, including posts they co-authored, or a tag. Supports conditional GET.
+// @Tags Feeds
+// @Produce xml
+// @Produce json
+// @Param format path string true "Feed format (rss, atom, json)"
+// @Param id path string false "Author ID (author feeds)"
+// @Param tag path string false "Tag (tag feeds)"
+// @Success 200 {string} string "Feed document"
+// @Success 304 {string} string "Not modified"
+// @Failure 400 {string} string "Invalid author ID"
+// @Failure 404 {string} string "Author not found"
+// @Router /feeds/posts.{format} [get]
+// @Router /feeds/authors/{id}/posts.{format} [get]
+// @Router /feeds/tags/{tag}/posts.{format} [get]
+func PostFeed(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	format := vars["format"]
+	base := config.PublicBaseURL()
+
+	feed := utils.Feed{
+		Title:       "Latest posts",
+		Description: "Public trade ideas and posts",
+		Link:        base,
+		FeedURL:     base + r.URL.Path,
+	}
+
+	filter := bson.M{"status": "published", "visibility": models.VisibilityPublic, "hidden": bson.M{"$ne": true}}
+
+	if hexID, ok := vars["id"]; ok {
+		authorID, err := primitive.ObjectIDFromHex(hexID)
+		if err != nil {
+			http.Error(w, "Invalid author ID", http.StatusBadRequest)
+			return
+		}
+		var author models.User
+		if err := config.UserCollection.FindOne(r.Context(), bson.M{"_id": authorID}).Decode(&author); err != nil {
+			http.Error(w, "Author not found", http.StatusNotFound)
+			return
+		}
+		for k, v := range models.ByAuthorFilter(authorID) {
+			filter[k] = v
+		}
+		feed.Title = "Posts by " + author.Name
+		feed.Description = "Public posts by " + author.Name
+	}
+
+	if tag, ok := vars["tag"]; ok {
+		filter["tags"] = tag
+		feed.Title = "Posts tagged " + tag
+		feed.Description = "Public posts tagged " + tag
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "published_at", Value: -1}}).
+		SetLimit(feedLimit)
+
+	cursor, err := config.PostCollection.Find(r.Context(), filter, findOptions)
+	if err != nil {
+		http.Error(w, "Failed to fetch posts", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(r.Context())
+
+	posts := []models.Post{}
+	if err := cursor.All(r.Context(), &posts); err != nil {
+		http.Error(w, "Failed to parse posts", http.StatusInternalServerError)
+		return
+	}
+
+	etag, lastModified := feedValidators(format, posts)
+	if utils.SetCacheValidators(w, r, etag, lastModified) {
+		return
+	}
+	feed.Updated = lastModified
+	if feed.Updated.IsZero() {
+		feed.Updated = time.Now()
+	}
+
+	authors, err := lookupAuthors(r, posts)
+	if err != nil {
+		http.Error(w, "Failed to fetch authors", http.StatusInternalServerError)
+		return
+	}
+
+	for _, post := range posts {
+		rendered, err := utils.CachedRenderContent(r.Context(), post.Content)
+		if err != nil {
+			http.Error(w, "Failed to render post", http.StatusInternalServerError)
+			return
+		}
+		item := utils.FeedItem{
+			ID:          post.ID.Hex(),
+			Title:       post.Title,
+			Link:        base + postPermalink(post, authors[post.AuthorID]),
+			ContentHTML: rendered.ContentHTML,
+			Summary:     rendered.Excerpt,
+			Author:      authors[post.AuthorID].Name,
+			Tags:        post.Tags,
+			Published:   post.CreatedAt,
+			Updated:     post.UpdatedAt,
+		}
+		if post.PublishedAt != nil {
+			item.Published = *post.PublishedAt
+		}
+		for _, id := range post.CoAuthorIDs() {
+			if name := authors[id].Name; name != "" {
+				item.CoAuthors = append(item.CoAuthors, name)
+			}
+		}
+		feed.Items = append(feed.Items, item)
+	}
+
+	var body []byte
+	switch format {
+	case "rss":
+		body, err = feed.RSS()
+	case "atom":
+		body, err = feed.Atom()
+	default:
+		body, err = feed.JSON()
+	}
+	if err != nil {
+		http.Error(w, "Failed to render feed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", feedContentTypes[format])
+	w.Write(body)
+}
+
+// feedValidators derives an ETag and Last-Modified from the posts in a feed,
+// so the feed changes whenever a post is added, removed or edited.
+func feedValidators(format string, posts []models.Post) (string, time.Time) {
+	h := sha1.New()
+	fmt.Fprint(h, format)
+
+	var lastModified time.Time
+	for _, post := range posts {
+		fmt.Fprintf(h, "|%s:%d", post.ID.Hex(), post.UpdatedAt.UnixNano())
+		if post.UpdatedAt.After(lastModified) {
+			lastModified = post.UpdatedAt
+		}
+		if post.PublishedAt != nil && post.PublishedAt.After(lastModified) {
+			lastModified = *post.PublishedAt
+		}
+	}
+
+	return `"` + hex.EncodeToString(h.Sum(nil)) + `"`, lastModified
+}
+
+// postPermalink is the public path of a published post: its permalink when
+// the author has a handle, else its slug, else its ID.
+func postPermalink(post models.Post, author models.User) string {
+	switch {
+	case post.Slug != "" && author.Handle != "":
+		return "/api/v1/posts/@" + author.Handle + "/" + post.Slug
+	case post.Slug != "":
+		return "/api/v1/posts/by-slug/" + post.Slug
+	default:
+		return "/api/v1/posts/" + post.ID.Hex()
+	}
+}
+
+// lookupAuthors loads the authors and co-authors of posts.
+func lookupAuthors(r *http.Request, posts []models.Post) (map[primitive.ObjectID]models.User, error) {
+	authors := map[primitive.ObjectID]models.User{}
+	ids := []primitive.ObjectID{}
+	for _, post := range posts {
+		for _, id := range append([]primitive.ObjectID{post.AuthorID}, post.CoAuthorIDs()...) {
+			if _, seen := authors[id]; !seen {
+				authors[id] = models.User{}
+				ids = append(ids, id)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return authors, nil
+	}
+
+	cursor, err := config.UserCollection.Find(r.Context(), bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(r.Context())
+
+	users := []models.User{}
+	if err := cursor.All(r.Context(), &users); err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		authors[user.ID] = user
+	}
+	return authors, nil
+}
@@ -0,0 +1,288 @@
+package controllers
+
+import (
+	"encoding/json"
+	"go-backend/config"
+	"go-backend/models"
+	"go-backend/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// pageLimit reads limit from the query string, between 1 and 100.
+func pageLimit(r *http.Request, fallback int) int {
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 && v <= 100 {
+		return v
+	}
+	return fallback
+}
+
+// FollowUser godoc
+// @Summary Follow an author
+// @Description Their published posts appear in the caller's home feed. Following twice is a no-op.
+// @Tags Follows
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {string} string "Following"
+// @Failure 400 {string} string "You cannot follow yourself"
+// @Failure 404 {string} string "User not found"
+// @Failure 409 {string} string "Following too many users"
+// @Router /api/v1/users/{id}/follow [put]
+func FollowUser(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	authorID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := requestViewer(r)
+	if authorID == userID {
+		http.Error(w, "You cannot follow yourself", http.StatusBadRequest)
+		return
+	}
+	n, err := config.UserCollection.CountDocuments(r.Context(), bson.M{"_id": authorID}, options.Count().SetLimit(1))
+	if err != nil || n == 0 {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	following, err := config.FollowCollection.CountDocuments(r.Context(), bson.M{"follower_id": userID})
+	if err != nil {
+		http.Error(w, "Failed to follow user", http.StatusInternalServerError)
+		return
+	}
+	if following >= models.MaxFollowing {
+		http.Error(w, "Following too many users", http.StatusConflict)
+		return
+	}
+
+	result, err := config.FollowCollection.UpdateOne(r.Context(),
+		bson.M{"follower_id": userID, "author_id": authorID},
+		bson.M{"$setOnInsert": bson.M{"created_at": time.Now()}},
+		options.Update().SetUpsert(true))
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		http.Error(w, "Failed to follow user", http.StatusInternalServerError)
+		return
+	}
+	if result != nil && result.UpsertedCount > 0 {
+		if err := utils.Feeds.Invalidate(r.Context(), userID); err != nil {
+			config.Logger.Warnf("Failed to reset feed of %s: %v", userID.Hex(), err)
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Following"})
+}
+
+// UnfollowUser godoc
+// @Summary Unfollow an author
+// @Tags Follows
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {string} string "Unfollowed"
+// @Failure 400 {string} string "Invalid user ID"
+// @Router /api/v1/users/{id}/follow [delete]
+func UnfollowUser(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	authorID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := requestViewer(r)
+
+	result, err := config.FollowCollection.DeleteOne(r.Context(), bson.M{"follower_id": userID, "author_id": authorID})
+	if err != nil {
+		http.Error(w, "Failed to unfollow user", http.StatusInternalServerError)
+		return
+	}
+	if result.DeletedCount > 0 {
+		if err := utils.Feeds.Invalidate(r.Context(), userID); err != nil {
+			config.Logger.Warnf("Failed to reset feed of %s: %v", userID.Hex(), err)
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Unfollowed"})
+}
+
+// listFollows pages through the follows where field is the user in the URL,
+// newest first, filling in the user on the other side.
+func listFollows(w http.ResponseWriter, r *http.Request, field, other string) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	limit := pageLimit(r, 20)
+
+	match := bson.M{field: userID}
+	total, err := config.FollowCollection.CountDocuments(r.Context(), match)
+	if err != nil {
+		http.Error(w, "Failed to fetch follows", http.StatusInternalServerError)
+		return
+	}
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		before, err := primitive.ObjectIDFromHex(cursor)
+		if err != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		match["_id"] = bson.M{"$lt": before}
+	}
+
+	cursor, err := config.FollowCollection.Aggregate(r.Context(), mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: -1}}}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "users",
+			"localField":   other,
+			"foreignField": "_id",
+			"as":           "user",
+		}}},
+		{{Key: "$unwind", Value: bson.M{"path": "$user", "preserveNullAndEmptyArrays": true}}},
+		{{Key: "$project", Value: bson.M{"user.password": 0, "user.email": 0}}},
+	})
+	if err != nil {
+		http.Error(w, "Failed to fetch follows", http.StatusInternalServerError)
+		return
+	}
+	follows := []models.Follow{}
+	if err := cursor.All(r.Context(), &follows); err != nil {
+		http.Error(w, "Failed to parse follows", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{"follows": follows, "total": total}
+	if len(follows) == limit {
+		response["next_cursor"] = follows[len(follows)-1].ID.Hex()
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// ListFollowers godoc
+// @Summary List the followers of a user
+// @Tags Follows
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Items per page (default 20, max 100)"
+// @Success 200 {array} models.Follow
+// @Router /api/v1/users/{id}/followers [get]
+func ListFollowers(w http.ResponseWriter, r *http.Request) {
+	listFollows(w, r, "author_id", "follower_id")
+}
+
+// ListFollowing godoc
+// @Summary List the users a user follows
+// @Tags Follows
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Items per page (default 20, max 100)"
+// @Success 200 {array} models.Follow
+// @Router /api/v1/users/{id}/following [get]
+func ListFollowing(w http.ResponseWriter, r *http.Request) {
+	listFollows(w, r, "follower_id", "author_id")
+}
+
+// GetHomeFeed godoc
+// @Summary Recent posts from followed authors
+// @Description Newest first. Pass next_cursor back as cursor for the following page; it is absent on the last page.
+// @Tags Follows
+// @Security BearerAuth
+// @Produce json
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Items per page (default 20, max 100)"
+// @Success 200 {array} models.Post
+// @Failure 400 {string} string "Invalid cursor"
+// @Router /api/v1/feed [get]
+func GetHomeFeed(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var after *utils.FeedEntry
+	if c := r.URL.Query().Get("cursor"); c != "" {
+		var err error
+		if after, err = utils.ParseFeedCursor(c); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	limit := pageLimit(r, 20)
+	userID, role := requestViewer(r)
+
+	// Feed entries can point at posts that were unpublished since, so read a
+	// few more pages when a page loses posts to the filter.
+	posts := []models.Post{}
+	nextCursor := ""
+	for round := 0; round < 3 && len(posts) < limit; round++ {
+		want := limit - len(posts)
+		entries, err := utils.Feeds.Page(r.Context(), userID, after, want)
+		if err != nil {
+			http.Error(w, "Failed to load feed", http.StatusInternalServerError)
+			return
+		}
+		if len(entries) == 0 {
+			nextCursor = ""
+			break
+		}
+
+		ids := make([]primitive.ObjectID, len(entries))
+		for i, e := range entries {
+			ids[i] = e.PostID
+		}
+		filter := readableFilter(userID, role)
+		filter["_id"] = bson.M{"$in": ids}
+		cursor, err := config.PostCollection.Find(r.Context(), filter)
+		if err != nil {
+			http.Error(w, "Failed to fetch posts", http.StatusInternalServerError)
+			return
+		}
+		var found []models.Post
+		if err := cursor.All(r.Context(), &found); err != nil {
+			http.Error(w, "Failed to parse posts", http.StatusInternalServerError)
+			return
+		}
+		byID := make(map[primitive.ObjectID]models.Post, len(found))
+		for _, p := range found {
+			byID[p.ID] = p
+		}
+		for _, e := range entries {
+			if p, ok := byID[e.PostID]; ok {
+				posts = append(posts, p)
+			}
+		}
+
+		last := entries[len(entries)-1]
+		after = &last
+		nextCursor = last.Cursor()
+		if len(entries) < want {
+			nextCursor = "" // the feed ran out
+			break
+		}
+	}
+
+	if err := preparePosts(r, posts); err != nil {
+		http.Error(w, "Failed to render posts", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{"posts": posts}
+	if nextCursor != "" {
+		response["next_cursor"] = nextCursor
+	}
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,358 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-backend/config"
+	"go-backend/models"
+	"go-backend/utils"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// multipartOverhead is allowed on top of the file size for headers and
+// boundaries.
+const multipartOverhead = 1 << 20
+
+// UploadMedia godoc
+// @Summary Upload a media file
+// @Description Multipart upload in the "file" field. The type is sniffed from the content (JPEG, PNG, GIF, WebP, MP4, PDF) and the size is capped per role. Uploading the same file twice returns the existing record. Images start out pending while metadata is stripped and variants are generated.
+// @Tags Media
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "File to upload"
+// @Success 201 {object} models.Media
+// @Success 200 {object} models.Media "Already uploaded"
+// @Failure 400 {string} string "Invalid upload"
+// @Failure 403 {string} string "Uploads not allowed"
+// @Failure 413 {string} string "File too large"
+// @Failure 415 {string} string "Unsupported file type"
+// @Router /api/v1/media [post]
+func UploadMedia(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, _ := utils.ExtractUserIDFromRequest(r)
+	role, _ := utils.ExtractUserRole(r)
+	ownerID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	limit := utils.MediaSizeLimit(role)
+	if limit == 0 {
+		http.Error(w, utils.ErrUploadNotAllowed.Error(), http.StatusForbidden)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, limit+multipartOverhead)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "Expected a multipart/form-data upload", http.StatusBadRequest)
+		return
+	}
+	var part io.ReadCloser
+	var filename string
+	for {
+		p, err := reader.NextPart()
+		if err != nil {
+			http.Error(w, "Missing file field", http.StatusBadRequest)
+			return
+		}
+		if p.FormName() == "file" {
+			part, filename = p, p.FileName()
+			break
+		}
+		p.Close()
+	}
+	defer part.Close()
+
+	upload, err := utils.SpoolUpload(part, limit)
+	if err != nil {
+		writeUploadError(w, err)
+		return
+	}
+	defer upload.Close()
+
+	media, created, err := saveUpload(r.Context(), ownerID, filename, upload)
+	if err != nil {
+		http.Error(w, "Failed to store media", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SignMedia(&media, time.Now().Add(utils.MediaURLTTL))
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(media)
+}
+
+// writeUploadError maps SpoolUpload errors to responses.
+func writeUploadError(w http.ResponseWriter, err error) {
+	var maxBytes *http.MaxBytesError
+	switch {
+	case errors.Is(err, utils.ErrMediaTooLarge), errors.As(err, &maxBytes):
+		http.Error(w, utils.ErrMediaTooLarge.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, utils.ErrImageTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, utils.ErrMediaType):
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+	case errors.Is(err, utils.ErrMediaEmpty), errors.Is(err, utils.ErrMediaUnreadable):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Failed to read upload", http.StatusInternalServerError)
+	}
+}
+
+// saveUpload stores a spooled upload and its media record. If the owner
+// already uploaded the same bytes that record is returned with created false.
+func saveUpload(ctx context.Context, ownerID primitive.ObjectID, filename string, upload *utils.SpooledUpload) (models.Media, bool, error) {
+	var existing models.Media
+	err := config.MediaCollection.FindOne(ctx, bson.M{"owner_id": ownerID, "sha256": upload.SHA256}).Decode(&existing)
+	if err == nil {
+		return existing, false, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return models.Media{}, false, err
+	}
+
+	now := time.Now()
+	media := models.Media{
+		OwnerID:   ownerID,
+		Filename:  filename,
+		CreatedAt: now,
+		Status:    models.MediaStatusReady,
+		// Uploads no post picks up are collected after the grace period.
+		UnreferencedAt: &now,
+	}
+	if upload.Width > 0 {
+		media.Status = models.MediaStatusPending
+	}
+	if err := upload.Store(ctx, utils.Blobs, &media); err != nil {
+		config.Logger.Errorf("Failed to store media blob: %v", err)
+		return models.Media{}, false, err
+	}
+	if _, err := config.MediaCollection.InsertOne(ctx, media); err != nil {
+		utils.Blobs.Delete(ctx, media.Key)
+		return models.Media{}, false, err
+	}
+	// Images are stripped and resized in the background.
+	if media.Status == models.MediaStatusPending {
+		utils.MediaJobs.Enqueue(media.ID)
+	}
+	return media, true, nil
+}
+
+// GetMedia godoc
+// @Summary Get a media record
+// @Description Owners and admins only. URLs are signed and expire after an hour.
+// @Tags Media
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Media ID"
+// @Success 200 {object} models.Media
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Media not found"
+// @Router /api/v1/media/{id} [get]
+func GetMedia(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	media, ok := findMedia(w, r, "view")
+	if !ok {
+		return
+	}
+
+	utils.SignMedia(media, time.Now().Add(utils.MediaURLTTL))
+	json.NewEncoder(w).Encode(media)
+}
+
+// DeleteMedia godoc
+// @Summary Delete a media file
+// @Description Owners can delete their own media, admins any
+// @Tags Media
+// @Security BearerAuth
+// @Param id path string true "Media ID"
+// @Success 200 {string} string "Media deleted"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Media not found"
+// @Router /api/v1/media/{id} [delete]
+func DeleteMedia(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	media, ok := findMedia(w, r, "delete")
+	if !ok {
+		return
+	}
+
+	if _, err := config.MediaCollection.DeleteOne(r.Context(), bson.M{"_id": media.ID}); err != nil {
+		http.Error(w, "Failed to delete media", http.StatusInternalServerError)
+		return
+	}
+	utils.DeleteMediaBlobs(r.Context(), utils.Blobs, *media)
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Media deleted"})
+}
+
+// ServeMediaFile godoc
+// @Summary Download a media file or one of its variants
+// @Description Streams the stored bytes; supports Range requests. Media its owner uses in a public post is served directly, anything else needs the signed link (expires, sig) issued with the post or media record. Images are only served once their metadata has been stripped.
+// @Tags Media
+// @Param key path string true "Blob key"
+// @Param expires query int false "Signed link expiry (unix)"
+// @Param sig query string false "Signed link signature"
+// @Success 200 {file} file
+// @Success 206 {file} file
+// @Failure 403 {string} string "Signed link required or expired"
+// @Failure 404 {string} string "Not found"
+// @Failure 409 {string} string "Still processing"
+// @Router /media/{key} [get]
+func ServeMediaFile(w http.ResponseWriter, r *http.Request) {
+	key := mux.Vars(r)["key"]
+
+	var media models.Media
+	filter := bson.M{"$or": []bson.M{{"key": key}, {"variants.key": key}}}
+	if err := config.MediaCollection.FindOne(r.Context(), filter).Decode(&media); err != nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	// Unprocessed originals may still carry EXIF data such as GPS position.
+	if media.Status == models.MediaStatusPending || media.Status == models.MediaStatusFailed {
+		http.Error(w, "Media is still being processed", http.StatusConflict)
+		return
+	}
+
+	// Signed links are handed out to readers allowed to see the post. Without
+	// one, only media used by a public post is served.
+	query := r.URL.Query()
+	signed := query.Has("sig")
+	if signed && !utils.VerifyMediaURL(key, query.Get("expires"), query.Get("sig"), time.Now()) {
+		http.Error(w, "Link is invalid or has expired", http.StatusForbidden)
+		return
+	}
+	if !signed {
+		public, err := usedByPublicPost(r.Context(), &media)
+		if err != nil {
+			http.Error(w, "Failed to check access", http.StatusInternalServerError)
+			return
+		}
+		if !public {
+			http.Error(w, "A signed link is required for this media", http.StatusForbidden)
+			return
+		}
+	}
+
+	contentType, modified := media.ContentType, media.CreatedAt
+	if media.ProcessedAt != nil {
+		modified = *media.ProcessedAt
+	}
+	for _, v := range media.Variants {
+		if v.Key == key {
+			contentType = v.ContentType
+		}
+	}
+
+	blob, err := utils.Blobs.Open(r.Context(), key)
+	if err != nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	defer blob.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if signed {
+		w.Header().Set("Cache-Control", "private, max-age="+strconv.Itoa(int(utils.MediaURLTTL.Seconds())))
+	} else {
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+	}
+	// ServeContent answers Range requests (206) so video can seek.
+	http.ServeContent(w, r, "", modified, blob)
+}
+
+// usedByPublicPost tells whether a published public post of the media's
+// owner references it. Links in other people's posts do not make it public.
+func usedByPublicPost(ctx context.Context, media *models.Media) (bool, error) {
+	urls := []string{media.URL}
+	for _, v := range media.Variants {
+		urls = append(urls, v.URL)
+	}
+	n, err := config.PostCollection.CountDocuments(ctx, bson.M{
+		"author_id":  media.OwnerID,
+		"status":     models.PostStatusPublished,
+		"visibility": models.VisibilityPublic,
+		"media_urls": bson.M{"$in": urls},
+	}, options.Count().SetLimit(1))
+	return n > 0, err
+}
+
+// checkPostMedia makes sure a post only links to uploads of its author, so
+// nobody can publish someone else's private media through their own post. It
+// writes the error response itself and returns false when it fails.
+func checkPostMedia(w http.ResponseWriter, r *http.Request, authorID primitive.ObjectID, urls []string) bool {
+	foreign, err := utils.ForeignMediaURLs(r.Context(), authorID, urls)
+	if err != nil {
+		http.Error(w, "Failed to check media", http.StatusInternalServerError)
+		return false
+	}
+	if len(foreign) > 0 {
+		http.Error(w, "media_urls can only link to the author's own uploads: "+foreign[0], http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// findMedia loads the media record in the URL for its owner or an admin,
+// writing the error response itself when it cannot. action names what the
+// caller was about to do in the 403.
+func findMedia(w http.ResponseWriter, r *http.Request, action string) (*models.Media, bool) {
+	mediaID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid media ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	var media models.Media
+	if err := config.MediaCollection.FindOne(r.Context(), bson.M{"_id": mediaID}).Decode(&media); err != nil {
+		http.Error(w, "Media not found", http.StatusNotFound)
+		return nil, false
+	}
+
+	userID, _ := utils.ExtractUserIDFromRequest(r)
+	role, _ := utils.ExtractUserRole(r)
+	if media.OwnerID.Hex() != userID && !strings.EqualFold(role, "admin") {
+		http.Error(w, "Not allowed to "+action+" this media", http.StatusForbidden)
+		return nil, false
+	}
+	return &media, true
+}
+
+// GetMediaGCReport godoc
+// @Summary Preview media garbage collection
+// @Description Lists the uploads the next collector run would delete: unreferenced by any post for longer than the grace period. Nothing is deleted.
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} utils.MediaGCReport
+// @Failure 500 {string} string "Failed to build report"
+// @Router /api/v1/admin/media/gc [get]
+func GetMediaGCReport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	report, err := utils.MediaCollector.Run(r.Context(), true)
+	if err != nil {
+		http.Error(w, "Failed to build report", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(report)
+}
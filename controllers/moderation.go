@@ -0,0 +1,483 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-backend/config"
+	"go-backend/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Reports of a post collect in its active moderation case. Once enough
+// readers report it the post is hidden until a moderator claims the case and
+// either restores the post or keeps it hidden. Every decision is appended to
+// the case history.
+
+const maxReportDetails = 1000
+
+var errCaseChanged = errors.New("case changed meanwhile, reload it and try again")
+
+// openModerationCase returns the unresolved case of post, opening one if
+// there is none.
+func openModerationCase(ctx context.Context, post *models.Post) (*models.ModerationCase, error) {
+	now := time.Now()
+	filter := bson.M{"post_id": post.ID, "active": true}
+	update := bson.M{"$setOnInsert": bson.M{
+		"author_id":    post.AuthorID,
+		"status":       models.CaseStatusOpen,
+		"report_count": 0,
+		"created_at":   now,
+		"updated_at":   now,
+	}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var c models.ModerationCase
+	err := config.ModerationCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&c)
+	if mongo.IsDuplicateKeyError(err) {
+		// Another report opened the case first.
+		err = config.ModerationCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&c)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// setPostHidden hides or restores a post and tells whether that changed it.
+func setPostHidden(ctx context.Context, postID primitive.ObjectID, hidden bool, reason string) (bool, error) {
+	filter := bson.M{"_id": postID, "hidden": bson.M{"$ne": true}}
+	update := bson.M{"$set": bson.M{"hidden": true, "hide_reason": reason}, "$inc": bson.M{"version": 1}}
+	if !hidden {
+		filter["hidden"] = true
+		update = bson.M{"$unset": bson.M{"hidden": "", "hide_reason": ""}, "$inc": bson.M{"version": 1}}
+	}
+	result, err := config.PostCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// flagPost queues a post for review on behalf of the content filter.
+func flagPost(ctx context.Context, post *models.Post, reason string) {
+	c, err := openModerationCase(ctx, post)
+	if err == nil {
+		now := time.Now()
+		decision := models.ModerationDecision{Action: models.ModerationFlag, Role: "system", Note: reason, At: now}
+		_, err = config.ModerationCollection.UpdateOne(ctx, bson.M{"_id": c.ID}, bson.M{
+			"$push": bson.M{"history": decision},
+			"$set":  bson.M{"updated_at": now},
+		})
+	}
+	if err != nil {
+		config.Logger.Warnf("Failed to flag post %s: %v", post.ID.Hex(), err)
+	}
+}
+
+// closeModerationCases resolves the unresolved case of a deleted post.
+func closeModerationCases(ctx context.Context, postID primitive.ObjectID) {
+	now := time.Now()
+	_, err := config.ModerationCollection.UpdateMany(ctx, bson.M{"post_id": postID, "active": true}, bson.M{
+		"$set":   bson.M{"status": models.CaseStatusResolved, "resolution": "deleted", "resolved_at": now, "updated_at": now},
+		"$unset": bson.M{"active": ""},
+	})
+	if err != nil {
+		config.Logger.Warnf("Failed to close moderation case of post %s: %v", postID.Hex(), err)
+	}
+}
+
+// ReportPost godoc
+// @Summary Report a post
+// @Description Flags a published post for moderation. Each reader may report a post once per case. Posts reported by enough readers are hidden pending review.
+// @Tags Moderation
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param report body object true "reason (spam, scam, abuse, misleading, other) and optional details"
+// @Success 201 {string} string "Post reported"
+// @Failure 400 {string} string "Invalid reason"
+// @Failure 403 {string} string "You cannot report your own post"
+// @Failure 404 {string} string "Post not found"
+// @Failure 409 {string} string "You already reported this post"
+// @Router /api/v1/posts/{id}/report [post]
+func ReportPost(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	post, ok := findReadablePost(w, r, "id")
+	if !ok {
+		return
+	}
+	userID, _ := requestViewer(r)
+	if post.AuthorID == userID {
+		http.Error(w, "You cannot report your own post", http.StatusForbidden)
+		return
+	}
+
+	var input struct {
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if !models.ValidReportReason(input.Reason) {
+		http.Error(w, "Invalid reason", http.StatusBadRequest)
+		return
+	}
+	if len(input.Details) > maxReportDetails {
+		http.Error(w, "Details are too long", http.StatusBadRequest)
+		return
+	}
+
+	c, err := openModerationCase(r.Context(), post)
+	if err != nil {
+		http.Error(w, "Failed to report post", http.StatusInternalServerError)
+		return
+	}
+	report := models.Report{
+		CaseID:     c.ID,
+		PostID:     post.ID,
+		ReporterID: userID,
+		Reason:     input.Reason,
+		Details:    input.Details,
+		CreatedAt:  time.Now(),
+	}
+	if _, err := config.ReportCollection.InsertOne(r.Context(), report); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			http.Error(w, "You already reported this post", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to report post", http.StatusInternalServerError)
+		return
+	}
+
+	err = config.ModerationCollection.FindOneAndUpdate(r.Context(), bson.M{"_id": c.ID}, bson.M{
+		"$inc": bson.M{"report_count": 1, "reasons." + input.Reason: 1},
+		"$set": bson.M{"updated_at": time.Now()},
+	}, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(c)
+	if err != nil {
+		http.Error(w, "Failed to report post", http.StatusInternalServerError)
+		return
+	}
+
+	if c.ReportCount >= config.ModerationHideThreshold() && c.Status == models.CaseStatusOpen {
+		reason := fmt.Sprintf("Reported by %d readers", c.ReportCount)
+		hidden, err := setPostHidden(r.Context(), post.ID, true, reason)
+		if err != nil {
+			config.Logger.Warnf("Failed to hide reported post %s: %v", post.ID.Hex(), err)
+		} else if hidden {
+			decision := models.ModerationDecision{Action: models.ModerationAutoHide, Role: "system", Note: reason, At: time.Now()}
+			_, err := config.ModerationCollection.UpdateOne(r.Context(), bson.M{"_id": c.ID}, bson.M{"$push": bson.M{"history": decision}})
+			if err != nil {
+				config.Logger.Warnf("Failed to record hiding post %s: %v", post.ID.Hex(), err)
+			}
+		}
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Post reported"})
+}
+
+// attachCasePosts fills in the post of each case; deleted posts stay nil.
+func attachCasePosts(ctx context.Context, cases []models.ModerationCase) error {
+	if len(cases) == 0 {
+		return nil
+	}
+	ids := make([]primitive.ObjectID, len(cases))
+	for i, c := range cases {
+		ids[i] = c.PostID
+	}
+	cursor, err := config.PostCollection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return err
+	}
+	var posts []models.Post
+	if err := cursor.All(ctx, &posts); err != nil {
+		return err
+	}
+	byID := make(map[primitive.ObjectID]*models.Post, len(posts))
+	for i := range posts {
+		byID[posts[i].ID] = &posts[i]
+	}
+	for i := range cases {
+		cases[i].Post = byID[cases[i].PostID]
+	}
+	return nil
+}
+
+// GetModerationQueue godoc
+// @Summary List moderation cases
+// @Description Unresolved cases by default, most reported first.
+// @Tags Moderation
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "open, claimed or resolved"
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Items per page (default 20, max 100)"
+// @Success 200 {array} models.ModerationCase
+// @Failure 400 {string} string "Invalid status"
+// @Router /api/v1/moderation/queue [get]
+func GetModerationQueue(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	filter := bson.M{"active": true}
+	sort := bson.D{{Key: "report_count", Value: -1}, {Key: "created_at", Value: 1}}
+	switch status := r.URL.Query().Get("status"); status {
+	case "":
+	case models.CaseStatusOpen, models.CaseStatusClaimed:
+		filter["status"] = status
+	case models.CaseStatusResolved:
+		filter = bson.M{"status": status}
+		sort = bson.D{{Key: "resolved_at", Value: -1}}
+	default:
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+	limit := pageLimit(r, 20)
+	page := 1
+	if v, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && v > 0 {
+		page = v
+	}
+
+	total, err := config.ModerationCollection.CountDocuments(r.Context(), filter)
+	if err != nil {
+		http.Error(w, "Failed to fetch cases", http.StatusInternalServerError)
+		return
+	}
+	cursor, err := config.ModerationCollection.Find(r.Context(), filter,
+		options.Find().SetSort(sort).SetSkip(int64((page-1)*limit)).SetLimit(int64(limit)))
+	if err != nil {
+		http.Error(w, "Failed to fetch cases", http.StatusInternalServerError)
+		return
+	}
+	cases := []models.ModerationCase{}
+	if err := cursor.All(r.Context(), &cases); err != nil {
+		http.Error(w, "Failed to parse cases", http.StatusInternalServerError)
+		return
+	}
+	if err := attachCasePosts(r.Context(), cases); err != nil {
+		http.Error(w, "Failed to fetch posts", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"cases": cases, "total": total, "page": page, "limit": limit})
+}
+
+// findCase loads the case in the URL, writing the error response itself
+// when it cannot.
+func findCase(w http.ResponseWriter, r *http.Request) (*models.ModerationCase, bool) {
+	caseID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid case ID", http.StatusBadRequest)
+		return nil, false
+	}
+	var c models.ModerationCase
+	if err := config.ModerationCollection.FindOne(r.Context(), bson.M{"_id": caseID}).Decode(&c); err != nil {
+		http.Error(w, "Case not found", http.StatusNotFound)
+		return nil, false
+	}
+	return &c, true
+}
+
+// GetModerationCase godoc
+// @Summary Get a moderation case
+// @Description Includes the post, every report and the decision history.
+// @Tags Moderation
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Case ID"
+// @Success 200 {object} models.ModerationCase
+// @Failure 404 {string} string "Case not found"
+// @Router /api/v1/moderation/cases/{id} [get]
+func GetModerationCase(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	c, ok := findCase(w, r)
+	if !ok {
+		return
+	}
+	cursor, err := config.ReportCollection.Find(r.Context(), bson.M{"case_id": c.ID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		http.Error(w, "Failed to fetch reports", http.StatusInternalServerError)
+		return
+	}
+	c.Reports = []models.Report{}
+	if err := cursor.All(r.Context(), &c.Reports); err != nil {
+		http.Error(w, "Failed to parse reports", http.StatusInternalServerError)
+		return
+	}
+	cases := []models.ModerationCase{*c}
+	if err := attachCasePosts(r.Context(), cases); err != nil {
+		http.Error(w, "Failed to fetch post", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(cases[0])
+}
+
+// moderate applies action to the case in the URL on behalf of the caller and
+// records the decision. The case must still have the status and claim it was
+// checked against, so two moderators cannot both claim it.
+func moderate(w http.ResponseWriter, r *http.Request, action string) {
+	w.Header().Set("Content-Type", "application/json")
+
+	c, ok := findCase(w, r)
+	if !ok {
+		return
+	}
+	var input struct {
+		Note string `json:"note"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+	}
+	if len(input.Note) > maxReportDetails {
+		http.Error(w, "Note is too long", http.StatusBadRequest)
+		return
+	}
+
+	userID, role := requestViewer(r)
+	now := time.Now()
+	if err := c.CheckAction(action, userID, role, now); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	set := bson.M{"updated_at": now}
+	update := bson.M{"$set": set}
+	switch action {
+	case models.ModerationClaim:
+		set["status"] = models.CaseStatusClaimed
+		set["claimed_by"] = userID
+		set["claimed_at"] = now
+	case models.ModerationResolve:
+		var post models.Post
+		err := config.PostCollection.FindOne(r.Context(), bson.M{"_id": c.PostID},
+			options.FindOne().SetProjection(bson.M{"hidden": 1})).Decode(&post)
+		switch {
+		case err == mongo.ErrNoDocuments:
+			set["resolution"] = "deleted"
+		case err != nil:
+			http.Error(w, "Failed to resolve case", http.StatusInternalServerError)
+			return
+		case post.Hidden:
+			set["resolution"] = "hidden"
+		default:
+			set["resolution"] = "kept"
+		}
+		set["status"] = models.CaseStatusResolved
+		set["resolved_at"] = now
+		update["$unset"] = bson.M{"active": ""}
+	}
+	update["$push"] = bson.M{"history": models.ModerationDecision{Action: action, By: userID, Role: role, Note: input.Note, At: now}}
+
+	err := config.ModerationCollection.FindOneAndUpdate(r.Context(),
+		bson.M{"_id": c.ID, "status": c.Status, "claimed_at": c.ClaimedAt}, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(c)
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, errCaseChanged.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update case", http.StatusInternalServerError)
+		return
+	}
+
+	// The post only changes once the decision is on record. Hiding and
+	// restoring are idempotent, so a failure here is fixed by repeating it.
+	if action == models.ModerationHide || action == models.ModerationRestore {
+		reason := input.Note
+		if reason == "" {
+			reason = "Hidden by a moderator"
+		}
+		if _, err := setPostHidden(r.Context(), c.PostID, action == models.ModerationHide, reason); err != nil {
+			http.Error(w, "Decision recorded but the post could not be updated, please retry", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	json.NewEncoder(w).Encode(c)
+}
+
+// ClaimModerationCase godoc
+// @Summary Claim a moderation case
+// @Description Keeps other moderators off the case for an hour. Admins may take over any case.
+// @Tags Moderation
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Case ID"
+// @Param note body object false "Optional note"
+// @Success 200 {object} models.ModerationCase
+// @Failure 404 {string} string "Case not found"
+// @Failure 409 {string} string "Case is claimed by another moderator"
+// @Router /api/v1/moderation/cases/{id}/claim [post]
+func ClaimModerationCase(w http.ResponseWriter, r *http.Request) {
+	moderate(w, r, models.ModerationClaim)
+}
+
+// HideModeratedPost godoc
+// @Summary Hide the post of a claimed case
+// @Description The note is kept as the reason shown to the author.
+// @Tags Moderation
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Case ID"
+// @Param note body object false "Optional note"
+// @Success 200 {object} models.ModerationCase
+// @Failure 404 {string} string "Case not found"
+// @Failure 409 {string} string "Claim the case before acting on it"
+// @Router /api/v1/moderation/cases/{id}/hide [post]
+func HideModeratedPost(w http.ResponseWriter, r *http.Request) {
+	moderate(w, r, models.ModerationHide)
+}
+
+// RestoreModeratedPost godoc
+// @Summary Restore the post of a claimed case
+// @Tags Moderation
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Case ID"
+// @Param note body object false "Optional note"
+// @Success 200 {object} models.ModerationCase
+// @Failure 404 {string} string "Case not found"
+// @Failure 409 {string} string "Claim the case before acting on it"
+// @Router /api/v1/moderation/cases/{id}/restore [post]
+func RestoreModeratedPost(w http.ResponseWriter, r *http.Request) {
+	moderate(w, r, models.ModerationRestore)
+}
+
+// ResolveModerationCase godoc
+// @Summary Resolve a moderation case
+// @Description Closes the case, leaving the post hidden or visible as it is. Later reports open a new case.
+// @Tags Moderation
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Case ID"
+// @Param note body object false "Optional note"
+// @Success 200 {object} models.ModerationCase
+// @Failure 404 {string} string "Case not found"
+// @Failure 409 {string} string "Case is already resolved"
+// @Router /api/v1/moderation/cases/{id}/resolve [post]
+func ResolveModerationCase(w http.ResponseWriter, r *http.Request) {
+	moderate(w, r, models.ModerationResolve)
+}
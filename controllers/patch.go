@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"errors"
+	"go-backend/models"
+	"go-backend/utils"
+	"net/http"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Partial updates take JSON Merge Patch documents (RFC 7396) limited to a
+// whitelist of fields. Every write to a post or user, including workflow,
+// trade, collaborator and moderation changes, bumps the document's version,
+// which is served as its ETag; PATCH requires a matching If-Match so an edit based on
+// a stale read fails with 412 instead of overwriting someone else's.
+
+// Fields a merge patch may change.
+var (
+	postPatchFields  = []string{"title", "content", "tags", "visibility", "media_urls"}
+	selfPatchFields  = []string{"name", "handle"}
+	adminPatchFields = []string{"name", "handle", "email", "role", "is_active"}
+)
+
+// readPatch reads a merge patch limited to fields, writing the error
+// response itself when it cannot.
+func readPatch(w http.ResponseWriter, r *http.Request, fields []string) (map[string]interface{}, bool) {
+	patch, err := utils.ReadMergePatch(r, fields)
+	if errors.Is(err, utils.ErrPatchMediaType) {
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return patch, true
+}
+
+// checkIfMatch enforces If-Match against the current version, writing 428
+// or 412 itself when it fails. Without required a missing header passes.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, version int64, required bool) bool {
+	if r.Header.Get("If-Match") == "" {
+		if required {
+			http.Error(w, "If-Match is required", http.StatusPreconditionRequired)
+			return false
+		}
+		return true
+	}
+	if !utils.IfMatch(r, utils.VersionETag(version)) {
+		http.Error(w, "Version does not match, reload and try again", http.StatusPreconditionFailed)
+		return false
+	}
+	return true
+}
+
+// patchUpdate sets each patched field to its value in the BSON form of doc,
+// or unsets it when doc leaves it out, and bumps the version. It relies on
+// patchable fields having the same JSON and BSON names.
+func patchUpdate(doc interface{}, patch map[string]interface{}, set bson.M) (bson.M, error) {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var values bson.M
+	if err := bson.Unmarshal(raw, &values); err != nil {
+		return nil, err
+	}
+
+	unset := bson.M{}
+	for field := range patch {
+		if v, ok := values[field]; ok {
+			set[field] = v
+		} else {
+			unset[field] = ""
+		}
+	}
+	update := bson.M{"$set": set, "$inc": bson.M{"version": 1}}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return update, nil
+}
+
+// validVisibility tells whether v is one of the known visibilities.
+func validVisibility(v models.Visibility) bool {
+	switch v {
+	case models.VisibilityPublic, models.VisibilityPrivate, models.VisibilityPremium:
+		return true
+	}
+	return false
+}
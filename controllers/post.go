@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"go-backend/config"
@@ -15,12 +16,15 @@ import (
 	"github.com/gorilla/mux"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+const errContentLocked = "Only admins can change the title and content of a post that has passed review"
+
 // CreatePost godoc
 // @Summary Create a new post
-// @Description Allows authors or admins to create posts (draft, scheduled, or published)
+// @Description Allows authors or admins to create draft posts. Use the workflow endpoints to submit and publish them.
 // @Tags Posts
 // @Security BearerAuth
 // @Accept json
@@ -29,6 +33,7 @@ import (
 // @Success 201 {object} models.Post
 // @Failure 400 {string} string "Invalid input"
 // @Failure 403 {string} string "Forbidden"
+// @Failure 422 {string} string "Post rejected by the content filter"
 // @Failure 500 {string} string "Internal error"
 // @Router /api/v1/posts [post]
 func CreatePost(w http.ResponseWriter, r *http.Request) {
@@ -56,26 +61,52 @@ fmt.Println("Extracted userID from JWT:", userID)
 		return
 	}
 
+	if err := validateTradePost(&post); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validatePostDates(post.ScheduledAt, post.ExpiresAt, time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	verdict, ok := screenPost(w, r, &post)
+	if !ok {
+		return
+	}
+
 	post.ID = primitive.NewObjectID()
 	post.AuthorID = authorID
+	post.MediaURLs = utils.CanonicalMediaURLs(post.MediaURLs)
+	if !checkPostMedia(w, r, authorID, post.MediaURLs) {
+		return
+	}
+	post.SlugHistory = nil
 	post.CreatedAt = time.Now()
 	post.UpdatedAt = time.Now()
-	post.Status = "draft"
-
-	if post.ScheduledAt != nil {
-		if post.ScheduledAt.Before(time.Now()) {
-			post.Status = "published"
-			post.PublishedAt = post.ScheduledAt
-		} else {
-			post.Status = "scheduled"
-		}
-	}
-
-	_, err = config.PostCollection.InsertOne(r.Context(), post)
+	// Every post starts as a draft; ScheduledAt is applied when it is published.
+	post.Status = models.PostStatusDraft
+	post.PublishedAt = nil
+	post.StatusHistory = nil
+	post.Hidden, post.HideReason = false, ""
+	post.Version = 0
+
+	err = utils.WriteWithSlug(r.Context(), func(ctx context.Context) (string, error) {
+		return utils.UniquePostSlug(ctx, post.Title, post.ID)
+	}, func(slug string) error {
+		post.Slug = slug
+		_, err := config.PostCollection.InsertOne(r.Context(), post)
+		return err
+	})
 	if err != nil {
 		http.Error(w, "Failed to create post", http.StatusInternalServerError)
 		return
 	}
+	if err := utils.SyncMediaRefs(r.Context(), post.ID, post.MediaURLs); err != nil {
+		config.Logger.Warnf("Failed to track media of post %s: %v", post.ID.Hex(), err)
+	}
+	if verdict.Action == models.FilterFlag {
+		flagPost(r.Context(), &post, verdict.Reason())
+	}
 
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(post)
@@ -83,12 +114,17 @@ fmt.Println("Extracted userID from JWT:", userID)
 
 // ListPosts godoc
 // @Summary List published posts
-// @Description Returns public posts, filtered by type and visibility
+// @Description Returns the published posts the caller may see, filtered by type and visibility. Other statuses can only be listed by admins, or by authors for their own posts.
 // @Tags Posts
 // @Produce json
+// @Param status query string false "Post status, published by default"
+// @Param author_id query string false "Only posts by this author"
 // @Param type query string false "Post type (idea, trade)"
 // @Param visibility query string false "Post visibility (public, private, premium)"
+// @Param sort query string false "Field to sort by, prefixed with - for descending, or trending"
 // @Success 200 {array} models.Post
+// @Failure 400 {string} string "Invalid author ID"
+// @Failure 403 {string} string "Not allowed to list unpublished posts"
 // @Failure 500 {string} string "Failed to fetch posts"
 // @Router /api/v1/posts [get]
 func ListPosts(w http.ResponseWriter, r *http.Request) {
@@ -110,12 +146,26 @@ func ListPosts(w http.ResponseWriter, r *http.Request) {
 	}
 	skip := (page - 1) * limit
 
-	// Build filter
-	filter := bson.M{}
-	if status := query.Get("status"); status != "" {
-		filter["status"] = strings.ToLower(status)
-	} else {
-		filter["status"] = "published" // Default to published if no status specified
+	// Build filter, starting from what the caller may see
+	viewerID, role := requestViewer(r)
+	filter := models.VisibilityFilter(viewerID, role)
+	if authorID := query.Get("author_id"); authorID != "" {
+		id, err := primitive.ObjectIDFromHex(authorID)
+		if err != nil {
+			http.Error(w, "Invalid author ID", http.StatusBadRequest)
+			return
+		}
+		filter["author_id"] = id
+	}
+	status := strings.ToLower(query.Get("status"))
+	if status == "" {
+		status = models.PostStatusPublished // Default to published if no status specified
+	}
+	filter["status"] = status
+	// Unpublished posts are only listed to their author and admins.
+	if status != models.PostStatusPublished && role != "admin" && (viewerID.IsZero() || filter["author_id"] != viewerID) {
+		http.Error(w, "Only your own unpublished posts can be listed, pass author_id", http.StatusForbidden)
+		return
 	}
 
 	// Type filter
@@ -130,10 +180,15 @@ func ListPosts(w http.ResponseWriter, r *http.Request) {
 
 	// Search
 	if search := query.Get("search"); search != "" {
-		filter["$or"] = []bson.M{
+		filter["$and"] = []bson.M{{"$or": []bson.M{
 			{"title": bson.M{"$regex": search, "$options": "i"}},
 			{"content": bson.M{"$regex": search, "$options": "i"}},
-		}
+		}}}
+	}
+
+	if query.Get("sort") == "trending" {
+		listTrendingPosts(w, r, filter, page, limit)
+		return
 	}
 
 	// Sort
@@ -151,7 +206,7 @@ func ListPosts(w http.ResponseWriter, r *http.Request) {
 
 	// Build options
 	findOptions := options.Find().
-		SetSort(bson.D{{sortField, sortOrder}}).
+		SetSort(bson.D{{Key: sortField, Value: sortOrder}}).
 		SetSkip(int64(skip)).
 		SetLimit(int64(limit))
 
@@ -176,6 +231,11 @@ func ListPosts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := preparePosts(r, posts); err != nil {
+		http.Error(w, "Failed to render posts", http.StatusInternalServerError)
+		return
+	}
+
 	// Build response
 	response := map[string]interface{}{
 		"posts": posts,
@@ -192,6 +252,7 @@ func ListPosts(w http.ResponseWriter, r *http.Request) {
 
 // GetPost godoc
 // @Summary Get a post by ID
+// @Description Published posts for readers; the owner, collaborators and admins also read drafts.
 // @Tags Posts
 // @Produce json
 // @Param id path string true "Post ID"
@@ -209,29 +270,43 @@ func GetPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Unpublished posts are only found for the people working on them.
+	filter := bson.M{"_id": postID, "status": models.PostStatusPublished}
+	if viewerID, role := requestViewer(r); role == "admin" {
+		delete(filter, "status")
+	} else if !viewerID.IsZero() {
+		filter = bson.M{"_id": postID, "$or": []bson.M{
+			{"status": models.PostStatusPublished},
+			models.SharedFilter(viewerID),
+		}}
+	}
+
 	var post models.Post
-	err = config.PostCollection.FindOne(r.Context(), bson.M{"_id": postID}).Decode(&post)
-	if err != nil || post.Status != "published" {
+	if err := config.PostCollection.FindOne(r.Context(), filter).Decode(&post); err != nil {
 		http.Error(w, "Post not found", http.StatusNotFound)
 		return
 	}
 
-	json.NewEncoder(w).Encode(post)
+	writePublishedPost(w, r, &post)
 }
 
 // UpdatePost godoc
 // @Summary Update a post
-// @Description Authors can update their own posts
+// @Description Authors, co-authors and editors can update posts. If-Match with the post's ETag is optional; a stale one fails with 412. Title and content of approved, scheduled and published posts can only be changed by admins.
 // @Tags Posts
 // @Security BearerAuth
 // @Accept json
 // @Produce json
 // @Param id path string true "Post ID"
+// @Param If-Match header string false "ETag of the post being edited"
 // @Param post body models.Post true "Post object"
 // @Success 200 {string} string "Post updated"
 // @Failure 400 {string} string "Bad input"
 // @Failure 403 {string} string "Forbidden"
 // @Failure 404 {string} string "Post not found"
+// @Failure 409 {string} string "Status can only change through the workflow, or the post has passed review"
+// @Failure 412 {string} string "Version does not match"
+// @Failure 422 {string} string "Post rejected by the content filter"
 // @Router /api/v1/posts/{id} [put]
 func UpdatePost(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -253,18 +328,243 @@ func UpdatePost(w http.ResponseWriter, r *http.Request) {
 	updates.UpdatedAt = time.Now()
 	authorID, _ := primitive.ObjectIDFromHex(userID)
 
-	filter := bson.M{"_id": postID, "author_id": authorID}
-	update := bson.M{"$set": updates}
+	// The owner, co-authors and editors may edit the post.
+	filter := models.EditableFilter(authorID)
+	filter["_id"] = postID
 
-	result, err := config.PostCollection.UpdateOne(r.Context(), filter, update)
-	if err != nil || result.MatchedCount == 0 {
+	var existing models.Post
+	if err := config.PostCollection.FindOne(r.Context(), filter).Decode(&existing); err != nil {
 		http.Error(w, "Update failed or not authorized", http.StatusForbidden)
 		return
 	}
+	if !checkIfMatch(w, r, existing.Version, false) {
+		return
+	}
+
+	// Status only changes through the workflow endpoints.
+	if updates.Status != "" && updates.Status != existing.Status {
+		http.Error(w, "Use the workflow endpoints to change post status", http.StatusConflict)
+		return
+	}
+	role, _ := utils.ExtractUserRole(r)
+	if (updates.Title != existing.Title || updates.Content != existing.Content) && models.ContentLocked(existing.Status, strings.ToLower(role)) {
+		http.Error(w, errContentLocked, http.StatusConflict)
+		return
+	}
+	// Collaborators edit the post, they do not take it over.
+	updates.AuthorID, updates.CreatedAt = existing.AuthorID, existing.CreatedAt
+	updates.Status = existing.Status
+	updates.StatusHistory = nil
+	updates.PublishedAt = nil
+	updates.Hidden, updates.HideReason = false, "" // only moderation hides posts
+	updates.Version = 0
+	updates.MediaURLs = utils.CanonicalMediaURLs(updates.MediaURLs)
+	if !checkPostMedia(w, r, existing.AuthorID, updates.MediaURLs) {
+		return
+	}
+
+	// Scheduled and published posts are rescheduled through /schedule so the
+	// status stays in step with scheduled_at.
+	if updates.ScheduledAt != nil && (existing.Status == models.PostStatusScheduled || existing.Status == models.PostStatusPublished) {
+		http.Error(w, "Use the schedule endpoint to change scheduled_at", http.StatusConflict)
+		return
+	}
+	scheduledAt, expiresAt := existing.ScheduledAt, existing.ExpiresAt
+	if updates.ScheduledAt != nil {
+		scheduledAt = updates.ScheduledAt
+	}
+	if updates.ExpiresAt != nil {
+		expiresAt = updates.ExpiresAt
+	}
+	if updates.ScheduledAt != nil || updates.ExpiresAt != nil {
+		if err := validatePostDates(scheduledAt, expiresAt, time.Now()); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if updates.Type == models.PostTypeTrade || updates.Trade != nil {
+		if updates.Type == "" {
+			updates.Type = existing.Type
+		}
+		if updates.Trade != nil && existing.Trade != nil && existing.Trade.Status != models.TradeStatusPending {
+			http.Error(w, "Trade setup can no longer be changed once filled", http.StatusConflict)
+			return
+		}
+		// A resent type with no trade body keeps the stored setup as is.
+		if updates.Trade != nil || existing.Trade == nil {
+			if err := validateTradePost(&updates); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	verdict, ok := screenPost(w, r, &updates)
+	if !ok {
+		return
+	}
+
+	filter["version"] = utils.VersionFilter(existing.Version)
+
+	// The slug follows the title; old slugs are kept so links keep working.
+	var result *mongo.UpdateResult
+	err = utils.WriteWithSlug(r.Context(), func(ctx context.Context) (string, error) {
+		if updates.Title == "" || updates.Title == existing.Title {
+			return existing.Slug, nil
+		}
+		return utils.UniquePostSlug(ctx, updates.Title, postID)
+	}, func(slug string) error {
+		updates.Slug, updates.SlugHistory = "", nil
+		if slug != existing.Slug {
+			updates.Slug = slug
+			updates.SlugHistory = renamedSlugHistory(existing.SlugHistory, existing.Slug, slug)
+		}
+		update := bson.M{"$set": updates, "$inc": bson.M{"version": 1}}
+		var err error
+		result, err = config.PostCollection.UpdateOne(r.Context(), filter, update)
+		return err
+	})
+	if err != nil {
+		http.Error(w, "Update failed or not authorized", http.StatusForbidden)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, "Version does not match, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+	if updates.MediaURLs != nil {
+		if err := utils.SyncMediaRefs(r.Context(), postID, updates.MediaURLs); err != nil {
+			config.Logger.Warnf("Failed to track media of post %s: %v", postID.Hex(), err)
+		}
+	}
+	if verdict.Action == models.FilterFlag {
+		flagPost(r.Context(), &existing, verdict.Reason())
+	}
 
 	json.NewEncoder(w).Encode(map[string]string{"message": "Post updated"})
 }
 
+// PatchPost godoc
+// @Summary Partially update a post
+// @Description Applies a JSON Merge Patch (RFC 7396) to title, content, tags, visibility and media_urls; null removes a field. Requires If-Match with the post's ETag and returns the updated post with its new ETag. Title and content of approved, scheduled and published posts can only be changed by admins.
+// @Tags Posts
+// @Security BearerAuth
+// @Accept application/merge-patch+json
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param If-Match header string true "ETag of the post being edited"
+// @Param patch body object true "Merge patch"
+// @Success 200 {object} models.Post
+// @Failure 400 {string} string "Bad input"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Post not found"
+// @Failure 409 {string} string "The post has passed review"
+// @Failure 412 {string} string "Version does not match"
+// @Failure 415 {string} string "Content-Type must be application/merge-patch+json"
+// @Failure 422 {string} string "Post rejected by the content filter"
+// @Failure 428 {string} string "If-Match is required"
+// @Router /api/v1/posts/{id} [patch]
+func PatchPost(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	postID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+	patch, ok := readPatch(w, r, postPatchFields)
+	if !ok {
+		return
+	}
+
+	var existing models.Post
+	if err := config.PostCollection.FindOne(r.Context(), bson.M{"_id": postID}).Decode(&existing); err != nil {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	}
+	userID, role := requestViewer(r)
+	if !existing.CanEdit(userID) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if !checkIfMatch(w, r, existing.Version, true) {
+		return
+	}
+
+	post := existing
+	if err := utils.ApplyMergePatch(&post, patch); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if (post.Title != existing.Title || post.Content != existing.Content) && models.ContentLocked(existing.Status, role) {
+		http.Error(w, errContentLocked, http.StatusConflict)
+		return
+	}
+	if post.Title == "" {
+		http.Error(w, "title cannot be empty", http.StatusBadRequest)
+		return
+	}
+	if post.Visibility == "" {
+		post.Visibility = models.VisibilityPublic
+	}
+	if !validVisibility(post.Visibility) {
+		http.Error(w, "visibility must be public, private or premium", http.StatusBadRequest)
+		return
+	}
+	if post.Tags == nil {
+		post.Tags = []string{}
+	}
+	post.MediaURLs = utils.CanonicalMediaURLs(post.MediaURLs)
+	if _, ok := patch["media_urls"]; ok && !checkPostMedia(w, r, existing.AuthorID, post.MediaURLs) {
+		return
+	}
+	verdict, ok := screenPost(w, r, &post)
+	if !ok {
+		return
+	}
+
+	filter := models.EditableFilter(userID)
+	filter["_id"], filter["version"] = postID, utils.VersionFilter(existing.Version)
+	err = utils.WriteWithSlug(r.Context(), func(ctx context.Context) (string, error) {
+		if post.Title == existing.Title {
+			return existing.Slug, nil
+		}
+		return utils.UniquePostSlug(ctx, post.Title, postID)
+	}, func(slug string) error {
+		set := bson.M{"updated_at": time.Now()}
+		if slug != existing.Slug {
+			set["slug"] = slug
+			set["slug_history"] = renamedSlugHistory(existing.SlugHistory, existing.Slug, slug)
+		}
+		update, err := patchUpdate(post, patch, set)
+		if err != nil {
+			return err
+		}
+		return config.PostCollection.FindOneAndUpdate(r.Context(), filter, update,
+			options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&post)
+	})
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, "Version does not match, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update post", http.StatusInternalServerError)
+		return
+	}
+	if _, ok := patch["media_urls"]; ok {
+		if err := utils.SyncMediaRefs(r.Context(), postID, post.MediaURLs); err != nil {
+			config.Logger.Warnf("Failed to track media of post %s: %v", postID.Hex(), err)
+		}
+	}
+	if verdict.Action == models.FilterFlag {
+		flagPost(r.Context(), &post, verdict.Reason())
+	}
+
+	w.Header().Set("ETag", utils.VersionETag(post.Version))
+	json.NewEncoder(w).Encode(post)
+}
+
 // DeletePost godoc
 // @Summary Delete a post
 // @Description Authors can delete their own posts
@@ -294,25 +594,30 @@ func DeletePost(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Delete failed or not authorized", http.StatusForbidden)
 		return
 	}
+	if err := utils.SyncMediaRefs(r.Context(), postID, nil); err != nil {
+		config.Logger.Warnf("Failed to release media of post %s: %v", postID.Hex(), err)
+	}
+	forgetPost(r.Context(), postID)
+	closeModerationCases(r.Context(), postID)
 
 	json.NewEncoder(w).Encode(map[string]string{"message": "Post deleted"})
 }
 
 // ListMyPosts godoc
 // @Summary List posts by current user
-// @Description Returns all posts by the logged-in user
+// @Description Returns all posts the logged-in user owns or collaborates on, drafts included. Any signed in user can call it, so viewers find the drafts shared with them.
 // @Tags Posts
 // @Security BearerAuth
 // @Success 200 {array} models.Post
 // @Failure 500 {string} string "Server error"
-// @Router /api/v1/posts/me [get]
+// @Router /api/v1/posts/my [get]
 func ListMyPosts(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	userID, _ := utils.ExtractUserIDFromRequest(r)
 	authorID, _ := primitive.ObjectIDFromHex(userID)
 
-	filter := bson.M{"author_id": authorID}
+	filter := models.SharedFilter(authorID)
 
 	cursor, err := config.PostCollection.Find(r.Context(), filter)
 	if err != nil {
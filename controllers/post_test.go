@@ -11,8 +11,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/mux"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -62,6 +64,32 @@ func TestCreatePost(t *testing.T) {
 	}
 }
 
+func TestCreatePost_ForeignMedia(t *testing.T) {
+	authorID, ownerID := primitive.NewObjectID(), primitive.NewObjectID()
+	media := models.Media{ID: primitive.NewObjectID(), OwnerID: ownerID, Key: "test/" + ownerID.Hex(), Status: models.MediaStatusReady, CreatedAt: time.Now()}
+	media.URL = config.PublicBaseURL() + "/media/" + media.Key
+	_, _ = config.MediaCollection.InsertOne(context.TODO(), media)
+
+	payload := models.Post{
+		Title:      "Borrowed media",
+		Content:    "Hello from test",
+		Type:       "idea",
+		Visibility: "public",
+		MediaURLs:  []string{media.URL},
+	}
+	body, _ := json.Marshal(payload)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/posts", bytes.NewReader(body))
+	req = bearerAuth(req, authorID.Hex(), "author")
+	w := httptest.NewRecorder()
+
+	CreatePost(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 Bad Request, got %d", w.Code)
+	}
+}
+
 func TestListPosts(t *testing.T) {
 	authorID := primitive.NewObjectID()
 	now := time.Now().Add(-time.Hour)
@@ -77,6 +105,38 @@ func TestListPosts(t *testing.T) {
 	}
 }
 
+func TestListPosts_OthersDrafts(t *testing.T) {
+	authorID := primitive.NewObjectID()
+	setupTestPost(authorID, "draft", nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/posts?status=draft&author_id="+authorID.Hex(), nil)
+	req = bearerAuth(req, primitive.NewObjectID().Hex(), "author")
+	w := httptest.NewRecorder()
+
+	ListPosts(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 Forbidden for another author, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/posts?status=draft&author_id="+authorID.Hex(), nil)
+	req = bearerAuth(req, authorID.Hex(), "author")
+	w = httptest.NewRecorder()
+
+	ListPosts(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 OK for the owner, got %d", w.Code)
+	}
+	var response struct {
+		Posts []models.Post `json:"posts"`
+	}
+	_ = json.NewDecoder(w.Body).Decode(&response)
+	if len(response.Posts) != 1 {
+		t.Errorf("expected the owner's draft, got %d posts", len(response.Posts))
+	}
+}
+
 func TestListMyPosts(t *testing.T) {
 	authorID := primitive.NewObjectID()
 	setupTestPost(authorID, "draft", nil)
@@ -113,6 +173,39 @@ func TestUpdatePost(t *testing.T) {
 	}
 }
 
+func TestUpdatePost_EditorKeepsAuthor(t *testing.T) {
+	authorID, editorID := primitive.NewObjectID(), primitive.NewObjectID()
+	post := setupTestPost(authorID, "draft", nil)
+	collaborators := []models.Collaborator{{UserID: editorID, Role: models.CollaboratorEditor, AddedAt: time.Now()}}
+	_, _ = config.PostCollection.UpdateOne(context.TODO(), bson.M{"_id": post.ID}, bson.M{"$set": bson.M{"collaborators": collaborators}})
+
+	update := models.Post{
+		AuthorID: editorID,
+		Title:    "Edited by an editor",
+		Content:  post.Content,
+	}
+	body, _ := json.Marshal(update)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/posts/"+post.ID.Hex(), bytes.NewReader(body))
+	req = muxWithParams(req, "id", post.ID.Hex())
+	req = bearerAuth(req, editorID.Hex(), "author")
+	w := httptest.NewRecorder()
+
+	UpdatePost(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 OK, got %d", w.Code)
+	}
+	var stored models.Post
+	_ = config.PostCollection.FindOne(context.TODO(), bson.M{"_id": post.ID}).Decode(&stored)
+	if stored.AuthorID != authorID {
+		t.Errorf("expected author %s to keep the post, got %s", authorID.Hex(), stored.AuthorID.Hex())
+	}
+	if stored.CreatedAt.IsZero() {
+		t.Error("expected created_at to be kept")
+	}
+}
+
 func TestDeletePost(t *testing.T) {
 	authorID := primitive.NewObjectID()
 	post := setupTestPost(authorID, "draft", nil)
@@ -137,6 +230,18 @@ func injectTestAuth(req *http.Request, userID string, role string) *http.Request
 }
 
 
+// bearerAuth signs a token for userID, for handlers that read the caller
+// from the Authorization header.
+func bearerAuth(req *http.Request, userID string, role string) *http.Request {
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": userID,
+		"role":    role,
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}).SignedString(jwtSecret)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
 func muxWithParams(req *http.Request, key, value string) *http.Request {
 	vars := map[string]string{key: value}
 	return mux.SetURLVars(req, vars)
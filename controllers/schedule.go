@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"go-backend/config"
+	"go-backend/models"
+	"go-backend/utils"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// validatePostDates checks the publish window of a post. Either end may be nil.
+func validatePostDates(scheduledAt, expiresAt *time.Time, now time.Time) error {
+	if expiresAt != nil && !expiresAt.After(now) {
+		return errors.New("expires_at must be in the future")
+	}
+	if scheduledAt != nil && expiresAt != nil && !expiresAt.After(*scheduledAt) {
+		return errors.New("expires_at must be after scheduled_at")
+	}
+	return nil
+}
+
+// optionalTime decodes a PATCH field: absent leaves it unchanged, null clears
+// it and a timestamp sets it.
+func optionalTime(fields map[string]json.RawMessage, key string) (present bool, value *time.Time, err error) {
+	raw, ok := fields[key]
+	if !ok {
+		return false, nil, nil
+	}
+	if strings.TrimSpace(string(raw)) == "null" {
+		return true, nil, nil
+	}
+	var t time.Time
+	if err := json.Unmarshal(raw, &t); err != nil {
+		return true, nil, errors.New(key + " must be an RFC3339 timestamp or null")
+	}
+	return true, &t, nil
+}
+
+// SchedulePost godoc
+// @Summary Schedule, reschedule or set expiry on a post
+// @Description Sets scheduled_at and/or expires_at; omitted fields are left as is and null clears them. An approved post with a future scheduled_at becomes scheduled, and a scheduled post without one goes back to approved. Published posts unpublish themselves once expires_at passes.
+// @Tags Workflow
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param schedule body object true "{\"scheduled_at\": \"RFC3339|null\", \"expires_at\": \"RFC3339|null\"}"
+// @Success 200 {object} models.Post
+// @Failure 400 {string} string "Invalid input"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Post not found"
+// @Failure 409 {string} string "Post can no longer be scheduled"
+// @Router /api/v1/posts/{id}/schedule [patch]
+func SchedulePost(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, _ := utils.ExtractUserIDFromRequest(r)
+	role, _ := utils.ExtractUserRole(r)
+	actorID, _ := primitive.ObjectIDFromHex(userID)
+	role = strings.ToLower(role)
+
+	postID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&fields); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	hasSchedule, scheduledAt, err := optionalTime(fields, "scheduled_at")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	hasExpiry, expiresAt, err := optionalTime(fields, "expires_at")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !hasSchedule && !hasExpiry {
+		http.Error(w, "Provide scheduled_at and/or expires_at", http.StatusBadRequest)
+		return
+	}
+
+	var post models.Post
+	if err := config.PostCollection.FindOne(r.Context(), bson.M{"_id": postID}).Decode(&post); err != nil {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	}
+	if post.AuthorID != actorID && role != "admin" {
+		http.Error(w, "Not allowed to schedule this post", http.StatusForbidden)
+		return
+	}
+
+	switch post.Status {
+	case models.PostStatusArchived:
+		http.Error(w, "Archived posts cannot be scheduled", http.StatusConflict)
+		return
+	case models.PostStatusPublished:
+		if hasSchedule {
+			http.Error(w, "Post is already published; only expires_at can be changed", http.StatusConflict)
+			return
+		}
+	}
+
+	now := time.Now()
+	if hasSchedule && scheduledAt != nil && !scheduledAt.After(now) {
+		http.Error(w, "scheduled_at must be in the future", http.StatusBadRequest)
+		return
+	}
+	if !hasSchedule {
+		scheduledAt = post.ScheduledAt
+	}
+	if !hasExpiry {
+		expiresAt = post.ExpiresAt
+	}
+	if err := validatePostDates(scheduledAt, expiresAt, now); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	set := bson.M{"updated_at": now}
+	unset := bson.M{}
+	for key, value := range map[string]*time.Time{"scheduled_at": scheduledAt, "expires_at": expiresAt} {
+		if value != nil {
+			set[key] = *value
+		} else {
+			unset[key] = ""
+		}
+	}
+
+	// Scheduling moves approved posts in and out of the scheduled state.
+	to := post.Status
+	action := ""
+	switch {
+	case post.Status == models.PostStatusApproved && scheduledAt != nil:
+		to, action = models.PostStatusScheduled, models.ActionSchedule
+	case post.Status == models.PostStatusScheduled && scheduledAt == nil:
+		to, action = models.PostStatusApproved, models.ActionUnschedule
+	case post.Status == models.PostStatusScheduled && hasSchedule:
+		action = models.ActionSchedule
+	}
+	set["status"] = to
+
+	update := bson.M{"$set": set, "$inc": bson.M{"version": 1}}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	if action != "" {
+		update["$push"] = bson.M{"status_history": models.StatusChange{
+			Action: action,
+			From:   post.Status,
+			To:     to,
+			By:     actorID,
+			Role:   role,
+			At:     now,
+		}}
+	}
+
+	result, err := config.PostCollection.UpdateOne(r.Context(), bson.M{"_id": postID, "status": post.Status}, update)
+	if err != nil {
+		http.Error(w, "Failed to schedule post", http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, "Post status changed concurrently, please retry", http.StatusConflict)
+		return
+	}
+
+	if err := config.PostCollection.FindOne(r.Context(), bson.M{"_id": postID}).Decode(&post); err != nil {
+		http.Error(w, "Failed to reload post", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(post)
+}
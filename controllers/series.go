@@ -0,0 +1,967 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"go-backend/config"
+	"go-backend/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Series and collections are public, ordered lists of posts. A series holds
+// posts by its author, each in at most one series, and readers of one of
+// them get links to the parts before and after it. A collection is curated by
+// an author or admin from any posts. Like reading lists, readers only see the
+// posts they may currently read.
+
+const maxCuratedTitle = 200
+
+const errInAnotherSeries = "A post is already in another series"
+
+type curatedInput struct {
+	Title       *string   `json:"title"`
+	Description *string   `json:"description"`
+	PostIDs     *[]string `json:"post_ids"` // replaces the posts, e.g. to reorder them
+}
+
+// validate checks the input and converts post_ids, dropping duplicates.
+func (in curatedInput) validate(creating bool, maxPosts int) ([]primitive.ObjectID, string) {
+	return validateList("Title", in.Title, maxCuratedTitle, creating, in.PostIDs, maxPosts)
+}
+
+// validateList checks the input of a list of posts: its name, trimmed in
+// place and called label in errors, and its post_ids, which it converts
+// dropping duplicates. Post IDs are nil when not given.
+func validateList(label string, name *string, maxName int, creating bool, postIDs *[]string, maxPosts int) ([]primitive.ObjectID, string) {
+	if name != nil {
+		*name = strings.TrimSpace(*name)
+	}
+	if (creating && name == nil) || (name != nil && *name == "") {
+		return nil, label + " is required"
+	}
+	if name != nil && len(*name) > maxName {
+		return nil, label + " is too long"
+	}
+	if postIDs == nil {
+		return nil, ""
+	}
+	if len(*postIDs) > maxPosts {
+		return nil, "Too many posts"
+	}
+
+	ids := []primitive.ObjectID{}
+	seen := map[primitive.ObjectID]bool{}
+	for _, s := range *postIDs {
+		id, err := primitive.ObjectIDFromHex(s)
+		if err != nil {
+			return nil, "Invalid post ID " + s
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, ""
+}
+
+// set returns the fields of in to store, plus post_ids when given.
+func (in curatedInput) set(postIDs []primitive.ObjectID) bson.M {
+	set := bson.M{"updated_at": time.Now()}
+	if in.Title != nil {
+		set["title"] = *in.Title
+	}
+	if in.Description != nil {
+		set["description"] = *in.Description
+	}
+	if postIDs != nil {
+		set["post_ids"] = postIDs
+	}
+	return set
+}
+
+// canCurate reports whether the caller may change a list owned by ownerID.
+func canCurate(r *http.Request, ownerID primitive.ObjectID) bool {
+	userID, role := requestViewer(r)
+	return userID == ownerID || role == "admin"
+}
+
+// listPage reads page and limit from the query string.
+func listPage(r *http.Request) (int64, int64) {
+	page := int64(1)
+	if v, err := strconv.ParseInt(r.URL.Query().Get("page"), 10, 64); err == nil && v > 0 {
+		page = v
+	}
+	return page, int64(pageLimit(r, 20))
+}
+
+// postPosition reads the 1-based position query parameter as an index, or
+// -1 to append when it is absent.
+func postPosition(r *http.Request) (int, bool) {
+	v := r.URL.Query().Get("position")
+	if v == "" {
+		return -1, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, false
+	}
+	return n - 1, true
+}
+
+// readablePostIDs returns which of ids the caller may read.
+func readablePostIDs(r *http.Request, ids []primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	readable := map[primitive.ObjectID]bool{}
+	if len(ids) == 0 {
+		return readable, nil
+	}
+	userID, role := requestViewer(r)
+	filter := readableFilter(userID, role)
+	filter["_id"] = bson.M{"$in": ids}
+	cursor, err := config.PostCollection.Find(r.Context(), filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var found []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(r.Context(), &found); err != nil {
+		return nil, err
+	}
+	for _, p := range found {
+		readable[p.ID] = true
+	}
+	return readable, nil
+}
+
+// narrowPostIDs drops the IDs of posts the caller may not read from each of
+// lists, so drafts and private posts do not leak through a listing.
+func narrowPostIDs(r *http.Request, lists ...*[]primitive.ObjectID) error {
+	all := []primitive.ObjectID{}
+	for _, ids := range lists {
+		all = append(all, *ids...)
+	}
+	readable, err := readablePostIDs(r, all)
+	if err != nil {
+		return err
+	}
+	for _, ids := range lists {
+		kept := []primitive.ObjectID{}
+		for _, id := range *ids {
+			if readable[id] {
+				kept = append(kept, id)
+			}
+		}
+		*ids = kept
+	}
+	return nil
+}
+
+// placePost moves or adds postID at index position of the list doc in coll,
+// whose current posts are ids. The write only applies while the list is
+// unchanged since it was read, so concurrent edits cannot lose posts.
+func placePost(ctx context.Context, coll *mongo.Collection, id primitive.ObjectID, updatedAt time.Time, ids []primitive.ObjectID, postID primitive.ObjectID, position int) (bool, error) {
+	result, err := coll.UpdateOne(ctx,
+		bson.M{"_id": id, "updated_at": updatedAt},
+		bson.M{"$set": bson.M{
+			"post_ids":   models.MovePostID(ids, postID, position),
+			"updated_at": time.Now(),
+		}})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// seriesHolding matches the series that hold any of postIDs. The post_ids.0
+// test lets the query use the unique post_ids index, which leaves out empty
+// series.
+func seriesHolding(postIDs ...primitive.ObjectID) bson.M {
+	return bson.M{"post_ids": bson.M{"$in": postIDs}, "post_ids.0": bson.M{"$exists": true}}
+}
+
+func containsID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+	return false
+}
+
+// findSeries loads the series in the URL, writing the error response itself
+// when it cannot. With write set, only its author or an admin get it.
+func findSeries(w http.ResponseWriter, r *http.Request, write bool) (*models.Series, bool) {
+	seriesID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid series ID", http.StatusBadRequest)
+		return nil, false
+	}
+	var series models.Series
+	if err := config.SeriesCollection.FindOne(r.Context(), bson.M{"_id": seriesID}).Decode(&series); err != nil {
+		http.Error(w, "Series not found", http.StatusNotFound)
+		return nil, false
+	}
+	if write && !canCurate(r, series.AuthorID) {
+		http.Error(w, "You cannot change this series", http.StatusForbidden)
+		return nil, false
+	}
+	return &series, true
+}
+
+// checkSeriesPosts makes sure postIDs are posts by the series author that no
+// other series holds, writing the error response itself when they are not.
+// The unique post_ids index catches series that take a post concurrently.
+func checkSeriesPosts(w http.ResponseWriter, r *http.Request, series *models.Series, postIDs []primitive.ObjectID) bool {
+	if len(postIDs) == 0 {
+		return true
+	}
+	n, err := config.PostCollection.CountDocuments(r.Context(), bson.M{"_id": bson.M{"$in": postIDs}, "author_id": series.AuthorID})
+	if err != nil {
+		http.Error(w, "Failed to check posts", http.StatusInternalServerError)
+		return false
+	}
+	if n != int64(len(postIDs)) {
+		http.Error(w, "Series posts must be by the series author", http.StatusBadRequest)
+		return false
+	}
+	others := seriesHolding(postIDs...)
+	others["_id"] = bson.M{"$ne": series.ID}
+	n, err = config.SeriesCollection.CountDocuments(r.Context(), others, options.Count().SetLimit(1))
+	if err != nil {
+		http.Error(w, "Failed to check posts", http.StatusInternalServerError)
+		return false
+	}
+	if n > 0 {
+		http.Error(w, errInAnotherSeries, http.StatusConflict)
+		return false
+	}
+	return true
+}
+
+// checkCollectionPosts makes sure postIDs are published posts the caller may
+// read, as AddToCollection does for one post, writing the error response
+// itself when they are not.
+func checkCollectionPosts(w http.ResponseWriter, r *http.Request, postIDs []primitive.ObjectID) bool {
+	readable, err := readablePostIDs(r, postIDs)
+	if err != nil {
+		http.Error(w, "Failed to check posts", http.StatusInternalServerError)
+		return false
+	}
+	for _, id := range postIDs {
+		if !readable[id] {
+			http.Error(w, "Collections can only hold published posts you can read", http.StatusBadRequest)
+			return false
+		}
+	}
+	return true
+}
+
+// loadSeriesPosts fills in the posts of series the caller may read, in
+// order. Readers other than the author and admins only get the IDs of those
+// posts.
+func loadSeriesPosts(r *http.Request, series *models.Series) error {
+	posts, err := readablePosts(r, series.PostIDs)
+	if err != nil {
+		return err
+	}
+	series.Posts = posts
+	if !canCurate(r, series.AuthorID) {
+		series.PostIDs = postIDsOf(posts)
+	}
+	return preparePosts(r, series.Posts)
+}
+
+// seriesNav places postID within its series, if it has one, among the parts
+// the caller may read.
+func seriesNav(r *http.Request, postID primitive.ObjectID) (*models.SeriesNav, error) {
+	var series models.Series
+	err := config.SeriesCollection.FindOne(r.Context(), seriesHolding(postID)).Decode(&series)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	posts, err := readablePosts(r, series.PostIDs)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewSeriesNav(series, posts, postID), nil
+}
+
+// ListSeries godoc
+// @Summary List series
+// @Description Most recently updated first, optionally only those of one author.
+// @Tags Series
+// @Produce json
+// @Param author_id query string false "Author ID"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page (default 20, max 100)"
+// @Success 200 {array} models.Series
+// @Failure 400 {string} string "Invalid author ID"
+// @Router /api/v1/series [get]
+func ListSeries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	filter := bson.M{}
+	if v := r.URL.Query().Get("author_id"); v != "" {
+		authorID, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			http.Error(w, "Invalid author ID", http.StatusBadRequest)
+			return
+		}
+		filter["author_id"] = authorID
+	}
+	page, limit := listPage(r)
+
+	total, err := config.SeriesCollection.CountDocuments(r.Context(), filter)
+	if err != nil {
+		http.Error(w, "Failed to fetch series", http.StatusInternalServerError)
+		return
+	}
+	cursor, err := config.SeriesCollection.Find(r.Context(), filter, options.Find().
+		SetSort(bson.D{{Key: "updated_at", Value: -1}}).
+		SetSkip((page-1)*limit).
+		SetLimit(limit))
+	if err != nil {
+		http.Error(w, "Failed to fetch series", http.StatusInternalServerError)
+		return
+	}
+	series := []models.Series{}
+	if err := cursor.All(r.Context(), &series); err != nil {
+		http.Error(w, "Failed to parse series", http.StatusInternalServerError)
+		return
+	}
+	lists := []*[]primitive.ObjectID{}
+	for i := range series {
+		if !canCurate(r, series[i].AuthorID) {
+			lists = append(lists, &series[i].PostIDs)
+		}
+	}
+	if err := narrowPostIDs(r, lists...); err != nil {
+		http.Error(w, "Failed to fetch series", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"series": series,
+		"pagination": map[string]interface{}{
+			"total":       total,
+			"page":        page,
+			"limit":       limit,
+			"total_pages": (total + limit - 1) / limit,
+		},
+	})
+}
+
+// GetSeries godoc
+// @Summary Get a series with its posts
+// @Tags Series
+// @Produce json
+// @Param id path string true "Series ID"
+// @Success 200 {object} models.Series
+// @Failure 404 {string} string "Series not found"
+// @Router /api/v1/series/{id} [get]
+func GetSeries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	series, ok := findSeries(w, r, false)
+	if !ok {
+		return
+	}
+	if err := loadSeriesPosts(r, series); err != nil {
+		http.Error(w, "Failed to load posts", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(series)
+}
+
+// CreateSeries godoc
+// @Summary Create a series
+// @Description The caller becomes the author. Posts must be theirs and not in another series.
+// @Tags Series
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param series body curatedInput true "Title, optional description and post IDs"
+// @Success 201 {object} models.Series
+// @Failure 400 {string} string "Invalid input"
+// @Failure 409 {string} string "A post is already in another series"
+// @Router /api/v1/series [post]
+func CreateSeries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var in curatedInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	postIDs, msg := in.validate(true, models.MaxSeriesPosts)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := requestViewer(r)
+	now := time.Now()
+	series := models.Series{
+		ID:        primitive.NewObjectID(),
+		AuthorID:  userID,
+		Title:     *in.Title,
+		PostIDs:   postIDs,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if series.PostIDs == nil {
+		series.PostIDs = []primitive.ObjectID{}
+	}
+	if in.Description != nil {
+		series.Description = *in.Description
+	}
+	if !checkSeriesPosts(w, r, &series, series.PostIDs) {
+		return
+	}
+
+	_, err := config.SeriesCollection.InsertOne(r.Context(), series)
+	if mongo.IsDuplicateKeyError(err) {
+		http.Error(w, errInAnotherSeries, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to create series", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(series)
+}
+
+// UpdateSeries godoc
+// @Summary Rename, describe or reorder a series
+// @Description post_ids, when given, replaces the posts of the series in that order.
+// @Tags Series
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Series ID"
+// @Param series body curatedInput true "Fields to change"
+// @Success 200 {object} models.Series
+// @Failure 400 {string} string "Invalid input"
+// @Failure 403 {string} string "You cannot change this series"
+// @Failure 404 {string} string "Series not found"
+// @Failure 409 {string} string "A post is already in another series, or the series was changed concurrently"
+// @Router /api/v1/series/{id} [put]
+func UpdateSeries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	series, ok := findSeries(w, r, true)
+	if !ok {
+		return
+	}
+	var in curatedInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	postIDs, msg := in.validate(false, models.MaxSeriesPosts)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if !checkSeriesPosts(w, r, series, postIDs) {
+		return
+	}
+
+	// Only write while the series is unchanged since it was read, as
+	// placePost does.
+	err := config.SeriesCollection.FindOneAndUpdate(r.Context(),
+		bson.M{"_id": series.ID, "updated_at": series.UpdatedAt}, bson.M{"$set": in.set(postIDs)},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(series)
+	if mongo.IsDuplicateKeyError(err) {
+		http.Error(w, errInAnotherSeries, http.StatusConflict)
+		return
+	}
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, "Series was changed, please retry", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update series", http.StatusInternalServerError)
+		return
+	}
+	if err := loadSeriesPosts(r, series); err != nil {
+		http.Error(w, "Failed to load posts", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(series)
+}
+
+// DeleteSeries godoc
+// @Summary Delete a series
+// @Description The posts themselves are kept.
+// @Tags Series
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Series ID"
+// @Success 200 {string} string "Series deleted"
+// @Failure 403 {string} string "You cannot change this series"
+// @Failure 404 {string} string "Series not found"
+// @Router /api/v1/series/{id} [delete]
+func DeleteSeries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	series, ok := findSeries(w, r, true)
+	if !ok {
+		return
+	}
+	if _, err := config.SeriesCollection.DeleteOne(r.Context(), bson.M{"_id": series.ID}); err != nil {
+		http.Error(w, "Failed to delete series", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Series deleted"})
+}
+
+// AddToSeries godoc
+// @Summary Add or move a post in a series
+// @Description Puts the post at position, counting from 1, or at the end when position is left out. A post already in the series is moved.
+// @Tags Series
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Series ID"
+// @Param postId path string true "Post ID"
+// @Param position query int false "New position, from 1"
+// @Success 200 {string} string "Post added"
+// @Failure 400 {string} string "Series posts must be by the series author"
+// @Failure 403 {string} string "You cannot change this series"
+// @Failure 404 {string} string "Series not found"
+// @Failure 409 {string} string "Series is full, was changed concurrently or the post is in another series"
+// @Router /api/v1/series/{id}/posts/{postId} [put]
+func AddToSeries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	series, ok := findSeries(w, r, true)
+	if !ok {
+		return
+	}
+	postID, err := primitive.ObjectIDFromHex(mux.Vars(r)["postId"])
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+	position, ok := postPosition(r)
+	if !ok {
+		http.Error(w, "Invalid position", http.StatusBadRequest)
+		return
+	}
+	if !containsID(series.PostIDs, postID) && len(series.PostIDs) >= models.MaxSeriesPosts {
+		http.Error(w, "Series is full", http.StatusConflict)
+		return
+	}
+	if !checkSeriesPosts(w, r, series, []primitive.ObjectID{postID}) {
+		return
+	}
+
+	placed, err := placePost(r.Context(), config.SeriesCollection, series.ID, series.UpdatedAt, series.PostIDs, postID, position)
+	if mongo.IsDuplicateKeyError(err) {
+		http.Error(w, errInAnotherSeries, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to add post", http.StatusInternalServerError)
+		return
+	}
+	if !placed {
+		http.Error(w, "Series was changed, please retry", http.StatusConflict)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Post added"})
+}
+
+// RemoveFromSeries godoc
+// @Summary Remove a post from a series
+// @Tags Series
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Series ID"
+// @Param postId path string true "Post ID"
+// @Success 200 {string} string "Post removed"
+// @Failure 403 {string} string "You cannot change this series"
+// @Failure 404 {string} string "Series not found"
+// @Router /api/v1/series/{id}/posts/{postId} [delete]
+func RemoveFromSeries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	series, ok := findSeries(w, r, true)
+	if !ok {
+		return
+	}
+	postID, err := primitive.ObjectIDFromHex(mux.Vars(r)["postId"])
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	_, err = config.SeriesCollection.UpdateOne(r.Context(), bson.M{"_id": series.ID}, bson.M{
+		"$pull": bson.M{"post_ids": postID},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+	if err != nil {
+		http.Error(w, "Failed to remove post", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Post removed"})
+}
+
+// findCollection loads the collection in the URL, writing the error response
+// itself when it cannot. With write set, only its owner or an admin get it.
+func findCollection(w http.ResponseWriter, r *http.Request, write bool) (*models.Collection, bool) {
+	collectionID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid collection ID", http.StatusBadRequest)
+		return nil, false
+	}
+	var collection models.Collection
+	if err := config.CollectionCollection.FindOne(r.Context(), bson.M{"_id": collectionID}).Decode(&collection); err != nil {
+		http.Error(w, "Collection not found", http.StatusNotFound)
+		return nil, false
+	}
+	if write && !canCurate(r, collection.OwnerID) {
+		http.Error(w, "You cannot change this collection", http.StatusForbidden)
+		return nil, false
+	}
+	return &collection, true
+}
+
+// loadCollectionPosts fills in the posts of collection the caller may read,
+// in order, narrowing PostIDs to them for everyone but the owner and admins.
+func loadCollectionPosts(r *http.Request, collection *models.Collection) error {
+	posts, err := readablePosts(r, collection.PostIDs)
+	if err != nil {
+		return err
+	}
+	collection.Posts = posts
+	if !canCurate(r, collection.OwnerID) {
+		collection.PostIDs = postIDsOf(posts)
+	}
+	return preparePosts(r, collection.Posts)
+}
+
+// ListCollections godoc
+// @Summary List collections
+// @Description Most recently updated first, optionally only those of one owner.
+// @Tags Collections
+// @Produce json
+// @Param owner_id query string false "Owner ID"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page (default 20, max 100)"
+// @Success 200 {array} models.Collection
+// @Failure 400 {string} string "Invalid owner ID"
+// @Router /api/v1/collections [get]
+func ListCollections(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	filter := bson.M{}
+	if v := r.URL.Query().Get("owner_id"); v != "" {
+		ownerID, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			http.Error(w, "Invalid owner ID", http.StatusBadRequest)
+			return
+		}
+		filter["owner_id"] = ownerID
+	}
+	page, limit := listPage(r)
+
+	total, err := config.CollectionCollection.CountDocuments(r.Context(), filter)
+	if err != nil {
+		http.Error(w, "Failed to fetch collections", http.StatusInternalServerError)
+		return
+	}
+	cursor, err := config.CollectionCollection.Find(r.Context(), filter, options.Find().
+		SetSort(bson.D{{Key: "updated_at", Value: -1}}).
+		SetSkip((page-1)*limit).
+		SetLimit(limit))
+	if err != nil {
+		http.Error(w, "Failed to fetch collections", http.StatusInternalServerError)
+		return
+	}
+	collections := []models.Collection{}
+	if err := cursor.All(r.Context(), &collections); err != nil {
+		http.Error(w, "Failed to parse collections", http.StatusInternalServerError)
+		return
+	}
+	lists := []*[]primitive.ObjectID{}
+	for i := range collections {
+		if !canCurate(r, collections[i].OwnerID) {
+			lists = append(lists, &collections[i].PostIDs)
+		}
+	}
+	if err := narrowPostIDs(r, lists...); err != nil {
+		http.Error(w, "Failed to fetch collections", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"collections": collections,
+		"pagination": map[string]interface{}{
+			"total":       total,
+			"page":        page,
+			"limit":       limit,
+			"total_pages": (total + limit - 1) / limit,
+		},
+	})
+}
+
+// GetCollection godoc
+// @Summary Get a collection with its posts
+// @Tags Collections
+// @Produce json
+// @Param id path string true "Collection ID"
+// @Success 200 {object} models.Collection
+// @Failure 404 {string} string "Collection not found"
+// @Router /api/v1/collections/{id} [get]
+func GetCollection(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	collection, ok := findCollection(w, r, false)
+	if !ok {
+		return
+	}
+	if err := loadCollectionPosts(r, collection); err != nil {
+		http.Error(w, "Failed to load posts", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(collection)
+}
+
+// CreateCollection godoc
+// @Summary Create a collection
+// @Description The caller becomes the owner.
+// @Tags Collections
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param collection body curatedInput true "Title, optional description and post IDs"
+// @Success 201 {object} models.Collection
+// @Failure 400 {string} string "Invalid input or a post the caller cannot read"
+// @Router /api/v1/collections [post]
+func CreateCollection(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var in curatedInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	postIDs, msg := in.validate(true, models.MaxCollectionPosts)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if !checkCollectionPosts(w, r, postIDs) {
+		return
+	}
+
+	userID, _ := requestViewer(r)
+	now := time.Now()
+	collection := models.Collection{
+		ID:        primitive.NewObjectID(),
+		OwnerID:   userID,
+		Title:     *in.Title,
+		PostIDs:   postIDs,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if collection.PostIDs == nil {
+		collection.PostIDs = []primitive.ObjectID{}
+	}
+	if in.Description != nil {
+		collection.Description = *in.Description
+	}
+
+	if _, err := config.CollectionCollection.InsertOne(r.Context(), collection); err != nil {
+		http.Error(w, "Failed to create collection", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(collection)
+}
+
+// UpdateCollection godoc
+// @Summary Rename, describe or reorder a collection
+// @Description post_ids, when given, replaces the posts of the collection in that order. They must be published posts the caller may read.
+// @Tags Collections
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Collection ID"
+// @Param collection body curatedInput true "Fields to change"
+// @Success 200 {object} models.Collection
+// @Failure 400 {string} string "Invalid input or a post the caller cannot read"
+// @Failure 403 {string} string "You cannot change this collection"
+// @Failure 404 {string} string "Collection not found"
+// @Failure 409 {string} string "Collection was changed concurrently"
+// @Router /api/v1/collections/{id} [put]
+func UpdateCollection(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	collection, ok := findCollection(w, r, true)
+	if !ok {
+		return
+	}
+	var in curatedInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	postIDs, msg := in.validate(false, models.MaxCollectionPosts)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if !checkCollectionPosts(w, r, postIDs) {
+		return
+	}
+
+	err := config.CollectionCollection.FindOneAndUpdate(r.Context(),
+		bson.M{"_id": collection.ID, "updated_at": collection.UpdatedAt}, bson.M{"$set": in.set(postIDs)},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(collection)
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, "Collection was changed, please retry", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update collection", http.StatusInternalServerError)
+		return
+	}
+	if err := loadCollectionPosts(r, collection); err != nil {
+		http.Error(w, "Failed to load posts", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(collection)
+}
+
+// DeleteCollection godoc
+// @Summary Delete a collection
+// @Tags Collections
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Collection ID"
+// @Success 200 {string} string "Collection deleted"
+// @Failure 403 {string} string "You cannot change this collection"
+// @Failure 404 {string} string "Collection not found"
+// @Router /api/v1/collections/{id} [delete]
+func DeleteCollection(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	collection, ok := findCollection(w, r, true)
+	if !ok {
+		return
+	}
+	if _, err := config.CollectionCollection.DeleteOne(r.Context(), bson.M{"_id": collection.ID}); err != nil {
+		http.Error(w, "Failed to delete collection", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Collection deleted"})
+}
+
+// AddToCollection godoc
+// @Summary Add or move a post in a collection
+// @Description Puts a published post the caller may read at position, counting from 1, or at the end when position is left out. A post already in the collection is moved.
+// @Tags Collections
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Collection ID"
+// @Param postId path string true "Post ID"
+// @Param position query int false "New position, from 1"
+// @Success 200 {string} string "Post added"
+// @Failure 403 {string} string "You cannot change this collection"
+// @Failure 404 {string} string "Collection or post not found"
+// @Failure 409 {string} string "Collection is full or was changed concurrently"
+// @Router /api/v1/collections/{id}/posts/{postId} [put]
+func AddToCollection(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	collection, ok := findCollection(w, r, true)
+	if !ok {
+		return
+	}
+	post, ok := findReadablePost(w, r, "postId")
+	if !ok {
+		return
+	}
+	position, ok := postPosition(r)
+	if !ok {
+		http.Error(w, "Invalid position", http.StatusBadRequest)
+		return
+	}
+	if !containsID(collection.PostIDs, post.ID) && len(collection.PostIDs) >= models.MaxCollectionPosts {
+		http.Error(w, "Collection is full", http.StatusConflict)
+		return
+	}
+
+	placed, err := placePost(r.Context(), config.CollectionCollection, collection.ID, collection.UpdatedAt, collection.PostIDs, post.ID, position)
+	if err != nil {
+		http.Error(w, "Failed to add post", http.StatusInternalServerError)
+		return
+	}
+	if !placed {
+		http.Error(w, "Collection was changed, please retry", http.StatusConflict)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Post added"})
+}
+
+// RemoveFromCollection godoc
+// @Summary Remove a post from a collection
+// @Tags Collections
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Collection ID"
+// @Param postId path string true "Post ID"
+// @Success 200 {string} string "Post removed"
+// @Failure 403 {string} string "You cannot change this collection"
+// @Failure 404 {string} string "Collection not found"
+// @Router /api/v1/collections/{id}/posts/{postId} [delete]
+func RemoveFromCollection(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	collection, ok := findCollection(w, r, true)
+	if !ok {
+		return
+	}
+	postID, err := primitive.ObjectIDFromHex(mux.Vars(r)["postId"])
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	_, err = config.CollectionCollection.UpdateOne(r.Context(), bson.M{"_id": collection.ID}, bson.M{
+		"$pull": bson.M{"post_ids": postID},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+	if err != nil {
+		http.Error(w, "Failed to remove post", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Post removed"})
+}
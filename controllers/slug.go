@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"go-backend/config"
+	"go-backend/models"
+	"go-backend/utils"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// renamedSlugHistory adds the outgoing slug to history and drops the incoming
+// one, which can happen when a post is renamed back to an earlier title.
+func renamedSlugHistory(history []string, oldSlug, newSlug string) []string {
+	out := []string{}
+	for _, s := range append(history, oldSlug) {
+		if s != "" && s != newSlug {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// writePublishedPost renders and encodes a published post, or a draft shared
+// with the caller, if the caller may see it, with links to its neighbours
+// when it is part of a series. Only views of published posts are counted.
+// Media of non-public posts is returned as signed, expiring links.
+func writePublishedPost(w http.ResponseWriter, r *http.Request, post *models.Post) {
+	userID, role := requestViewer(r)
+	if !post.VisibleTo(userID, role) {
+		if post.Visibility == models.VisibilityPremium && !post.Hidden {
+			http.Error(w, "This post is for premium members", http.StatusForbidden)
+			return
+		}
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	}
+
+	if post.Status == models.PostStatusPublished {
+		utils.Views.RecordRequest(r, *post, userID)
+	}
+
+	var err error
+	post.RenderedContent, err = utils.CachedRenderContent(r.Context(), post.Content)
+	if err != nil {
+		http.Error(w, "Failed to render post", http.StatusInternalServerError)
+		return
+	}
+	posts := []models.Post{*post}
+	if err := utils.AttachMedia(r.Context(), posts); err != nil {
+		http.Error(w, "Failed to load post media", http.StatusInternalServerError)
+		return
+	}
+	if err := attachCoAuthors(r.Context(), posts); err != nil {
+		http.Error(w, "Failed to load co-authors", http.StatusInternalServerError)
+		return
+	}
+	if posts[0].Series, err = seriesNav(r, posts[0].ID); err != nil {
+		http.Error(w, "Failed to load series", http.StatusInternalServerError)
+		return
+	}
+	if posts[0].Visibility != models.VisibilityPublic {
+		utils.SignPostMedia(&posts[0], time.Now().Add(utils.MediaURLTTL))
+	}
+
+	w.Header().Set("ETag", utils.VersionETag(posts[0].Version))
+	json.NewEncoder(w).Encode(posts[0])
+}
+
+// preparePosts renders posts and attaches their media and co-authors for a
+// listing. Listings query with models.VisibilityFilter; should a post the
+// caller may not read get through anyway, only its title is shown. Only
+// readers entitled to a non-public post get working media links.
+func preparePosts(r *http.Request, posts []models.Post) error {
+	viewerID, viewerRole := requestViewer(r)
+	for i := range posts {
+		if !posts[i].VisibleTo(viewerID, viewerRole) {
+			posts[i].Content, posts[i].MediaURLs, posts[i].Trade = "", nil, nil
+		}
+	}
+
+	if err := utils.RenderPosts(r.Context(), posts); err != nil {
+		return err
+	}
+	if err := utils.AttachMedia(r.Context(), posts); err != nil {
+		return err
+	}
+	if err := attachCoAuthors(r.Context(), posts); err != nil {
+		return err
+	}
+	expires := time.Now().Add(utils.MediaURLTTL)
+	for i := range posts {
+		if posts[i].Visibility != models.VisibilityPublic && posts[i].VisibleTo(viewerID, viewerRole) {
+			utils.SignPostMedia(&posts[i], expires)
+		}
+	}
+	return nil
+}
+
+// requestViewer returns the caller's ID and role, zero values for anonymous
+// requests.
+func requestViewer(r *http.Request) (primitive.ObjectID, string) {
+	userID, _ := utils.ExtractUserIDFromRequest(r)
+	role, _ := utils.ExtractUserRole(r)
+	id, _ := primitive.ObjectIDFromHex(userID)
+	return id, strings.ToLower(role)
+}
+
+// findPublishedBySlug looks a slug up among current slugs first and then in
+// slug history. renamed is true when it only matched history.
+func findPublishedBySlug(ctx context.Context, slug string, extra bson.M) (post models.Post, renamed bool, err error) {
+	filter := bson.M{"slug": slug, "status": "published"}
+	for k, v := range extra {
+		filter[k] = v
+	}
+
+	err = config.PostCollection.FindOne(ctx, filter).Decode(&post)
+	if err != mongo.ErrNoDocuments {
+		return post, false, err
+	}
+
+	delete(filter, "slug")
+	filter["slug_history"] = slug
+	err = config.PostCollection.FindOne(ctx, filter).Decode(&post)
+	return post, true, err
+}
+
+// GetPostBySlug godoc
+// @Summary Get a post by slug
+// @Description Old slugs of renamed posts redirect to the current slug with 301
+// @Tags Posts
+// @Produce json
+// @Param slug path string true "Post slug"
+// @Success 200 {object} models.Post
+// @Success 301 {string} string "Moved to the current slug"
+// @Failure 404 {string} string "Post not found"
+// @Router /api/v1/posts/by-slug/{slug} [get]
+func GetPostBySlug(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	post, renamed, err := findPublishedBySlug(r.Context(), mux.Vars(r)["slug"], nil)
+	if err != nil {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	}
+
+	if renamed {
+		http.Redirect(w, r, "/api/v1/posts/by-slug/"+post.Slug, http.StatusMovedPermanently)
+		return
+	}
+
+	writePublishedPost(w, r, &post)
+}
+
+// GetPostByPermalink godoc
+// @Summary Get a post by author handle and slug
+// @Description Permalink form of a post. Old slugs redirect with 301.
+// @Tags Posts
+// @Produce json
+// @Param handle path string true "Author handle"
+// @Param slug path string true "Post slug"
+// @Success 200 {object} models.Post
+// @Success 301 {string} string "Moved to the current permalink"
+// @Failure 404 {string} string "Post not found"
+// @Router /api/v1/posts/@{handle}/{slug} [get]
+func GetPostByPermalink(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+
+	var author models.User
+	if err := config.UserCollection.FindOne(r.Context(), bson.M{"handle": vars["handle"]}).Decode(&author); err != nil {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	}
+
+	post, renamed, err := findPublishedBySlug(r.Context(), vars["slug"], bson.M{"author_id": author.ID})
+	if err != nil {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	}
+
+	if renamed {
+		http.Redirect(w, r, "/api/v1/posts/@"+author.Handle+"/"+post.Slug, http.StatusMovedPermanently)
+		return
+	}
+
+	writePublishedPost(w, r, &post)
+}
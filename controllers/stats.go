@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"encoding/json"
+	"go-backend/config"
+	"go-backend/models"
+	"go-backend/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Longest ranges a stats report may span per interval.
+var statsMaxRange = map[string]time.Duration{
+	models.StatsHourly: 31 * 24 * time.Hour,
+	models.StatsDaily:  366 * 24 * time.Hour,
+}
+
+// Ranges reported when the caller gives no from.
+var statsDefaultRange = map[string]time.Duration{
+	models.StatsHourly: 48 * time.Hour,
+	models.StatsDaily:  30 * 24 * time.Hour,
+}
+
+// statsRange reads interval, from and to from the query string.
+func statsRange(r *http.Request, now time.Time) (interval string, from, to time.Time, msg string) {
+	query := r.URL.Query()
+	interval = query.Get("interval")
+	if interval == "" {
+		interval = models.StatsDaily
+	}
+	if _, ok := statsMaxRange[interval]; !ok {
+		return "", from, to, "interval must be hour or day"
+	}
+
+	to = now
+	if v := query.Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return "", from, to, "to must be an RFC 3339 time"
+		}
+		to = t
+	}
+	from = to.Add(-statsDefaultRange[interval])
+	if v := query.Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return "", from, to, "from must be an RFC 3339 time"
+		}
+		from = t
+	}
+
+	if !from.Before(to) {
+		return "", from, to, "from must be before to"
+	}
+	if to.Sub(from) > statsMaxRange[interval] {
+		return "", from, to, "Range is too long for this interval"
+	}
+	return interval, from, to, ""
+}
+
+// GetPostStats godoc
+// @Summary View analytics of a post
+// @Description Views, unique viewers and referrers over time. Counts are rolled up every few minutes. Only the author and admins may see them.
+// @Tags Posts
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param interval query string false "hour or day (default day)"
+// @Param from query string false "Start, RFC 3339 (default 48 hours or 30 days back)"
+// @Param to query string false "End, RFC 3339 (default now)"
+// @Success 200 {object} models.PostStats
+// @Failure 400 {string} string "Invalid range"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Post not found"
+// @Router /api/v1/posts/{id}/stats [get]
+func GetPostStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	postID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+	interval, from, to, msg := statsRange(r, time.Now())
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	var post models.Post
+	if err := config.PostCollection.FindOne(r.Context(), bson.M{"_id": postID}).Decode(&post); err != nil {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	}
+	userID, role := requestViewer(r)
+	if post.AuthorID != userID && role != "admin" {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	stats, err := utils.Views.PostStats(r.Context(), postID, interval, from, to)
+	if err != nil {
+		http.Error(w, "Failed to load stats", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(stats)
+}
+
+// topQuery reads days (1-365, default 7) and limit (1-100, default 10).
+func topQuery(r *http.Request, now time.Time) (from time.Time, limit int) {
+	days, limit := 7, 10
+	if v, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && v > 0 && v <= 365 {
+		days = v
+	}
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 && v <= 100 {
+		limit = v
+	}
+	today := now.UTC().Truncate(24 * time.Hour)
+	return today.AddDate(0, 0, 1-days), limit
+}
+
+// GetTopPosts godoc
+// @Summary Most viewed posts site-wide
+// @Description Uniques are summed per day, so a reader returning on several days counts more than once.
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param days query int false "Days back, including today (default 7)"
+// @Param limit query int false "Number of posts (default 10, max 100)"
+// @Success 200 {array} models.TopPost
+// @Failure 500 {string} string "Failed to load stats"
+// @Router /api/v1/admin/stats/top-posts [get]
+func GetTopPosts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	from, limit := topQuery(r, time.Now())
+	top, err := utils.Views.TopPosts(r.Context(), from, limit)
+	if err != nil {
+		http.Error(w, "Failed to load stats", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(top)
+}
+
+// GetTopReferrers godoc
+// @Summary Hosts sending the most views site-wide
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param days query int false "Days back, including today (default 7)"
+// @Param limit query int false "Number of hosts (default 10, max 100)"
+// @Success 200 {array} models.ReferrerCount
+// @Failure 500 {string} string "Failed to load stats"
+// @Router /api/v1/admin/stats/top-referrers [get]
+func GetTopReferrers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	from, limit := topQuery(r, time.Now())
+	top, err := utils.Views.TopReferrers(r.Context(), from, limit)
+	if err != nil {
+		http.Error(w, "Failed to load stats", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(top)
+}
@@ -0,0 +1,240 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"go-backend/config"
+	"go-backend/models"
+	"go-backend/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TradeFillRequest struct {
+	Price    float64    `json:"price"`
+	Quantity float64    `json:"quantity"`
+	FilledAt *time.Time `json:"filled_at,omitempty"`
+}
+
+type CloseTradeRequest struct {
+	Outcome   models.TradeOutcome `json:"outcome"`
+	ExitPrice *float64            `json:"exit_price,omitempty"`
+	ClosedAt  *time.Time          `json:"closed_at,omitempty"`
+}
+
+// validateTradePost makes sure trade details are present exactly when the
+// post is a trade, normalizes them and resets the lifecycle to pending.
+func validateTradePost(post *models.Post) error {
+	if post.Type != models.PostTypeTrade {
+		if post.Trade != nil {
+			return errors.New("trade details are only allowed on trade posts")
+		}
+		return nil
+	}
+
+	if post.Trade == nil {
+		return errors.New("trade posts require trade details")
+	}
+
+	post.Trade.Normalize()
+	if err := post.Trade.Validate(); err != nil {
+		return err
+	}
+	post.Trade.Reset()
+	return nil
+}
+
+// findOwnTradePost loads a trade post owned by the caller, writing the error
+// response itself when it cannot.
+func findOwnTradePost(w http.ResponseWriter, r *http.Request) (*models.Post, bool) {
+	userID, _ := utils.ExtractUserIDFromRequest(r)
+	authorID, _ := primitive.ObjectIDFromHex(userID)
+
+	postID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	var post models.Post
+	err = config.PostCollection.FindOne(r.Context(), bson.M{"_id": postID, "author_id": authorID}).Decode(&post)
+	if err != nil {
+		http.Error(w, "Post not found or not authorized", http.StatusNotFound)
+		return nil, false
+	}
+
+	if post.Type != models.PostTypeTrade || post.Trade == nil {
+		http.Error(w, "Post is not a trade", http.StatusBadRequest)
+		return nil, false
+	}
+
+	return &post, true
+}
+
+// RecordTradeFill godoc
+// @Summary Record a fill on a trade post
+// @Description Authors record entry fills; the first fill opens the trade. filled_at defaults to now and cannot be in the future or before the post was published.
+// @Tags Trades
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param fill body TradeFillRequest true "Fill details"
+// @Success 200 {object} models.Trade
+// @Failure 400 {string} string "Invalid input"
+// @Failure 404 {string} string "Post not found"
+// @Failure 409 {string} string "Trade already closed"
+// @Router /api/v1/posts/{id}/trade/fills [post]
+func RecordTradeFill(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req TradeFillRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if req.Price <= 0 || req.Quantity <= 0 {
+		http.Error(w, "Fill price and quantity must be positive", http.StatusBadRequest)
+		return
+	}
+
+	post, ok := findOwnTradePost(w, r)
+	if !ok {
+		return
+	}
+	if post.Trade.Status == models.TradeStatusClosed {
+		http.Error(w, "Trade is already closed", http.StatusConflict)
+		return
+	}
+
+	fill := models.TradeFill{Price: req.Price, Quantity: req.Quantity, FilledAt: time.Now()}
+	if req.FilledAt != nil {
+		fill.FilledAt = *req.FilledAt
+		if err := post.CheckTradeTime(fill.FilledAt, time.Now()); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	event := models.TradeEvent{Type: models.TradeEventFilled, Price: &fill.Price, Source: models.TradeSourceAuthor, At: fill.FilledAt}
+
+	filter := bson.M{"_id": post.ID, "trade.status": bson.M{"$ne": models.TradeStatusClosed}}
+	update := bson.M{
+		"$push": bson.M{"trade.fills": fill, "trade.events": event},
+		"$set":  bson.M{"trade.status": models.TradeStatusOpen, "updated_at": time.Now()},
+		"$inc":  bson.M{"version": 1},
+	}
+
+	result, err := config.PostCollection.UpdateOne(r.Context(), filter, update)
+	if err != nil {
+		http.Error(w, "Failed to record fill", http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, "Trade is already closed", http.StatusConflict)
+		return
+	}
+
+	post.Trade.Fills = append(post.Trade.Fills, fill)
+	post.Trade.Events = append(post.Trade.Events, event)
+	post.Trade.Status = models.TradeStatusOpen
+	json.NewEncoder(w).Encode(post.Trade)
+}
+
+// CloseTrade godoc
+// @Summary Close a trade post with an outcome
+// @Description Outcome is one of target_hit, stopped, expired or manual. The exit price defaults to the first target or the stop. closed_at defaults to now and cannot be in the future, before the post was published or before the last fill.
+// @Tags Trades
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param outcome body CloseTradeRequest true "Outcome"
+// @Success 200 {object} models.Trade
+// @Failure 400 {string} string "Invalid input"
+// @Failure 404 {string} string "Post not found"
+// @Failure 409 {string} string "Trade already closed, never filled or changed meanwhile"
+// @Router /api/v1/posts/{id}/trade/close [post]
+func CloseTrade(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req CloseTradeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if !models.ValidTradeOutcome(req.Outcome) {
+		http.Error(w, "Outcome must be one of target_hit, stopped, expired, manual", http.StatusBadRequest)
+		return
+	}
+	if req.ExitPrice != nil && *req.ExitPrice <= 0 {
+		http.Error(w, "Exit price must be positive", http.StatusBadRequest)
+		return
+	}
+
+	post, ok := findOwnTradePost(w, r)
+	if !ok {
+		return
+	}
+	if post.Trade.Status == models.TradeStatusClosed {
+		http.Error(w, "Trade is already closed", http.StatusConflict)
+		return
+	}
+	if err := post.Trade.CheckClose(req.Outcome, req.ExitPrice); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	exitPrice := req.ExitPrice
+	if exitPrice == nil && post.Trade.Filled() {
+		switch req.Outcome {
+		case models.TradeOutcomeTargetHit:
+			exitPrice = &post.Trade.Targets[0]
+		case models.TradeOutcomeStopped:
+			exitPrice = &post.Trade.Stop
+		}
+	}
+	if exitPrice == nil && post.Trade.Status == models.TradeStatusOpen {
+		http.Error(w, "Exit price is required to close a filled trade", http.StatusBadRequest)
+		return
+	}
+
+	closedAt := time.Now()
+	if req.ClosedAt != nil {
+		closedAt = *req.ClosedAt
+		if err := post.CheckTradeTime(closedAt, time.Now()); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := post.Trade.CheckCloseTime(closedAt); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	previousStatus, fillCount := post.Trade.Status, len(post.Trade.Fills)
+	post.Trade.Close(req.Outcome, exitPrice, closedAt, models.TradeSourceAuthor)
+
+	// The whole trade is replaced, so only write it if no fill or close was
+	// recorded since it was read.
+	filter := bson.M{"_id": post.ID, "trade.status": previousStatus}
+	filter["trade.fills."+strconv.Itoa(fillCount)] = bson.M{"$exists": false}
+	update := bson.M{"$set": bson.M{"trade": post.Trade, "updated_at": time.Now()}, "$inc": bson.M{"version": 1}}
+
+	result, err := config.PostCollection.UpdateOne(r.Context(), filter, update)
+	if err != nil {
+		http.Error(w, "Failed to close trade", http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, "Trade was changed meanwhile, please retry", http.StatusConflict)
+		return
+	}
+
+	json.NewEncoder(w).Encode(post.Trade)
+}
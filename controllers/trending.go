@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"encoding/json"
+	"go-backend/config"
+	"go-backend/models"
+	"go-backend/utils"
+	"math"
+	"net/http"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// listTrendingPosts answers ListPosts with sort=trending. The ranking is
+// matched against the other filters as a whole, so pages are full and the
+// total counts only matching posts; then the posts of the page are loaded.
+func listTrendingPosts(w http.ResponseWriter, r *http.Request, filter bson.M, page, limit int) {
+	postType, _ := filter["type"].(string)
+	ranked, err := utils.Trending.Ranked(r.Context(), models.PostType(postType))
+	if err != nil {
+		http.Error(w, "Failed to fetch trending posts", http.StatusInternalServerError)
+		return
+	}
+
+	var ids []primitive.ObjectID
+	if len(ranked) > 0 {
+		filter["_id"] = bson.M{"$in": ranked}
+		cursor, err := config.PostCollection.Find(r.Context(), filter, options.Find().SetProjection(bson.M{"_id": 1}))
+		if err != nil {
+			http.Error(w, "Failed to fetch posts", http.StatusInternalServerError)
+			return
+		}
+		var matched []models.Post
+		if err := cursor.All(r.Context(), &matched); err != nil {
+			http.Error(w, "Failed to parse posts", http.StatusInternalServerError)
+			return
+		}
+		ids = matchingRanked(ranked, postIDsOf(matched))
+	}
+	total := len(ids)
+	ids = ids[min((page-1)*limit, total):min(page*limit, total)]
+
+	posts, err := readablePosts(r, ids)
+	if err != nil {
+		http.Error(w, "Failed to fetch posts", http.StatusInternalServerError)
+		return
+	}
+	if err := preparePosts(r, posts); err != nil {
+		http.Error(w, "Failed to render posts", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"posts": posts,
+		"pagination": map[string]interface{}{
+			"total":       total,
+			"page":        page,
+			"limit":       limit,
+			"total_pages": math.Ceil(float64(total) / float64(limit)),
+		},
+	})
+}
+
+// matchingRanked keeps the ranked IDs that are in matched, in rank order.
+func matchingRanked(ranked, matched []primitive.ObjectID) []primitive.ObjectID {
+	keep := make(map[primitive.ObjectID]bool, len(matched))
+	for _, id := range matched {
+		keep[id] = true
+	}
+	ids := []primitive.ObjectID{}
+	for _, id := range ranked {
+		if keep[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
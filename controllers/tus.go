@@ -0,0 +1,273 @@
+package controllers
+
+import (
+	"errors"
+	"go-backend/config"
+	"go-backend/utils"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// tus 1.0 resumable uploads. Progress lives in Redis and received chunks in
+// the blob store, so a client may continue an upload on any replica. Once the
+// last chunk arrives the file goes through the same checks and storage as
+// UploadMedia.
+
+const tusExtensions = "creation,termination,expiration"
+
+// tusHeaders sets the headers every tus response carries.
+func tusHeaders(w http.ResponseWriter) {
+	w.Header().Set("Tus-Resumable", utils.TusVersion)
+	w.Header().Set("Cache-Control", "no-store")
+}
+
+// tusVersionOK rejects requests for a protocol version we do not speak.
+func tusVersionOK(w http.ResponseWriter, r *http.Request) bool {
+	if r.Header.Get("Tus-Resumable") != utils.TusVersion {
+		w.Header().Set("Tus-Version", utils.TusVersion)
+		http.Error(w, "Unsupported tus version", http.StatusPreconditionFailed)
+		return false
+	}
+	return true
+}
+
+func tusUploadHeaders(w http.ResponseWriter, u *utils.TusUpload) {
+	w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(u.Length, 10))
+	w.Header().Set("Upload-Expires", u.ExpiresAt.UTC().Format(http.TimeFormat))
+	if u.MediaID != "" {
+		w.Header().Set("X-Media-ID", u.MediaID)
+	}
+}
+
+// findTusUpload loads the caller's upload in the URL, writing the error
+// response itself when it cannot.
+func findTusUpload(w http.ResponseWriter, r *http.Request) (*utils.TusUpload, bool) {
+	userID, _ := utils.ExtractUserIDFromRequest(r)
+	u, err := utils.Tus.Get(r.Context(), mux.Vars(r)["id"], userID)
+	switch {
+	case errors.Is(err, utils.ErrTusNotFound):
+		http.Error(w, "Upload not found", http.StatusNotFound)
+		return nil, false
+	case err != nil:
+		http.Error(w, "Failed to load upload", http.StatusInternalServerError)
+		return nil, false
+	}
+	return u, true
+}
+
+// TusOptions godoc
+// @Summary Discover tus upload capabilities
+// @Description Needs no token, so browsers can send it as a CORS preflight. Tus-Max-Size is only reported to signed in callers.
+// @Tags Media
+// @Success 204
+// @Router /api/v1/media/uploads [options]
+func TusOptions(w http.ResponseWriter, r *http.Request) {
+	tusHeaders(w)
+	w.Header().Set("Tus-Version", utils.TusVersion)
+	w.Header().Set("Tus-Extension", tusExtensions)
+	role, _ := utils.ExtractUserRole(r)
+	if limit := utils.MediaSizeLimit(role); limit > 0 {
+		w.Header().Set("Tus-Max-Size", strconv.FormatInt(limit, 10))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateTusUpload godoc
+// @Summary Start a resumable upload (tus creation)
+// @Description Requires Upload-Length. Upload-Metadata may carry "filename". Returns the upload URL in Location.
+// @Tags Media
+// @Security BearerAuth
+// @Param Tus-Resumable header string true "1.0.0"
+// @Param Upload-Length header int true "Total size in bytes"
+// @Param Upload-Metadata header string false "tus metadata"
+// @Success 201
+// @Failure 400 {string} string "Invalid headers"
+// @Failure 412 {string} string "Unsupported tus version"
+// @Failure 413 {string} string "File too large"
+// @Router /api/v1/media/uploads [post]
+func CreateTusUpload(w http.ResponseWriter, r *http.Request) {
+	tusHeaders(w)
+	if !tusVersionOK(w, r) {
+		return
+	}
+
+	userID, _ := utils.ExtractUserIDFromRequest(r)
+	role, _ := utils.ExtractUserRole(r)
+	limit := utils.MediaSizeLimit(role)
+	if limit == 0 {
+		http.Error(w, utils.ErrUploadNotAllowed.Error(), http.StatusForbidden)
+		return
+	}
+
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		http.Error(w, "Upload-Length must be a positive integer", http.StatusBadRequest)
+		return
+	}
+	if length > limit {
+		http.Error(w, utils.ErrMediaTooLarge.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	metadata, err := utils.ParseTusMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	u, err := utils.Tus.Create(r.Context(), userID, length, metadata)
+	if err != nil {
+		http.Error(w, "Failed to create upload", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Location", config.PublicBaseURL()+"/api/v1/media/uploads/"+u.ID)
+	w.Header().Set("Upload-Expires", u.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// GetTusUploadOffset godoc
+// @Summary Get the offset of a resumable upload
+// @Description X-Media-ID is set once the upload has been assembled.
+// @Tags Media
+// @Security BearerAuth
+// @Param id path string true "Upload ID"
+// @Success 200
+// @Failure 404 {string} string "Upload not found"
+// @Router /api/v1/media/uploads/{id} [head]
+func GetTusUploadOffset(w http.ResponseWriter, r *http.Request) {
+	tusHeaders(w)
+	if !tusVersionOK(w, r) {
+		return
+	}
+	u, ok := findTusUpload(w, r)
+	if !ok {
+		return
+	}
+	tusUploadHeaders(w, u)
+	w.WriteHeader(http.StatusOK)
+}
+
+// PatchTusUpload godoc
+// @Summary Append a chunk to a resumable upload
+// @Description Upload-Offset must match the current offset. After the last chunk the file is checked and stored like a regular upload and X-Media-ID is returned.
+// @Tags Media
+// @Security BearerAuth
+// @Accept application/offset+octet-stream
+// @Param id path string true "Upload ID"
+// @Param Upload-Offset header int true "Offset of this chunk"
+// @Success 204
+// @Failure 404 {string} string "Upload not found"
+// @Failure 409 {string} string "Offset mismatch"
+// @Failure 415 {string} string "Wrong content type or file type"
+// @Failure 423 {string} string "Upload busy"
+// @Router /api/v1/media/uploads/{id} [patch]
+func PatchTusUpload(w http.ResponseWriter, r *http.Request) {
+	tusHeaders(w)
+	if !tusVersionOK(w, r) {
+		return
+	}
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		http.Error(w, "Content-Type must be application/offset+octet-stream", http.StatusUnsupportedMediaType)
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		http.Error(w, "Upload-Offset must be a non-negative integer", http.StatusBadRequest)
+		return
+	}
+
+	u, ok := findTusUpload(w, r)
+	if !ok {
+		return
+	}
+
+	err = utils.Tus.Append(r.Context(), u, offset, r.Body, r.ContentLength)
+	if errors.Is(err, utils.ErrTusComplete) && u.MediaID == "" && offset == u.Length {
+		err = nil // all bytes arrived but assembling failed before; retry it
+	}
+	switch {
+	case errors.Is(err, utils.ErrTusOffsetMismatch), errors.Is(err, utils.ErrTusComplete):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, utils.ErrTusLocked):
+		http.Error(w, err.Error(), http.StatusLocked)
+		return
+	case err != nil:
+		// Usually a dropped connection; the client resumes from HEAD.
+		http.Error(w, "Failed to store chunk", http.StatusInternalServerError)
+		return
+	}
+
+	if u.Complete() && u.MediaID == "" {
+		if !assembleTusUpload(w, r, u) {
+			return
+		}
+	}
+
+	tusUploadHeaders(w, u)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// assembleTusUpload turns a finished upload into a media record. A file that
+// fails the upload checks is discarded along with the upload.
+func assembleTusUpload(w http.ResponseWriter, r *http.Request, u *utils.TusUpload) bool {
+	ownerID, _ := primitive.ObjectIDFromHex(u.OwnerID)
+
+	errStore := errors.New("failed to store media")
+	err := utils.Tus.Assemble(r.Context(), u, func(parts io.Reader) (string, error) {
+		upload, err := utils.SpoolUpload(parts, u.Length)
+		if err != nil {
+			if errors.Is(err, utils.ErrMediaType) || errors.Is(err, utils.ErrMediaUnreadable) || errors.Is(err, utils.ErrMediaEmpty) || errors.Is(err, utils.ErrImageTooLarge) {
+				utils.Tus.Terminate(r.Context(), u)
+			}
+			return "", err
+		}
+		defer upload.Close()
+
+		media, _, err := saveUpload(r.Context(), ownerID, u.Metadata["filename"], upload)
+		if err != nil {
+			return "", errStore
+		}
+		return media.ID.Hex(), nil
+	})
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, utils.ErrTusLocked):
+		http.Error(w, err.Error(), http.StatusLocked)
+	case errors.Is(err, errStore):
+		http.Error(w, "Failed to store media", http.StatusInternalServerError)
+	default:
+		writeUploadError(w, err)
+	}
+	return false
+}
+
+// TerminateTusUpload godoc
+// @Summary Cancel a resumable upload (tus termination)
+// @Tags Media
+// @Security BearerAuth
+// @Param id path string true "Upload ID"
+// @Success 204
+// @Failure 404 {string} string "Upload not found"
+// @Router /api/v1/media/uploads/{id} [delete]
+func TerminateTusUpload(w http.ResponseWriter, r *http.Request) {
+	tusHeaders(w)
+	if !tusVersionOK(w, r) {
+		return
+	}
+	u, ok := findTusUpload(w, r)
+	if !ok {
+		return
+	}
+	if err := utils.Tus.Terminate(r.Context(), u); err != nil {
+		http.Error(w, "Failed to terminate upload", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -4,9 +4,15 @@ import (
 	"encoding/json"
 	"go-backend/config"
 	"go-backend/models"
+	"go-backend/utils"
 	"net/http"
+	"strings"
 
+	"github.com/gorilla/mux"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // UserProfile godoc
@@ -57,3 +63,152 @@ func UserList(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(users)
 }
 
+// GetMe godoc
+// @Summary Get the caller's account
+// @Description The ETag header carries the version to send back as If-Match when patching.
+// @Tags User
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.User
+// @Failure 404 {string} string "User not found"
+// @Router /api/v1/users/me [get]
+func GetMe(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, _ := requestViewer(r)
+	var user models.User
+	if err := config.UserCollection.FindOne(r.Context(), bson.M{"_id": userID}).Decode(&user); err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	user.Password = "" // do not return hashed password
+
+	w.Header().Set("ETag", utils.VersionETag(user.Version))
+	json.NewEncoder(w).Encode(user)
+}
+
+// PatchMe godoc
+// @Summary Partially update the caller's account
+// @Description Applies a JSON Merge Patch (RFC 7396) to name and handle. Requires If-Match with the account's ETag.
+// @Tags User
+// @Security BearerAuth
+// @Accept application/merge-patch+json
+// @Produce json
+// @Param If-Match header string true "ETag of the account"
+// @Param patch body object true "Merge patch"
+// @Success 200 {object} models.User
+// @Failure 400 {string} string "Bad input"
+// @Failure 409 {string} string "Handle is taken"
+// @Failure 412 {string} string "Version does not match"
+// @Failure 428 {string} string "If-Match is required"
+// @Router /api/v1/users/me [patch]
+func PatchMe(w http.ResponseWriter, r *http.Request) {
+	userID, _ := requestViewer(r)
+	patchUser(w, r, userID, selfPatchFields)
+}
+
+// PatchUser godoc
+// @Summary Partially update a user
+// @Description Applies a JSON Merge Patch (RFC 7396) to name, handle, email, role and is_active (admin only). Requires If-Match with the user's ETag.
+// @Tags Admin
+// @Security BearerAuth
+// @Accept application/merge-patch+json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param If-Match header string true "ETag of the user"
+// @Param patch body object true "Merge patch"
+// @Success 200 {object} models.User
+// @Failure 400 {string} string "Bad input"
+// @Failure 404 {string} string "User not found"
+// @Failure 409 {string} string "Handle or email is taken"
+// @Failure 412 {string} string "Version does not match"
+// @Failure 428 {string} string "If-Match is required"
+// @Router /api/v1/admin/users/{id} [patch]
+func PatchUser(w http.ResponseWriter, r *http.Request) {
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	patchUser(w, r, id, adminPatchFields)
+}
+
+// patchUser applies a merge patch limited to fields to the user with id.
+func patchUser(w http.ResponseWriter, r *http.Request, id primitive.ObjectID, fields []string) {
+	w.Header().Set("Content-Type", "application/json")
+
+	patch, ok := readPatch(w, r, fields)
+	if !ok {
+		return
+	}
+	var existing models.User
+	if err := config.UserCollection.FindOne(r.Context(), bson.M{"_id": id}).Decode(&existing); err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if !checkIfMatch(w, r, existing.Version, true) {
+		return
+	}
+
+	user := existing
+	if err := utils.ApplyMergePatch(&user, patch); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	user.Name = strings.TrimSpace(user.Name)
+	user.Email = strings.TrimSpace(user.Email)
+	user.Role = strings.ToLower(strings.TrimSpace(user.Role))
+	if _, ok := patch["name"]; ok && user.Name == "" {
+		http.Error(w, "name cannot be empty", http.StatusBadRequest)
+		return
+	}
+	if _, ok := patch["handle"]; ok && (user.Handle == "" || utils.Slugify(user.Handle) != user.Handle) {
+		http.Error(w, "handle may only contain lowercase letters, digits and dashes", http.StatusBadRequest)
+		return
+	}
+	if _, ok := patch["email"]; ok && !strings.Contains(user.Email, "@") {
+		http.Error(w, "email is invalid", http.StatusBadRequest)
+		return
+	}
+	if _, ok := patch["role"]; ok && !models.ValidRole(user.Role) {
+		http.Error(w, "role must be one of "+strings.Join(models.Roles, ", "), http.StatusBadRequest)
+		return
+	}
+	if user.Email != existing.Email {
+		n, err := config.UserCollection.CountDocuments(r.Context(),
+			bson.M{"email": user.Email, "_id": bson.M{"$ne": id}}, options.Count().SetLimit(1))
+		if err != nil {
+			http.Error(w, "Failed to update user", http.StatusInternalServerError)
+			return
+		}
+		if n > 0 {
+			http.Error(w, "Email is taken", http.StatusConflict)
+			return
+		}
+	}
+
+	update, err := patchUpdate(user, patch, bson.M{})
+	if err != nil {
+		http.Error(w, "Failed to update user", http.StatusInternalServerError)
+		return
+	}
+	err = config.UserCollection.FindOneAndUpdate(r.Context(),
+		bson.M{"_id": id, "version": utils.VersionFilter(existing.Version)}, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, "Version does not match, reload and try again", http.StatusPreconditionFailed)
+		return
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		http.Error(w, "Handle is taken", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update user", http.StatusInternalServerError)
+		return
+	}
+	user.Password = "" // do not return hashed password
+
+	w.Header().Set("ETag", utils.VersionETag(user.Version))
+	json.NewEncoder(w).Encode(user)
+}
@@ -0,0 +1,232 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"go-backend/config"
+	"go-backend/models"
+	"go-backend/utils"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TransitionRequest struct {
+	Reason string `json:"reason,omitempty"`
+	Force  bool   `json:"force,omitempty"` // publish only: go live now even if scheduled
+}
+
+// transitionPost applies a workflow action to the post in the URL and records
+// who performed it in the post's status history.
+func transitionPost(w http.ResponseWriter, r *http.Request, action string) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, _ := utils.ExtractUserIDFromRequest(r)
+	role, _ := utils.ExtractUserRole(r)
+	actorID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	postID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	var req TransitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if action == models.ActionReject && req.Reason == "" {
+		http.Error(w, "A reason is required to reject a post", http.StatusBadRequest)
+		return
+	}
+	if action == models.ActionPublish && (req.Force || r.URL.Query().Get("force") == "true") {
+		action = models.ActionForcePublish
+	}
+
+	var post models.Post
+	if err := config.PostCollection.FindOne(r.Context(), bson.M{"_id": postID}).Decode(&post); err != nil {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	}
+
+	role = strings.ToLower(role)
+	to, err := models.NextStatus(action, post.Status, role, post.AuthorID == actorID)
+	switch {
+	case errors.Is(err, models.ErrTransitionForbidden):
+		http.Error(w, "Not allowed to "+action+" this post", http.StatusForbidden)
+		return
+	case errors.Is(err, models.ErrInvalidTransition):
+		http.Error(w, "Cannot "+action+" a post that is "+post.Status, http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	set := bson.M{"updated_at": now}
+	unset := bson.M{}
+
+	switch to {
+	case models.PostStatusPublished:
+		if action != models.ActionForcePublish && post.ScheduledAt != nil && post.ScheduledAt.After(now) {
+			to = models.PostStatusScheduled
+		} else {
+			set["published_at"] = now
+		}
+	case models.PostStatusDraft:
+		unset["published_at"] = ""
+	}
+	set["status"] = to
+
+	change := models.StatusChange{
+		Action: action,
+		From:   post.Status,
+		To:     to,
+		By:     actorID,
+		Role:   role,
+		Reason: req.Reason,
+		At:     now,
+	}
+
+	update := bson.M{"$set": set, "$push": bson.M{"status_history": change}, "$inc": bson.M{"version": 1}}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	// Guard on the status we validated against so concurrent transitions
+	// cannot both succeed.
+	result, err := config.PostCollection.UpdateOne(r.Context(), bson.M{"_id": postID, "status": post.Status}, update)
+	if err != nil {
+		http.Error(w, "Failed to update post status", http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, "Post status changed concurrently, please retry", http.StatusConflict)
+		return
+	}
+
+	if err := config.PostCollection.FindOne(r.Context(), bson.M{"_id": postID}).Decode(&post); err != nil {
+		http.Error(w, "Failed to reload post", http.StatusInternalServerError)
+		return
+	}
+
+	switch {
+	case to == models.PostStatusPublished:
+		utils.Events.Emit(r.Context(), utils.PostPublishedEvent(post, now))
+	case change.From == models.PostStatusPublished:
+		utils.Events.Emit(r.Context(), utils.PostUnpublishedEvent(post, action, now))
+	}
+
+	json.NewEncoder(w).Encode(post)
+}
+
+// SubmitPost godoc
+// @Summary Submit a draft for review
+// @Tags Workflow
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Post ID"
+// @Success 200 {object} models.Post
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Post not found"
+// @Failure 409 {string} string "Invalid transition"
+// @Router /api/v1/posts/{id}/submit [post]
+func SubmitPost(w http.ResponseWriter, r *http.Request) {
+	transitionPost(w, r, models.ActionSubmit)
+}
+
+// ApprovePost godoc
+// @Summary Approve a post in review
+// @Description Admin only
+// @Tags Workflow
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Post ID"
+// @Success 200 {object} models.Post
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Post not found"
+// @Failure 409 {string} string "Invalid transition"
+// @Router /api/v1/posts/{id}/approve [post]
+func ApprovePost(w http.ResponseWriter, r *http.Request) {
+	transitionPost(w, r, models.ActionApprove)
+}
+
+// RejectPost godoc
+// @Summary Reject a post in review back to draft
+// @Description Admin only. A reason is required.
+// @Tags Workflow
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param body body TransitionRequest true "Rejection reason"
+// @Success 200 {object} models.Post
+// @Failure 400 {string} string "Missing reason"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Post not found"
+// @Failure 409 {string} string "Invalid transition"
+// @Router /api/v1/posts/{id}/reject [post]
+func RejectPost(w http.ResponseWriter, r *http.Request) {
+	transitionPost(w, r, models.ActionReject)
+}
+
+// PublishPost godoc
+// @Summary Publish an approved post
+// @Description Publishes now, or schedules the post when scheduled_at is in the future. With force, an approved or scheduled post goes live immediately.
+// @Tags Workflow
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param force query bool false "Publish now, ignoring scheduled_at"
+// @Param body body TransitionRequest false "Options"
+// @Success 200 {object} models.Post
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Post not found"
+// @Failure 409 {string} string "Invalid transition"
+// @Router /api/v1/posts/{id}/publish [post]
+func PublishPost(w http.ResponseWriter, r *http.Request) {
+	transitionPost(w, r, models.ActionPublish)
+}
+
+// UnpublishPost godoc
+// @Summary Unpublish a published or scheduled post back to draft
+// @Tags Workflow
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Post ID"
+// @Success 200 {object} models.Post
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Post not found"
+// @Failure 409 {string} string "Invalid transition"
+// @Router /api/v1/posts/{id}/unpublish [post]
+func UnpublishPost(w http.ResponseWriter, r *http.Request) {
+	transitionPost(w, r, models.ActionUnpublish)
+}
+
+// ArchivePost godoc
+// @Summary Archive a post
+// @Tags Workflow
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Post ID"
+// @Success 200 {object} models.Post
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Post not found"
+// @Failure 409 {string} string "Invalid transition"
+// @Router /api/v1/posts/{id}/archive [post]
+func ArchivePost(w http.ResponseWriter, r *http.Request) {
+	transitionPost(w, r, models.ActionArchive)
+}
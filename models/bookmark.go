@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Bookmark is a post a user saved for later.
+type Bookmark struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	PostID    primitive.ObjectID `bson:"post_id" json:"post_id"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+
+	Post *Post `bson:"post,omitempty" json:"post,omitempty"` // filled in on read
+}
+
+// ReadingList is a named, ordered set of posts. Anyone with the share link
+// can read it while ShareToken is set.
+type ReadingList struct {
+	ID          primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	OwnerID     primitive.ObjectID   `bson:"owner_id" json:"owner_id"`
+	Name        string               `bson:"name" json:"name"`
+	Description string               `bson:"description,omitempty" json:"description,omitempty"`
+	PostIDs     []primitive.ObjectID `bson:"post_ids" json:"post_ids"`
+	ShareToken  string               `bson:"share_token,omitempty" json:"-"`
+	ShareURL    string               `bson:"-" json:"share_url,omitempty"` // owner only
+	CreatedAt   time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time            `bson:"updated_at" json:"updated_at"`
+
+	Posts []Post `bson:"-" json:"posts,omitempty"` // readable posts in order, filled in on read
+}
+
+// MaxReadingListPosts caps how many posts a reading list holds.
+const MaxReadingListPosts = 500
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type CollaboratorRole string
+
+// Collaborator roles. Co-authors and editors may edit a post; co-authors are
+// also credited publicly. Viewers may only read it, drafts included.
+const (
+	CollaboratorCoAuthor CollaboratorRole = "co-author"
+	CollaboratorEditor   CollaboratorRole = "editor"
+	CollaboratorViewer   CollaboratorRole = "viewer"
+)
+
+// MaxCollaborators caps the collaborators of one post.
+const MaxCollaborators = 20
+
+func (r CollaboratorRole) Valid() bool {
+	switch r {
+	case CollaboratorCoAuthor, CollaboratorEditor, CollaboratorViewer:
+		return true
+	}
+	return false
+}
+
+// CanEdit tells whether the role grants edit rights.
+func (r CollaboratorRole) CanEdit() bool {
+	return r == CollaboratorCoAuthor || r == CollaboratorEditor
+}
+
+// Collaborator is a user the owner of a post shares it with.
+type Collaborator struct {
+	UserID  primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Role    CollaboratorRole   `bson:"role" json:"role"`
+	AddedAt time.Time          `bson:"added_at" json:"added_at"`
+	User    *UserSummary       `bson:"-" json:"user,omitempty"` // filled in on read
+}
+
+// CollaboratorRole returns the role userID has on the post, empty when they
+// are not a collaborator.
+func (p Post) CollaboratorRole(userID primitive.ObjectID) CollaboratorRole {
+	for _, c := range p.Collaborators {
+		if c.UserID == userID {
+			return c.Role
+		}
+	}
+	return ""
+}
+
+// CanEdit tells whether userID may edit the post: its author, a co-author or
+// an editor.
+func (p Post) CanEdit(userID primitive.ObjectID) bool {
+	if userID.IsZero() {
+		return false
+	}
+	return p.AuthorID == userID || p.CollaboratorRole(userID).CanEdit()
+}
+
+// CoAuthorIDs lists the co-authors of the post in the order they were added.
+func (p Post) CoAuthorIDs() []primitive.ObjectID {
+	var ids []primitive.ObjectID
+	for _, c := range p.Collaborators {
+		if c.Role == CollaboratorCoAuthor {
+			ids = append(ids, c.UserID)
+		}
+	}
+	return ids
+}
+
+// EditableFilter is the query form of CanEdit.
+func EditableFilter(userID primitive.ObjectID) bson.M {
+	return bson.M{"$or": []bson.M{
+		{"author_id": userID},
+		{"collaborators": bson.M{"$elemMatch": bson.M{
+			"user_id": userID,
+			"role":    bson.M{"$in": []CollaboratorRole{CollaboratorCoAuthor, CollaboratorEditor}},
+		}}},
+	}}
+}
+
+// SharedFilter matches the posts userID owns or collaborates on in any role,
+// drafts included.
+func SharedFilter(userID primitive.ObjectID) bson.M {
+	return bson.M{"$or": []bson.M{
+		{"author_id": userID},
+		{"collaborators.user_id": userID},
+	}}
+}
+
+// ByAuthorFilter matches the posts userID wrote or co-authored.
+func ByAuthorFilter(userID primitive.ObjectID) bson.M {
+	return bson.M{"$or": []bson.M{
+		{"author_id": userID},
+		{"collaborators": bson.M{"$elemMatch": bson.M{"user_id": userID, "role": CollaboratorCoAuthor}}},
+	}}
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// FilterAction is what the content filter does about a finding.
+type FilterAction string
+
+const (
+	FilterReject FilterAction = "reject" // refuse the post
+	FilterFlag   FilterAction = "flag"   // accept it and queue it for moderation
+	FilterMask   FilterAction = "mask"   // accept it with the offending text masked
+)
+
+// Severity orders actions so the strongest finding decides the outcome.
+func (a FilterAction) Severity() int {
+	switch a {
+	case FilterReject:
+		return 3
+	case FilterFlag:
+		return 2
+	case FilterMask:
+		return 1
+	}
+	return 0
+}
+
+func (a FilterAction) Valid() bool {
+	return a.Severity() > 0
+}
+
+// BlockedTerm is a word or phrase matched case-insensitively on word
+// boundaries, so "ass" does not match "class".
+type BlockedTerm struct {
+	Term   string       `bson:"term" json:"term"`
+	Action FilterAction `bson:"action" json:"action"`
+}
+
+// ContentFilterConfig is the runtime configuration of the content filter,
+// managed by admins and stored as a single document.
+type ContentFilterConfig struct {
+	BlockedTerms   []BlockedTerm `bson:"blocked_terms" json:"blocked_terms"`
+	AllowedDomains []string      `bson:"allowed_domains" json:"allowed_domains"` // when set, links may only point here
+	DeniedDomains  []string      `bson:"denied_domains" json:"denied_domains"`
+	DomainAction   FilterAction  `bson:"domain_action" json:"domain_action"`
+	MaxLinks       int           `bson:"max_links" json:"max_links"` // 0 means no limit
+	MaxLinksAction FilterAction  `bson:"max_links_action" json:"max_links_action"`
+	UpdatedAt      time.Time     `bson:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Follow subscribes FollowerID to the posts of AuthorID.
+type Follow struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	FollowerID primitive.ObjectID `bson:"follower_id" json:"follower_id"`
+	AuthorID   primitive.ObjectID `bson:"author_id" json:"author_id"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+
+	User *UserSummary `bson:"user,omitempty" json:"user,omitempty"` // the other side, filled in on read
+}
+
+// UserSummary is the public part of a user, safe to show to anyone.
+type UserSummary struct {
+	ID     primitive.ObjectID `bson:"_id" json:"id"`
+	Name   string             `bson:"name" json:"name"`
+	Handle string             `bson:"handle,omitempty" json:"handle,omitempty"`
+	Role   string             `bson:"role" json:"role"`
+}
+
+// MaxFollowing caps how many users one user may follow.
+const MaxFollowing = 5000
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Media is an uploaded file. The bytes live in the blob store under Key.
+type Media struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OwnerID     primitive.ObjectID `bson:"owner_id" json:"owner_id"`
+	Key         string             `bson:"key" json:"key"`
+	URL         string             `bson:"url" json:"url"`
+	Filename    string             `bson:"filename,omitempty" json:"filename,omitempty"` // as sent by the client
+	ContentType string             `bson:"content_type" json:"content_type"`             // sniffed, not trusted from the client
+	Size        int64              `bson:"size" json:"size"`
+	SHA256      string             `bson:"sha256" json:"sha256"` // of the file as uploaded
+	Width       int                `bson:"width,omitempty" json:"width,omitempty"`
+	Height      int                `bson:"height,omitempty" json:"height,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+
+	// Filled in by the image pipeline once the upload is processed.
+	Status      string         `bson:"status,omitempty" json:"status,omitempty"` // see MediaStatus*
+	Variants    []MediaVariant `bson:"variants,omitempty" json:"variants,omitempty"`
+	Blurhash    string         `bson:"blurhash,omitempty" json:"blurhash,omitempty"`
+	LQIP        string         `bson:"lqip,omitempty" json:"lqip,omitempty"` // tiny inline data: URI placeholder
+	ProcessedAt *time.Time     `bson:"processed_at,omitempty" json:"processed_at,omitempty"`
+	Error       string         `bson:"error,omitempty" json:"-"`
+
+	// Reference tracking for garbage collection. UnreferencedAt is set while
+	// no post uses the media; it is collected once that is old enough.
+	PostIDs        []primitive.ObjectID `bson:"post_ids,omitempty" json:"post_ids,omitempty"`
+	UnreferencedAt *time.Time           `bson:"unreferenced_at,omitempty" json:"unreferenced_at,omitempty"`
+	Attempts       int                  `bson:"attempts,omitempty" json:"-"`
+}
+
+// Processing states of uploaded images. Other media is stored as is and
+// is ready straight away.
+const (
+	MediaStatusPending = "pending"
+	MediaStatusReady   = "ready"
+	MediaStatusFailed  = "failed"
+)
+
+// MediaVariant is a resized rendition of an image, e.g. "thumb".
+type MediaVariant struct {
+	Name        string `bson:"name" json:"name"`
+	Key         string `bson:"key" json:"-"`
+	URL         string `bson:"url" json:"url"`
+	ContentType string `bson:"content_type" json:"content_type"`
+	Width       int    `bson:"width" json:"width"`
+	Height      int    `bson:"height" json:"height"`
+	Size        int64  `bson:"size" json:"size"`
+}
+
+// IsImage tells whether the media can be processed as an image.
+func (m Media) IsImage() bool {
+	return strings.HasPrefix(m.ContentType, "image/")
+}
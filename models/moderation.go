@@ -0,0 +1,124 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Reasons a reader may give when reporting a post.
+const (
+	ReportReasonSpam       = "spam"
+	ReportReasonScam       = "scam"
+	ReportReasonAbuse      = "abuse"
+	ReportReasonMisleading = "misleading"
+	ReportReasonOther      = "other"
+)
+
+var ReportReasons = []string{ReportReasonSpam, ReportReasonScam, ReportReasonAbuse, ReportReasonMisleading, ReportReasonOther}
+
+func ValidReportReason(reason string) bool {
+	for _, r := range ReportReasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// Report is one reader flagging a post. Reports of the same post are grouped
+// into the ModerationCase that is open when they arrive.
+type Report struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	CaseID     primitive.ObjectID `bson:"case_id" json:"case_id"`
+	PostID     primitive.ObjectID `bson:"post_id" json:"post_id"`
+	ReporterID primitive.ObjectID `bson:"reporter_id" json:"reporter_id"`
+	Reason     string             `bson:"reason" json:"reason"` // see ReportReason*
+	Details    string             `bson:"details,omitempty" json:"details,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// Moderation case statuses:
+//
+//	open -> claimed -> resolved
+const (
+	CaseStatusOpen     = "open"
+	CaseStatusClaimed  = "claimed"
+	CaseStatusResolved = "resolved"
+)
+
+// Moderation actions, recorded in the case history.
+const (
+	ModerationAutoHide = "auto_hide" // report threshold reached
+	ModerationFlag     = "flag"      // queued by the content filter
+	ModerationClaim    = "claim"
+	ModerationHide     = "hide"
+	ModerationRestore  = "restore"
+	ModerationResolve  = "resolve"
+)
+
+// ModerationClaimTTL is how long a claim keeps other moderators off a case.
+const ModerationClaimTTL = time.Hour
+
+var (
+	ErrCaseResolved   = errors.New("case is already resolved")
+	ErrCaseClaimed    = errors.New("case is claimed by another moderator")
+	ErrCaseNotClaimed = errors.New("claim the case before acting on it")
+)
+
+// ModerationDecision is one entry in a case's audit trail.
+type ModerationDecision struct {
+	Action string             `bson:"action" json:"action"`
+	By     primitive.ObjectID `bson:"by" json:"by"`
+	Role   string             `bson:"role" json:"role"`
+	Note   string             `bson:"note,omitempty" json:"note,omitempty"`
+	At     time.Time          `bson:"at" json:"at"`
+}
+
+// ModerationCase collects the reports of a post until a moderator resolves
+// it. A post has at most one unresolved case.
+type ModerationCase struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	PostID      primitive.ObjectID  `bson:"post_id" json:"post_id"`
+	AuthorID    primitive.ObjectID  `bson:"author_id" json:"author_id"`
+	Status      string              `bson:"status" json:"status"`                   // see CaseStatus*
+	Active      bool                `bson:"active,omitempty" json:"-"`              // set until resolved; unique per post
+	ReportCount int                 `bson:"report_count" json:"report_count"`       // distinct reporters
+	Reasons     map[string]int      `bson:"reasons,omitempty" json:"reasons"`       // reports per reason
+	ClaimedBy   *primitive.ObjectID `bson:"claimed_by,omitempty" json:"claimed_by"` // nil when unclaimed
+	ClaimedAt   *time.Time          `bson:"claimed_at,omitempty" json:"claimed_at,omitempty"`
+	Resolution  string              `bson:"resolution,omitempty" json:"resolution,omitempty"` // hidden or kept
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time           `bson:"updated_at" json:"updated_at"`
+	ResolvedAt  *time.Time          `bson:"resolved_at,omitempty" json:"resolved_at,omitempty"`
+
+	History []ModerationDecision `bson:"history,omitempty" json:"history"`
+
+	Post    *Post    `bson:"-" json:"post,omitempty"`    // filled in on read
+	Reports []Report `bson:"-" json:"reports,omitempty"` // filled in on read
+}
+
+// CheckAction tells whether actorID with role may perform action on the case
+// now. Claims go stale after ModerationClaimTTL; admins may act on any case.
+func (c ModerationCase) CheckAction(action string, actorID primitive.ObjectID, role string, now time.Time) error {
+	if c.Status == CaseStatusResolved {
+		return ErrCaseResolved
+	}
+	claimedByActor := c.ClaimedBy != nil && *c.ClaimedBy == actorID
+	claimLive := c.ClaimedBy != nil && c.ClaimedAt != nil && now.Sub(*c.ClaimedAt) < ModerationClaimTTL
+
+	if action == ModerationClaim {
+		if claimLive && !claimedByActor && role != "admin" {
+			return ErrCaseClaimed
+		}
+		return nil
+	}
+	if role == "admin" || (claimedByActor && claimLive) {
+		return nil
+	}
+	if claimLive {
+		return ErrCaseClaimed
+	}
+	return ErrCaseNotClaimed
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestModerationCase_CheckAction(t *testing.T) {
+	now := time.Now()
+	alice, bob := primitive.NewObjectID(), primitive.NewObjectID()
+	open := ModerationCase{Status: CaseStatusOpen}
+
+	assert.NoError(t, open.CheckAction(ModerationClaim, alice, "moderator", now))
+	assert.ErrorIs(t, open.CheckAction(ModerationHide, alice, "moderator", now), ErrCaseNotClaimed)
+	assert.NoError(t, open.CheckAction(ModerationHide, alice, "admin", now), "admins need no claim")
+
+	claimedAt := now.Add(-10 * time.Minute)
+	claimed := ModerationCase{Status: CaseStatusClaimed, ClaimedBy: &alice, ClaimedAt: &claimedAt}
+	assert.NoError(t, claimed.CheckAction(ModerationResolve, alice, "moderator", now))
+	assert.ErrorIs(t, claimed.CheckAction(ModerationClaim, bob, "moderator", now), ErrCaseClaimed)
+	assert.ErrorIs(t, claimed.CheckAction(ModerationRestore, bob, "moderator", now), ErrCaseClaimed)
+	assert.NoError(t, claimed.CheckAction(ModerationClaim, bob, "admin", now))
+
+	// A stale claim may be taken over, but not acted on without reclaiming.
+	later := now.Add(ModerationClaimTTL)
+	assert.NoError(t, claimed.CheckAction(ModerationClaim, bob, "moderator", later))
+	assert.ErrorIs(t, claimed.CheckAction(ModerationHide, alice, "moderator", later), ErrCaseNotClaimed)
+
+	resolved := ModerationCase{Status: CaseStatusResolved}
+	assert.ErrorIs(t, resolved.CheckAction(ModerationClaim, alice, "admin", now), ErrCaseResolved)
+}
+
+func TestValidReportReason(t *testing.T) {
+	assert.True(t, ValidReportReason(ReportReasonSpam))
+	assert.False(t, ValidReportReason("boring"))
+	assert.False(t, ValidReportReason(""))
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TickerPerformance struct {
+	Instrument string  `json:"instrument"`
+	Trades     int     `json:"trades"`
+	Wins       int     `json:"wins"`
+	WinRate    float64 `json:"win_rate"`
+	AvgR       float64 `json:"avg_r"`
+	TotalR     float64 `json:"total_r"`
+}
+
+// AuthorPerformance summarizes the closed trade posts of a single author.
+// All R values are multiples of the initial risk (entry to stop).
+type AuthorPerformance struct {
+	AuthorID    primitive.ObjectID  `json:"author_id"`
+	From        *time.Time          `json:"from,omitempty"`
+	To          *time.Time          `json:"to,omitempty"`
+	Trades      int                 `json:"trades"`
+	Wins        int                 `json:"wins"`
+	Losses      int                 `json:"losses"`
+	WinRate     float64             `json:"win_rate"`
+	AvgR        float64             `json:"avg_r"`
+	AvgWinR     float64             `json:"avg_win_r"`
+	AvgLossR    float64             `json:"avg_loss_r"`
+	Expectancy  float64             `json:"expectancy"`
+	TotalR      float64             `json:"total_r"`
+	MaxDrawdown float64             `json:"max_drawdown_r"`
+	ByTicker    []TickerPerformance `json:"by_ticker"`
+}
+
+type LeaderboardEntry struct {
+	Rank int `json:"rank"`
+	AuthorPerformance
+}
+
+type Leaderboard struct {
+	GeneratedAt time.Time          `json:"generated_at"`
+	WindowDays  int                `json:"window_days"`
+	Entries     []LeaderboardEntry `json:"entries"`
+}
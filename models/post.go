@@ -3,6 +3,7 @@ package models
 import (
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -25,14 +26,72 @@ type Post struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	AuthorID    primitive.ObjectID `bson:"author_id" json:"author_id"`
 	Title       string             `bson:"title" json:"title"`
-	Content     string             `bson:"content" json:"content"` // markdown
+	Slug        string             `bson:"slug,omitempty" json:"slug,omitempty"`
+	SlugHistory []string           `bson:"slug_history,omitempty" json:"-"` // previous slugs, redirected with 301
+	Content     string             `bson:"content" json:"content"`          // markdown
 	Tags        []string           `bson:"tags" json:"tags"`
 	Visibility  Visibility         `bson:"visibility" json:"visibility"` // public, private, premium
 	Type        PostType           `bson:"type" json:"type"`             // idea, trade
-	Status      string             `bson:"status" json:"status"`         // draft, published, scheduled
+	Status      string             `bson:"status" json:"status"`         // see PostStatus* in workflow.go
 	MediaURLs   []string           `bson:"media_urls,omitempty" json:"media_urls,omitempty"`
+	Trade       *Trade             `bson:"trade,omitempty" json:"trade,omitempty"` // only set on trade posts
 	ScheduledAt *time.Time         `bson:"scheduled_at,omitempty" json:"scheduled_at,omitempty"`
 	PublishedAt *time.Time         `bson:"published_at,omitempty" json:"published_at,omitempty"`
+	ExpiresAt   *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"` // unpublished automatically after this
+	Hidden      bool               `bson:"hidden,omitempty" json:"hidden,omitempty"`         // taken down by moderation, see moderation.go
+	HideReason  string             `bson:"hide_reason,omitempty" json:"hide_reason,omitempty"`
 	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	Version     int64              `bson:"version,omitempty" json:"version"` // bumped by every edit, served as the ETag
+
+	StatusHistory []StatusChange `bson:"status_history,omitempty" json:"status_history,omitempty"`
+	Collaborators []Collaborator `bson:"collaborators,omitempty" json:"-"` // managed through the collaborator endpoints
+
+	RenderedContent `bson:"-"`    // filled in on read
+	Media           []Media       `bson:"-" json:"media,omitempty"`      // uploads referenced by MediaURLs, filled in on read
+	CoAuthors       []UserSummary `bson:"-" json:"co_authors,omitempty"` // filled in on read
+	Series          *SeriesNav    `bson:"-" json:"series,omitempty"`     // previous and next parts, filled in on read of one post
+}
+
+// VisibleTo tells whether a reader may see the post given its visibility.
+// Authors and collaborators always see their own posts and admins see
+// everything; premium posts are for premium readers and private posts for
+// the author only. Hidden posts are left to moderators, who also see premium
+// posts.
+func (p Post) VisibleTo(userID primitive.ObjectID, role string) bool {
+	if role == "admin" || (!userID.IsZero() && (userID == p.AuthorID || p.CollaboratorRole(userID) != "")) {
+		return true
+	}
+	if p.Hidden && role != "moderator" {
+		return false
+	}
+	switch p.Visibility {
+	case VisibilityPremium:
+		return role == "premium" || role == "moderator"
+	case VisibilityPrivate:
+		return false
+	}
+	return true
+}
+
+// VisibilityFilter is the query form of VisibleTo, for listing posts a
+// reader may see straight from the database.
+func VisibilityFilter(userID primitive.ObjectID, role string) bson.M {
+	if role == "admin" {
+		return bson.M{}
+	}
+	public := bson.M{"visibility": bson.M{"$nin": []Visibility{VisibilityPremium, VisibilityPrivate}}}
+	premium := bson.M{"visibility": VisibilityPremium}
+	if role != "moderator" {
+		public["hidden"] = bson.M{"$ne": true}
+		premium["hidden"] = bson.M{"$ne": true}
+	}
+	or := []bson.M{public}
+	if role == "premium" || role == "moderator" {
+		or = append(or, premium)
+	}
+	if !userID.IsZero() {
+		or = append(or, bson.M{"author_id": userID}, bson.M{"collaborators.user_id": userID})
+	}
+	return bson.M{"$or": or}
 }
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPost_VisibleTo(t *testing.T) {
+	author, reader := primitive.NewObjectID(), primitive.NewObjectID()
+	premium := Post{AuthorID: author, Visibility: VisibilityPremium}
+	private := Post{AuthorID: author, Visibility: VisibilityPrivate}
+	public := Post{AuthorID: author, Visibility: VisibilityPublic}
+
+	assert.True(t, public.VisibleTo(primitive.NilObjectID, ""))
+	assert.True(t, premium.VisibleTo(reader, "premium"))
+	assert.False(t, premium.VisibleTo(reader, "user"))
+	assert.True(t, premium.VisibleTo(author, "author"))
+	assert.False(t, private.VisibleTo(reader, "premium"))
+	assert.True(t, private.VisibleTo(reader, "admin"))
+	assert.False(t, private.VisibleTo(primitive.NilObjectID, ""), "anonymous is not the author")
+	assert.True(t, premium.VisibleTo(reader, "moderator"))
+	assert.False(t, private.VisibleTo(reader, "moderator"))
+}
+
+func TestPost_VisibleTo_Hidden(t *testing.T) {
+	author, reader := primitive.NewObjectID(), primitive.NewObjectID()
+	hidden := Post{AuthorID: author, Visibility: VisibilityPublic, Hidden: true}
+
+	assert.False(t, hidden.VisibleTo(primitive.NilObjectID, ""))
+	assert.False(t, hidden.VisibleTo(reader, "premium"))
+	assert.True(t, hidden.VisibleTo(author, "author"))
+	assert.True(t, hidden.VisibleTo(reader, "moderator"))
+	assert.True(t, hidden.VisibleTo(reader, "admin"))
+}
+
+func TestVisibilityFilter(t *testing.T) {
+	reader := primitive.NewObjectID()
+
+	assert.Empty(t, VisibilityFilter(reader, "admin"))
+	assert.Len(t, VisibilityFilter(primitive.NilObjectID, "")["$or"], 1)
+	assert.Len(t, VisibilityFilter(reader, "user")["$or"], 3)
+	assert.Len(t, VisibilityFilter(reader, "premium")["$or"], 4)
+	assert.Len(t, VisibilityFilter(reader, "moderator")["$or"], 4)
+
+	or := VisibilityFilter(reader, "user")["$or"].([]bson.M)
+	assert.Equal(t, bson.M{"$ne": true}, or[0]["hidden"], "hidden posts are left out")
+	or = VisibilityFilter(reader, "moderator")["$or"].([]bson.M)
+	assert.NotContains(t, or[0], "hidden")
+}
+
+func TestPost_Collaborators(t *testing.T) {
+	owner, coAuthor, editor, viewer, stranger := primitive.NewObjectID(), primitive.NewObjectID(),
+		primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	post := Post{AuthorID: owner, Visibility: VisibilityPrivate, Collaborators: []Collaborator{
+		{UserID: coAuthor, Role: CollaboratorCoAuthor},
+		{UserID: editor, Role: CollaboratorEditor},
+		{UserID: viewer, Role: CollaboratorViewer},
+	}}
+
+	assert.True(t, post.CanEdit(owner))
+	assert.True(t, post.CanEdit(coAuthor))
+	assert.True(t, post.CanEdit(editor))
+	assert.False(t, post.CanEdit(viewer))
+	assert.False(t, post.CanEdit(stranger))
+	assert.False(t, post.CanEdit(primitive.NilObjectID))
+
+	assert.True(t, post.VisibleTo(viewer, "user"), "collaborators see private posts")
+	assert.False(t, post.VisibleTo(stranger, "premium"))
+	assert.Equal(t, []primitive.ObjectID{coAuthor}, post.CoAuthorIDs())
+}
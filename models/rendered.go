@@ -0,0 +1,10 @@
+package models
+
+// RenderedContent is derived from Post.Content when a post is served.
+// It is cached by content hash and never stored with the post.
+type RenderedContent struct {
+	ContentHTML string `json:"content_html,omitempty"` // sanitized
+	Excerpt     string `json:"excerpt,omitempty"`      // plain text
+	WordCount   int    `json:"word_count,omitempty"`
+	ReadingTime int    `json:"reading_time,omitempty"` // minutes
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Series is an ordered run of posts by one author, such as a multi-part
+// analysis. A post belongs to at most one series.
+type Series struct {
+	ID          primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	AuthorID    primitive.ObjectID   `bson:"author_id" json:"author_id"`
+	Title       string               `bson:"title" json:"title"`
+	Description string               `bson:"description,omitempty" json:"description,omitempty"`
+	PostIDs     []primitive.ObjectID `bson:"post_ids" json:"post_ids"`
+	CreatedAt   time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time            `bson:"updated_at" json:"updated_at"`
+
+	Posts []Post `bson:"-" json:"posts,omitempty"` // readable posts in order, filled in on read
+}
+
+// Collection is a curated, ordered list of posts by any authors, kept by an
+// admin or author.
+type Collection struct {
+	ID          primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	OwnerID     primitive.ObjectID   `bson:"owner_id" json:"owner_id"`
+	Title       string               `bson:"title" json:"title"`
+	Description string               `bson:"description,omitempty" json:"description,omitempty"`
+	PostIDs     []primitive.ObjectID `bson:"post_ids" json:"post_ids"`
+	CreatedAt   time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time            `bson:"updated_at" json:"updated_at"`
+
+	Posts []Post `bson:"-" json:"posts,omitempty"` // readable posts in order, filled in on read
+}
+
+const (
+	MaxSeriesPosts     = 100
+	MaxCollectionPosts = 500
+)
+
+// PostLink points at another post.
+type PostLink struct {
+	ID    primitive.ObjectID `json:"id"`
+	Title string             `json:"title"`
+	Slug  string             `json:"slug,omitempty"`
+}
+
+// SeriesNav places a post within its series, counting only the posts the
+// reader may see.
+type SeriesNav struct {
+	ID       primitive.ObjectID `json:"id"`
+	Title    string             `json:"title"`
+	Position int                `json:"position"` // 1-based
+	Total    int                `json:"total"`
+	Prev     *PostLink          `json:"prev,omitempty"`
+	Next     *PostLink          `json:"next,omitempty"`
+}
+
+// NewSeriesNav locates postID among posts, the readable posts of s in series
+// order. It returns nil when postID is not among them.
+func NewSeriesNav(s Series, posts []Post, postID primitive.ObjectID) *SeriesNav {
+	for i, p := range posts {
+		if p.ID != postID {
+			continue
+		}
+		nav := &SeriesNav{ID: s.ID, Title: s.Title, Position: i + 1, Total: len(posts)}
+		if i > 0 {
+			nav.Prev = &PostLink{ID: posts[i-1].ID, Title: posts[i-1].Title, Slug: posts[i-1].Slug}
+		}
+		if i+1 < len(posts) {
+			nav.Next = &PostLink{ID: posts[i+1].ID, Title: posts[i+1].Title, Slug: posts[i+1].Slug}
+		}
+		return nav
+	}
+	return nil
+}
+
+// MovePostID returns ids with id at position, moving it there if it is
+// already present. Positions past either end are clamped.
+func MovePostID(ids []primitive.ObjectID, id primitive.ObjectID, position int) []primitive.ObjectID {
+	out := make([]primitive.ObjectID, 0, len(ids)+1)
+	for _, other := range ids {
+		if other != id {
+			out = append(out, other)
+		}
+	}
+	if position < 0 || position > len(out) {
+		position = len(out)
+	}
+	out = append(out, primitive.NilObjectID)
+	copy(out[position+1:], out[position:])
+	out[position] = id
+	return out
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestNewSeriesNav(t *testing.T) {
+	s := Series{ID: primitive.NewObjectID(), Title: "Gold, a study"}
+	posts := []Post{
+		{ID: primitive.NewObjectID(), Title: "Part 1", Slug: "part-1"},
+		{ID: primitive.NewObjectID(), Title: "Part 2", Slug: "part-2"},
+		{ID: primitive.NewObjectID(), Title: "Part 3", Slug: "part-3"},
+	}
+
+	first := NewSeriesNav(s, posts, posts[0].ID)
+	assert.Equal(t, 1, first.Position)
+	assert.Equal(t, 3, first.Total)
+	assert.Nil(t, first.Prev)
+	assert.Equal(t, "part-2", first.Next.Slug)
+
+	middle := NewSeriesNav(s, posts, posts[1].ID)
+	assert.Equal(t, posts[0].ID, middle.Prev.ID)
+	assert.Equal(t, posts[2].ID, middle.Next.ID)
+
+	last := NewSeriesNav(s, posts, posts[2].ID)
+	assert.Nil(t, last.Next)
+
+	assert.Nil(t, NewSeriesNav(s, posts, primitive.NewObjectID()))
+}
+
+func TestMovePostID(t *testing.T) {
+	a, b, c, d := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	ids := []primitive.ObjectID{a, b, c}
+
+	assert.Equal(t, []primitive.ObjectID{a, b, c, d}, MovePostID(ids, d, -1), "appended by default")
+	assert.Equal(t, []primitive.ObjectID{d, a, b, c}, MovePostID(ids, d, 0))
+	assert.Equal(t, []primitive.ObjectID{b, c, a}, MovePostID(ids, a, 2))
+	assert.Equal(t, []primitive.ObjectID{c, a, b}, MovePostID(ids, c, 0))
+	assert.Equal(t, []primitive.ObjectID{a, c, b}, MovePostID(ids, b, 99), "clamped to the end")
+	assert.Equal(t, []primitive.ObjectID{a, b, c}, ids, "input is left alone")
+}
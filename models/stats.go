@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	StatsHourly = "hour"
+	StatsDaily  = "day"
+)
+
+// PostStatsBucket is the views of one post in one hour or day, rolled up
+// from the live counters in Redis into the post_stats collection.
+type PostStatsBucket struct {
+	PostID      primitive.ObjectID `bson:"post_id" json:"-"`
+	Granularity string             `bson:"granularity" json:"-"` // StatsHourly or StatsDaily
+	Start       time.Time          `bson:"start" json:"start"`
+	Views       int64              `bson:"views" json:"views"`
+	Uniques     int64              `bson:"uniques" json:"uniques"`                         // distinct viewers within the bucket
+	Referrers   []ReferrerCount    `bson:"referrers,omitempty" json:"referrers,omitempty"` // daily buckets only
+	UpdatedAt   time.Time          `bson:"updated_at" json:"-"`
+}
+
+// ReferrerCount is how many views came from one referring host. "direct"
+// stands for views without a referrer.
+type ReferrerCount struct {
+	Host  string `bson:"host" json:"host"`
+	Views int64  `bson:"views" json:"views"`
+}
+
+// PostStats is the analytics report of one post.
+type PostStats struct {
+	PostID       primitive.ObjectID `json:"post_id"`
+	Interval     string             `json:"interval"`
+	From         time.Time          `json:"from"`
+	To           time.Time          `json:"to"`
+	Views        int64              `json:"views"`         // within the range
+	TotalUniques int64              `json:"total_uniques"` // all time, approximate
+	Series       []PostStatsBucket  `json:"series"`
+	Referrers    []ReferrerCount    `json:"referrers"` // within the range, most views first
+}
+
+// TopPost is one entry of the site-wide most viewed posts.
+type TopPost struct {
+	PostID   primitive.ObjectID `bson:"_id" json:"post_id"`
+	Title    string             `bson:"title" json:"title"`
+	Slug     string             `bson:"slug,omitempty" json:"slug,omitempty"`
+	AuthorID primitive.ObjectID `bson:"author_id" json:"author_id"`
+	Views    int64              `bson:"views" json:"views"`
+	Uniques  int64              `bson:"uniques" json:"uniques"` // sum of daily uniques
+}
@@ -0,0 +1,254 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type TradeDirection string
+
+const (
+	TradeDirectionLong  TradeDirection = "long"
+	TradeDirectionShort TradeDirection = "short"
+)
+
+type TradeStatus string
+
+const (
+	TradeStatusPending TradeStatus = "pending" // waiting for entry
+	TradeStatusOpen    TradeStatus = "open"    // at least one fill recorded
+	TradeStatusClosed  TradeStatus = "closed"
+)
+
+type TradeOutcome string
+
+const (
+	TradeOutcomeTargetHit TradeOutcome = "target_hit"
+	TradeOutcomeStopped   TradeOutcome = "stopped"
+	TradeOutcomeExpired   TradeOutcome = "expired"
+	TradeOutcomeManual    TradeOutcome = "manual"
+)
+
+// Trade event types. Closing events reuse the TradeOutcome values.
+const (
+	TradeEventFilled = "filled"
+)
+
+// Sources of a trade event.
+const (
+	TradeSourceAuthor     = "author"
+	TradeSourceMarketData = "market_data"
+)
+
+// Timeframes accepted on a trade setup.
+var TradeTimeframes = []string{"scalp", "intraday", "swing", "position"}
+
+type TradeFill struct {
+	Price    float64   `bson:"price" json:"price"`
+	Quantity float64   `bson:"quantity" json:"quantity"`
+	FilledAt time.Time `bson:"filled_at" json:"filled_at"`
+}
+
+// TradeEvent records a lifecycle change on a trade, whether entered by the
+// author or resolved automatically from market data.
+type TradeEvent struct {
+	Type   string    `bson:"type" json:"type"` // filled, target_hit, stopped, expired, manual
+	Price  *float64  `bson:"price,omitempty" json:"price,omitempty"`
+	Source string    `bson:"source" json:"source"` // author, market_data
+	At     time.Time `bson:"at" json:"at"`
+}
+
+// Trade holds the structured setup and lifecycle of a PostTypeTrade post.
+type Trade struct {
+	Instrument string         `bson:"instrument" json:"instrument"` // ticker, e.g. AAPL or BTCUSD
+	Direction  TradeDirection `bson:"direction" json:"direction"`   // long, short
+	Entry      float64        `bson:"entry" json:"entry"`
+	Stop       float64        `bson:"stop" json:"stop"`
+	Targets    []float64      `bson:"targets" json:"targets"`
+	Timeframe  string         `bson:"timeframe" json:"timeframe"`
+	Expiry     *time.Time     `bson:"expiry,omitempty" json:"expiry,omitempty"`
+
+	Status    TradeStatus  `bson:"status" json:"status"` // pending, open, closed
+	Fills     []TradeFill  `bson:"fills,omitempty" json:"fills,omitempty"`
+	Outcome   TradeOutcome `bson:"outcome,omitempty" json:"outcome,omitempty"`
+	ExitPrice *float64     `bson:"exit_price,omitempty" json:"exit_price,omitempty"`
+	ClosedAt  *time.Time   `bson:"closed_at,omitempty" json:"closed_at,omitempty"`
+	Events    []TradeEvent `bson:"events,omitempty" json:"events,omitempty"`
+
+	// CheckedThrough is the time of the last price bar evaluated by the resolver.
+	CheckedThrough *time.Time `bson:"checked_through,omitempty" json:"checked_through,omitempty"`
+}
+
+// Normalize trims and upper-cases the instrument and lower-cases the enums.
+func (t *Trade) Normalize() {
+	t.Instrument = strings.ToUpper(strings.TrimSpace(t.Instrument))
+	t.Direction = TradeDirection(strings.ToLower(strings.TrimSpace(string(t.Direction))))
+	t.Timeframe = strings.ToLower(strings.TrimSpace(t.Timeframe))
+}
+
+// Validate checks that the trade setup is complete and internally consistent.
+// Lifecycle fields (status, fills, outcome) are not inspected.
+func (t *Trade) Validate() error {
+	if t.Instrument == "" {
+		return errors.New("trade instrument is required")
+	}
+	if t.Direction != TradeDirectionLong && t.Direction != TradeDirectionShort {
+		return errors.New("trade direction must be long or short")
+	}
+	if t.Entry <= 0 || t.Stop <= 0 {
+		return errors.New("trade entry and stop must be positive")
+	}
+	if len(t.Targets) == 0 {
+		return errors.New("trade needs at least one target")
+	}
+
+	for _, target := range t.Targets {
+		if target <= 0 {
+			return errors.New("trade targets must be positive")
+		}
+	}
+
+	switch t.Direction {
+	case TradeDirectionLong:
+		if t.Stop >= t.Entry {
+			return errors.New("stop must be below entry for a long trade")
+		}
+		for _, target := range t.Targets {
+			if target <= t.Entry {
+				return errors.New("targets must be above entry for a long trade")
+			}
+		}
+	case TradeDirectionShort:
+		if t.Stop <= t.Entry {
+			return errors.New("stop must be above entry for a short trade")
+		}
+		for _, target := range t.Targets {
+			if target >= t.Entry {
+				return errors.New("targets must be below entry for a short trade")
+			}
+		}
+	}
+
+	if !validTimeframe(t.Timeframe) {
+		return fmt.Errorf("trade timeframe must be one of %s", strings.Join(TradeTimeframes, ", "))
+	}
+
+	if t.Expiry != nil && !t.Expiry.After(time.Now()) {
+		return errors.New("trade expiry must be in the future")
+	}
+
+	return nil
+}
+
+// Reset clears lifecycle fields so a freshly submitted setup starts pending.
+func (t *Trade) Reset() {
+	t.Status = TradeStatusPending
+	t.Fills = nil
+	t.Outcome = ""
+	t.ExitPrice = nil
+	t.ClosedAt = nil
+	t.Events = nil
+	t.CheckedThrough = nil
+}
+
+var ErrTradeNotFilled = errors.New("a trade that never filled can only close as expired or manual, without an exit price")
+
+// Filled reports whether any quantity of the trade was filled.
+func (t *Trade) Filled() bool {
+	for _, f := range t.Fills {
+		if f.Quantity > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckClose tells whether the trade may close with outcome. A trade that
+// never filled holds no position, so it cannot hit its target or stop and
+// has no exit price.
+func (t *Trade) CheckClose(outcome TradeOutcome, exitPrice *float64) error {
+	if t.Filled() {
+		return nil
+	}
+	if outcome == TradeOutcomeTargetHit || outcome == TradeOutcomeStopped || exitPrice != nil {
+		return ErrTradeNotFilled
+	}
+	return nil
+}
+
+var (
+	ErrTradeTimeInFuture    = errors.New("fill and close times cannot be in the future")
+	ErrTradeTimeBeforePost  = errors.New("fill and close times cannot be before the post was published")
+	ErrTradeCloseBeforeFill = errors.New("a trade cannot close before its last fill")
+)
+
+// CheckTradeTime tells whether a fill or close of the post's trade may be
+// dated at. Performance is computed from these times, so they cannot lie in
+// the future or before the trade was posted: the later of creation and
+// publication.
+func (p *Post) CheckTradeTime(at, now time.Time) error {
+	if at.After(now) {
+		return ErrTradeTimeInFuture
+	}
+	posted := p.CreatedAt
+	if p.PublishedAt != nil && p.PublishedAt.After(posted) {
+		posted = *p.PublishedAt
+	}
+	if at.Before(posted) {
+		return ErrTradeTimeBeforePost
+	}
+	return nil
+}
+
+// CheckCloseTime tells whether the trade may close at, which is no earlier
+// than its last fill.
+func (t *Trade) CheckCloseTime(at time.Time) error {
+	for _, f := range t.Fills {
+		if at.Before(f.FilledAt) {
+			return ErrTradeCloseBeforeFill
+		}
+	}
+	return nil
+}
+
+// Close marks the trade closed and records the matching event.
+func (t *Trade) Close(outcome TradeOutcome, exitPrice *float64, at time.Time, source string) {
+	t.Status = TradeStatusClosed
+	t.Outcome = outcome
+	t.ExitPrice = exitPrice
+	t.ClosedAt = &at
+	t.Events = append(t.Events, TradeEvent{Type: string(outcome), Price: exitPrice, Source: source, At: at})
+}
+
+// AverageEntry returns the quantity-weighted fill price, falling back to the
+// planned entry when no fills have been recorded.
+func (t *Trade) AverageEntry() float64 {
+	var qty, notional float64
+	for _, f := range t.Fills {
+		qty += f.Quantity
+		notional += f.Price * f.Quantity
+	}
+	if qty == 0 {
+		return t.Entry
+	}
+	return notional / qty
+}
+
+func ValidTradeOutcome(o TradeOutcome) bool {
+	switch o {
+	case TradeOutcomeTargetHit, TradeOutcomeStopped, TradeOutcomeExpired, TradeOutcomeManual:
+		return true
+	}
+	return false
+}
+
+func validTimeframe(tf string) bool {
+	for _, allowed := range TradeTimeframes {
+		if tf == allowed {
+			return true
+		}
+	}
+	return false
+}
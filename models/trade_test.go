@@ -0,0 +1,93 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func validLongTrade() Trade {
+	return Trade{
+		Instrument: " aapl ",
+		Direction:  "Long",
+		Entry:      100,
+		Stop:       95,
+		Targets:    []float64{110, 120},
+		Timeframe:  "swing",
+	}
+}
+
+func TestTradeValidate_Long(t *testing.T) {
+	trade := validLongTrade()
+	trade.Normalize()
+
+	assert.NoError(t, trade.Validate())
+	assert.Equal(t, "AAPL", trade.Instrument)
+	assert.Equal(t, TradeDirectionLong, trade.Direction)
+}
+
+func TestTradeValidate_RejectsInconsistentLevels(t *testing.T) {
+	trade := validLongTrade()
+	trade.Normalize()
+	trade.Stop = 105
+	assert.Error(t, trade.Validate())
+
+	trade = validLongTrade()
+	trade.Normalize()
+	trade.Direction = TradeDirectionShort
+	assert.Error(t, trade.Validate())
+
+	trade.Stop = 105
+	trade.Targets = []float64{90}
+	assert.NoError(t, trade.Validate())
+}
+
+func TestTradeValidate_RejectsPastExpiry(t *testing.T) {
+	trade := validLongTrade()
+	trade.Normalize()
+	past := time.Now().Add(-time.Hour)
+	trade.Expiry = &past
+
+	assert.Error(t, trade.Validate())
+}
+
+func TestTradeAverageEntry(t *testing.T) {
+	trade := validLongTrade()
+	assert.Equal(t, 100.0, trade.AverageEntry())
+
+	trade.Fills = []TradeFill{{Price: 100, Quantity: 1}, {Price: 103, Quantity: 2}}
+	assert.Equal(t, 102.0, trade.AverageEntry())
+}
+
+func TestTradeCheckClose(t *testing.T) {
+	trade := validLongTrade()
+	exit := 110.0
+
+	assert.ErrorIs(t, trade.CheckClose(TradeOutcomeTargetHit, nil), ErrTradeNotFilled)
+	assert.ErrorIs(t, trade.CheckClose(TradeOutcomeStopped, nil), ErrTradeNotFilled)
+	assert.ErrorIs(t, trade.CheckClose(TradeOutcomeManual, &exit), ErrTradeNotFilled, "no position to exit")
+	assert.NoError(t, trade.CheckClose(TradeOutcomeExpired, nil))
+	assert.NoError(t, trade.CheckClose(TradeOutcomeManual, nil))
+
+	trade.Fills = []TradeFill{{Price: 100, Quantity: 1}}
+	assert.NoError(t, trade.CheckClose(TradeOutcomeTargetHit, &exit))
+}
+
+func TestTradeTimes(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	published := now.Add(-24 * time.Hour)
+	post := Post{CreatedAt: now.Add(-48 * time.Hour), PublishedAt: &published}
+
+	assert.NoError(t, post.CheckTradeTime(now.Add(-time.Hour), now))
+	assert.ErrorIs(t, post.CheckTradeTime(now.Add(time.Minute), now), ErrTradeTimeInFuture)
+	assert.ErrorIs(t, post.CheckTradeTime(now.Add(-30*time.Hour), now), ErrTradeTimeBeforePost, "before publication")
+
+	draft := Post{CreatedAt: now.Add(-48 * time.Hour)}
+	assert.NoError(t, draft.CheckTradeTime(now.Add(-30*time.Hour), now))
+	assert.ErrorIs(t, draft.CheckTradeTime(now.Add(-50*time.Hour), now), ErrTradeTimeBeforePost, "before creation")
+
+	trade := Trade{Fills: []TradeFill{{Price: 100, Quantity: 1, FilledAt: now.Add(-2 * time.Hour)}}}
+	assert.NoError(t, trade.CheckCloseTime(now.Add(-time.Hour)))
+	assert.ErrorIs(t, trade.CheckCloseTime(now.Add(-3*time.Hour)), ErrTradeCloseBeforeFill)
+}
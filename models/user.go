@@ -9,9 +9,26 @@ import (
 type User struct {
     ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
     Name  string             `bson:"name" json:"name"`
+    Handle    string             `bson:"handle,omitempty" json:"handle,omitempty"` // used in post permalinks
     Email     string             `bson:"email" json:"email"`
     Password  string             `bson:"password" json:"password"`
     Role      string             `bson:"role" json:"role"`
     IsActive  bool               `bson:"is_active" json:"is_active"`
     CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+    Version   int64              `bson:"version,omitempty" json:"version"` // bumped by every edit, served as the ETag
+}
+
+// Roles a user can have. Readers sign up as users; premium readers see
+// premium posts, authors write, moderators review reports and admins run the
+// site.
+var Roles = []string{"user", "premium", "author", "moderator", "admin"}
+
+// ValidRole tells whether role is one of Roles.
+func ValidRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidRole(t *testing.T) {
+	assert.True(t, ValidRole("author"))
+	assert.True(t, ValidRole("moderator"))
+	assert.False(t, ValidRole("superuser"))
+	assert.False(t, ValidRole(""))
+	assert.False(t, ValidRole("Admin"), "roles are stored lowercase")
+}
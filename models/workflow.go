@@ -0,0 +1,138 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Post statuses of the editorial workflow:
+//
+//	draft -> in_review -> approved -> scheduled/published -> archived
+const (
+	PostStatusDraft     = "draft"
+	PostStatusInReview  = "in_review"
+	PostStatusApproved  = "approved"
+	PostStatusScheduled = "scheduled"
+	PostStatusPublished = "published"
+	PostStatusArchived  = "archived"
+)
+
+// Workflow actions exposed as transition endpoints.
+const (
+	ActionSubmit       = "submit"
+	ActionApprove      = "approve"
+	ActionReject       = "reject"
+	ActionPublish      = "publish"
+	ActionForcePublish = "force_publish" // publish now, ignoring scheduled_at
+	ActionUnpublish    = "unpublish"
+	ActionArchive      = "archive"
+
+	// Recorded in history by the schedule endpoint and the expiry job.
+	ActionSchedule   = "schedule"
+	ActionUnschedule = "unschedule"
+	ActionExpire     = "expire"
+)
+
+var (
+	ErrInvalidTransition   = errors.New("transition not allowed from the current status")
+	ErrTransitionForbidden = errors.New("role may not perform this transition")
+	ErrUnknownAction       = errors.New("unknown workflow action")
+)
+
+// StatusChange is one entry in a post's workflow audit trail.
+type StatusChange struct {
+	Action string             `bson:"action" json:"action"`
+	From   string             `bson:"from" json:"from"`
+	To     string             `bson:"to" json:"to"`
+	By     primitive.ObjectID `bson:"by" json:"by"`
+	Role   string             `bson:"role" json:"role"`
+	Reason string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	At     time.Time          `bson:"at" json:"at"`
+}
+
+// ContentLocked tells whether role may no longer change the title and content
+// of a post in status, because the post has passed review. Admins, who
+// approve posts, may still correct them; published and scheduled posts can
+// also be unpublished and sent through review again.
+func ContentLocked(status, role string) bool {
+	switch status {
+	case PostStatusApproved, PostStatusScheduled, PostStatusPublished:
+		return role != "admin"
+	}
+	return false
+}
+
+type transition struct {
+	from []string
+	to   string
+	// roles allowed to act on any post; "owner" means the post's author.
+	roles []string
+}
+
+var transitions = map[string]transition{
+	ActionSubmit: {
+		from:  []string{PostStatusDraft},
+		to:    PostStatusInReview,
+		roles: []string{"owner", "admin"},
+	},
+	ActionApprove: {
+		from:  []string{PostStatusInReview},
+		to:    PostStatusApproved,
+		roles: []string{"admin"},
+	},
+	ActionReject: {
+		from:  []string{PostStatusInReview},
+		to:    PostStatusDraft,
+		roles: []string{"admin"},
+	},
+	ActionPublish: {
+		from:  []string{PostStatusApproved},
+		to:    PostStatusPublished,
+		roles: []string{"owner", "admin"},
+	},
+	ActionForcePublish: {
+		from:  []string{PostStatusApproved, PostStatusScheduled},
+		to:    PostStatusPublished,
+		roles: []string{"owner", "admin"},
+	},
+	ActionUnpublish: {
+		from:  []string{PostStatusPublished, PostStatusScheduled},
+		to:    PostStatusDraft,
+		roles: []string{"owner", "admin"},
+	},
+	ActionArchive: {
+		from:  []string{PostStatusDraft, PostStatusInReview, PostStatusApproved, PostStatusScheduled, PostStatusPublished},
+		to:    PostStatusArchived,
+		roles: []string{"owner", "admin"},
+	},
+}
+
+// NextStatus returns the status a post moves to when role performs action on
+// it. isOwner tells whether the caller authored the post. Publishing yields
+// PostStatusPublished; callers turn that into scheduled for future dates.
+func NextStatus(action, from, role string, isOwner bool) (string, error) {
+	t, ok := transitions[action]
+	if !ok {
+		return "", ErrUnknownAction
+	}
+
+	allowed := false
+	for _, r := range t.roles {
+		if r == role || (r == "owner" && isOwner) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return "", ErrTransitionForbidden
+	}
+
+	for _, f := range t.from {
+		if f == from {
+			return t.to, nil
+		}
+	}
+	return "", ErrInvalidTransition
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextStatus_HappyPath(t *testing.T) {
+	to, err := NextStatus(ActionSubmit, PostStatusDraft, "author", true)
+	assert.NoError(t, err)
+	assert.Equal(t, PostStatusInReview, to)
+
+	to, err = NextStatus(ActionApprove, to, "admin", false)
+	assert.NoError(t, err)
+	assert.Equal(t, PostStatusApproved, to)
+
+	to, err = NextStatus(ActionPublish, to, "author", true)
+	assert.NoError(t, err)
+	assert.Equal(t, PostStatusPublished, to)
+
+	to, err = NextStatus(ActionArchive, to, "author", true)
+	assert.NoError(t, err)
+	assert.Equal(t, PostStatusArchived, to)
+}
+
+func TestNextStatus_RoleChecks(t *testing.T) {
+	_, err := NextStatus(ActionApprove, PostStatusInReview, "author", true)
+	assert.ErrorIs(t, err, ErrTransitionForbidden)
+
+	_, err = NextStatus(ActionSubmit, PostStatusDraft, "author", false)
+	assert.ErrorIs(t, err, ErrTransitionForbidden)
+
+	_, err = NextStatus(ActionSubmit, PostStatusDraft, "admin", false)
+	assert.NoError(t, err)
+}
+
+func TestNextStatus_InvalidTransitions(t *testing.T) {
+	_, err := NextStatus(ActionPublish, PostStatusDraft, "admin", false)
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+
+	_, err = NextStatus(ActionArchive, PostStatusArchived, "admin", false)
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+
+	to, err := NextStatus(ActionReject, PostStatusInReview, "admin", false)
+	assert.NoError(t, err)
+	assert.Equal(t, PostStatusDraft, to)
+
+	_, err = NextStatus("delete", PostStatusDraft, "admin", false)
+	assert.ErrorIs(t, err, ErrUnknownAction)
+}
+
+func TestNextStatus_ForcePublish(t *testing.T) {
+	to, err := NextStatus(ActionForcePublish, PostStatusScheduled, "author", true)
+	assert.NoError(t, err)
+	assert.Equal(t, PostStatusPublished, to)
+
+	_, err = NextStatus(ActionPublish, PostStatusScheduled, "author", true)
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+
+	_, err = NextStatus(ActionForcePublish, PostStatusInReview, "admin", false)
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+}
+
+func TestContentLocked(t *testing.T) {
+	assert.False(t, ContentLocked(PostStatusDraft, "author"))
+	assert.False(t, ContentLocked(PostStatusInReview, "author"))
+	assert.True(t, ContentLocked(PostStatusApproved, "author"))
+	assert.True(t, ContentLocked(PostStatusScheduled, "author"))
+	assert.True(t, ContentLocked(PostStatusPublished, "author"))
+	assert.False(t, ContentLocked(PostStatusPublished, "admin"))
+}
@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"expvar"
 	"net/http"
 
 	"go-backend/controllers"
@@ -8,11 +9,22 @@ import (
 	"github.com/gorilla/mux"
 )
 
-// RegisterAdminRoutes sets up the admin endpoints for user management.
+// RegisterAdminRoutes sets up the admin endpoints for user management,
+// media housekeeping, site analytics, process metrics and the content filter.
 func RegisterAdminRoutes(router *mux.Router) {
 	router.HandleFunc("/users", controllers.ListUsers).Methods(http.MethodGet)
 	router.HandleFunc("/users/{id}", controllers.GetUser).Methods(http.MethodGet)
 	router.HandleFunc("/users", controllers.CreateUser).Methods(http.MethodPost)
 	router.HandleFunc("/users/{id}", controllers.UpdateUser).Methods(http.MethodPut)
+	router.HandleFunc("/users/{id}", controllers.PatchUser).Methods(http.MethodPatch)
 	router.HandleFunc("/users/{id}", controllers.DeleteUser).Methods(http.MethodDelete)
+
+	router.HandleFunc("/media/gc", controllers.GetMediaGCReport).Methods(http.MethodGet)
+	router.HandleFunc("/stats/top-posts", controllers.GetTopPosts).Methods(http.MethodGet)
+	router.HandleFunc("/stats/top-referrers", controllers.GetTopReferrers).Methods(http.MethodGet)
+	router.Handle("/metrics", expvar.Handler()).Methods(http.MethodGet)
+
+	router.HandleFunc("/content-filter", controllers.GetContentFilter).Methods(http.MethodGet)
+	router.HandleFunc("/content-filter", controllers.UpdateContentFilter).Methods(http.MethodPut)
+	router.HandleFunc("/content-filter/check", controllers.CheckContentFilter).Methods(http.MethodPost)
 }
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"net/http"
+
+	"go-backend/controllers"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterAuthorRoutes sets up author analytics endpoints.
+func RegisterAuthorRoutes(router *mux.Router) {
+	router.HandleFunc("/leaderboard", controllers.GetAuthorLeaderboard).Methods(http.MethodGet)
+	router.HandleFunc("/{id}/performance", controllers.GetAuthorPerformance).Methods(http.MethodGet)
+}
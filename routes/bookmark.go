@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"net/http"
+
+	"go-backend/controllers"
+	"go-backend/middleware"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterBookmarkRoutes sets up bookmarks and reading lists for any signed
+// in reader, plus the public share links of reading lists.
+func RegisterBookmarkRoutes(router *mux.Router) {
+	router.HandleFunc("/api/v1/lists/shared/{token}", controllers.GetSharedReadingList).Methods(http.MethodGet)
+
+	api := router.PathPrefix("/api/v1").Subrouter()
+	api.Use(middleware.JWTMiddleware)
+
+	api.HandleFunc("/posts/{id}/bookmark", controllers.BookmarkPost).Methods(http.MethodPut)
+	api.HandleFunc("/posts/{id}/bookmark", controllers.RemoveBookmark).Methods(http.MethodDelete)
+	api.HandleFunc("/users/me/bookmarks", controllers.ListBookmarks).Methods(http.MethodGet)
+
+	api.HandleFunc("/users/me/lists", controllers.ListReadingLists).Methods(http.MethodGet)
+	api.HandleFunc("/users/me/lists", controllers.CreateReadingList).Methods(http.MethodPost)
+	api.HandleFunc("/users/me/lists/{id}", controllers.GetReadingList).Methods(http.MethodGet)
+	api.HandleFunc("/users/me/lists/{id}", controllers.UpdateReadingList).Methods(http.MethodPut)
+	api.HandleFunc("/users/me/lists/{id}", controllers.DeleteReadingList).Methods(http.MethodDelete)
+	api.HandleFunc("/users/me/lists/{id}/posts/{postId}", controllers.AddToReadingList).Methods(http.MethodPut)
+	api.HandleFunc("/users/me/lists/{id}/posts/{postId}", controllers.RemoveFromReadingList).Methods(http.MethodDelete)
+	api.HandleFunc("/users/me/lists/{id}/share", controllers.ShareReadingList).Methods(http.MethodPost)
+	api.HandleFunc("/users/me/lists/{id}/share", controllers.UnshareReadingList).Methods(http.MethodDelete)
+}
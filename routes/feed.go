@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"net/http"
+
+	"go-backend/controllers"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterFeedRoutes sets up the public RSS, Atom and JSON Feed endpoints.
+func RegisterFeedRoutes(router *mux.Router) {
+	const format = "posts.{format:rss|atom|json}"
+
+	router.HandleFunc("/feeds/"+format, controllers.PostFeed).Methods(http.MethodGet, http.MethodHead)
+	router.HandleFunc("/feeds/authors/{id}/"+format, controllers.PostFeed).Methods(http.MethodGet, http.MethodHead)
+	router.HandleFunc("/feeds/tags/{tag}/"+format, controllers.PostFeed).Methods(http.MethodGet, http.MethodHead)
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"net/http"
+
+	"go-backend/controllers"
+	"go-backend/middleware"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterFollowRoutes sets up following authors and the home feed for any
+// signed in user.
+func RegisterFollowRoutes(router *mux.Router) {
+	api := router.PathPrefix("/api/v1").Subrouter()
+	api.Use(middleware.JWTMiddleware)
+
+	api.HandleFunc("/users/{id}/follow", controllers.FollowUser).Methods(http.MethodPut)
+	api.HandleFunc("/users/{id}/follow", controllers.UnfollowUser).Methods(http.MethodDelete)
+	api.HandleFunc("/users/{id}/followers", controllers.ListFollowers).Methods(http.MethodGet)
+	api.HandleFunc("/users/{id}/following", controllers.ListFollowing).Methods(http.MethodGet)
+	api.HandleFunc("/feed", controllers.GetHomeFeed).Methods(http.MethodGet)
+}
@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"net/http"
+
+	"go-backend/controllers"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterMediaRoutes sets up media upload and management. Size limits are
+// applied per role by the upload handler.
+func RegisterMediaRoutes(router *mux.Router) {
+	router.HandleFunc("", controllers.UploadMedia).Methods(http.MethodPost)
+
+	// Resumable uploads (tus 1.0)
+	router.HandleFunc("/uploads", controllers.CreateTusUpload).Methods(http.MethodPost)
+	router.HandleFunc("/uploads/{id}", controllers.GetTusUploadOffset).Methods(http.MethodHead)
+	router.HandleFunc("/uploads/{id}", controllers.PatchTusUpload).Methods(http.MethodPatch)
+	router.HandleFunc("/uploads/{id}", controllers.TerminateTusUpload).Methods(http.MethodDelete)
+
+	router.HandleFunc("/{id}", controllers.GetMedia).Methods(http.MethodGet)
+	router.HandleFunc("/{id}", controllers.DeleteMedia).Methods(http.MethodDelete)
+}
+
+// RegisterMediaFileRoutes serves stored media bytes and answers tus
+// discovery, which browsers send as a preflight without a token.
+func RegisterMediaFileRoutes(router *mux.Router) {
+	router.HandleFunc("/media/{key:.+}", controllers.ServeMediaFile).Methods(http.MethodGet, http.MethodHead)
+	router.HandleFunc("/api/v1/media/uploads", controllers.TusOptions).Methods(http.MethodOptions)
+}
@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"net/http"
+
+	"go-backend/controllers"
+	"go-backend/middleware"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterModerationRoutes sets up reporting posts, open to any signed in
+// user, and the moderation queue for moderators and admins.
+func RegisterModerationRoutes(router *mux.Router) {
+	api := router.PathPrefix("/api/v1").Subrouter()
+	api.Use(middleware.JWTMiddleware)
+	api.HandleFunc("/posts/{id}/report", controllers.ReportPost).Methods(http.MethodPost)
+
+	mod := router.PathPrefix("/api/v1/moderation").Subrouter()
+	mod.Use(middleware.JWTMiddleware)
+	mod.Use(middleware.RBAC("moderator", "admin"))
+	mod.HandleFunc("/queue", controllers.GetModerationQueue).Methods(http.MethodGet)
+	mod.HandleFunc("/cases/{id}", controllers.GetModerationCase).Methods(http.MethodGet)
+	mod.HandleFunc("/cases/{id}/claim", controllers.ClaimModerationCase).Methods(http.MethodPost)
+	mod.HandleFunc("/cases/{id}/hide", controllers.HideModeratedPost).Methods(http.MethodPost)
+	mod.HandleFunc("/cases/{id}/restore", controllers.RestoreModeratedPost).Methods(http.MethodPost)
+	mod.HandleFunc("/cases/{id}/resolve", controllers.ResolveModerationCase).Methods(http.MethodPost)
+}
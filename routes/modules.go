@@ -19,6 +19,10 @@ func RegisterProtectedRoutes(router *mux.Router) {
 	// Example RBAC-based route
 	api.Handle("/users/profile", middleware.RBAC("admin", "premium")(http.HandlerFunc(controllers.UserProfile))).Methods(http.MethodGet)
 
+	// The caller's own account
+	api.HandleFunc("/users/me", controllers.GetMe).Methods(http.MethodGet)
+	api.HandleFunc("/users/me", controllers.PatchMe).Methods(http.MethodPatch)
+
 	// Example generic protected route
 	api.Handle("/protected", http.HandlerFunc(controllers.ProtectedRoute)).Methods(http.MethodGet)
 }
@@ -9,22 +9,52 @@ import (
 	"github.com/gorilla/mux"
 )
 
+// RegisterPublicPostRoutes sets up the reads of single published posts, which
+// readers reach without a token. Visibility is checked per post.
+func RegisterPublicPostRoutes(router *mux.Router) {
+	router.HandleFunc("/api/v1/posts/{id:[0-9a-fA-F]{24}}", controllers.GetPost).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/posts/by-slug/{slug}", controllers.GetPostBySlug).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/posts/@{handle}/{slug}", controllers.GetPostByPermalink).Methods(http.MethodGet)
+}
+
+// RegisterSharedPostRoutes sets up the routes every collaborator needs,
+// whatever their role: viewers can be any signed in user, not only authors.
+// The handlers check access per post.
+func RegisterSharedPostRoutes(router *mux.Router) {
+	router.Handle("/api/v1/posts/my", middleware.JWTMiddleware(http.HandlerFunc(controllers.ListMyPosts))).Methods(http.MethodGet)
+	router.Handle("/api/v1/posts/{id}/collaborators", middleware.JWTMiddleware(http.HandlerFunc(controllers.ListCollaborators))).Methods(http.MethodGet)
+	router.Handle("/api/v1/posts/{id}/collaborators/{userId}", middleware.JWTMiddleware(http.HandlerFunc(controllers.RemoveCollaborator))).Methods(http.MethodDelete)
+}
+
 func RegisterPostRoutes(router *mux.Router) {
 
 	// Public routes
 	router.HandleFunc("", controllers.ListPosts).Methods(http.MethodGet)
 	
-	router.HandleFunc("/my", controllers.ListMyPosts).Methods(http.MethodGet)
-
-	router.HandleFunc("/{id}", controllers.GetPost).Methods(http.MethodGet)
 
 	// Protected (author)
 	router.Use(middleware.JWTMiddleware)
 	router.HandleFunc("", controllers.CreatePost).Methods(http.MethodPost)
 	router.HandleFunc("/{id}", controllers.UpdatePost).Methods(http.MethodPut)
+	router.HandleFunc("/{id}", controllers.PatchPost).Methods(http.MethodPatch)
 	router.HandleFunc("/{id}", controllers.DeletePost).Methods(http.MethodDelete)
 
-	// TODO: Add utility routes
-	// router.HandleFunc("/{id}/publish", controllers.ForcePublishPost).Methods(http.MethodPost)
-	// router.HandleFunc("/upload", controllers.UploadMedia).Methods(http.MethodPost)
+	// Trade lifecycle (author)
+	router.HandleFunc("/{id}/trade/fills", controllers.RecordTradeFill).Methods(http.MethodPost)
+	router.HandleFunc("/{id}/trade/close", controllers.CloseTrade).Methods(http.MethodPost)
+
+	// Editorial workflow (author, admin)
+	router.HandleFunc("/{id}/submit", controllers.SubmitPost).Methods(http.MethodPost)
+	router.HandleFunc("/{id}/approve", controllers.ApprovePost).Methods(http.MethodPost)
+	router.HandleFunc("/{id}/reject", controllers.RejectPost).Methods(http.MethodPost)
+	router.HandleFunc("/{id}/publish", controllers.PublishPost).Methods(http.MethodPost)
+	router.HandleFunc("/{id}/unpublish", controllers.UnpublishPost).Methods(http.MethodPost)
+	router.HandleFunc("/{id}/archive", controllers.ArchivePost).Methods(http.MethodPost)
+	router.HandleFunc("/{id}/schedule", controllers.SchedulePost).Methods(http.MethodPatch)
+
+	// Collaborators (owner, admin)
+	router.HandleFunc("/{id}/collaborators/{userId}", controllers.SetCollaborator).Methods(http.MethodPut)
+
+	// Analytics (author, admin)
+	router.HandleFunc("/{id}/stats", controllers.GetPostStats).Methods(http.MethodGet)
 }
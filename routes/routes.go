@@ -18,6 +18,14 @@ func InitRoutes() *mux.Router {
 	RegisterHealthRoutes(router)
 	RegisterSwaggerRoutes(router)
 	RegisterAuthRoutes(router)
+	RegisterFeedRoutes(router)
+	RegisterMediaFileRoutes(router)
+	RegisterBookmarkRoutes(router)
+	RegisterFollowRoutes(router)
+	RegisterModerationRoutes(router)
+	RegisterSeriesRoutes(router)
+	RegisterPublicPostRoutes(router)
+	RegisterSharedPostRoutes(router)
 	adminRouter := router.PathPrefix("/api/v1/admin").Subrouter()
 	adminRouter.Use(middleware.JWTMiddleware)
 	adminRouter.Use(middleware.RBAC("admin"))
@@ -25,9 +33,18 @@ func InitRoutes() *mux.Router {
 
 	postRouter := router.PathPrefix("/api/v1/posts").Subrouter()
 	postRouter.Use(middleware.JWTMiddleware)
-	postRouter.Use(middleware.RBAC("author"))
+	postRouter.Use(middleware.RBAC("author", "admin"))
 	RegisterPostRoutes(postRouter)
 
+	authorRouter := router.PathPrefix("/api/v1/authors").Subrouter()
+	authorRouter.Use(middleware.JWTMiddleware)
+	authorRouter.Use(middleware.RBAC("premium", "admin"))
+	RegisterAuthorRoutes(authorRouter)
+
+	mediaRouter := router.PathPrefix("/api/v1/media").Subrouter()
+	mediaRouter.Use(middleware.JWTMiddleware)
+	RegisterMediaRoutes(mediaRouter)
+
 	RegisterProtectedRoutes(router)
 
 	return router
@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"net/http"
+
+	"go-backend/controllers"
+	"go-backend/middleware"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterSeriesRoutes sets up series and collections, readable by anyone and
+// kept by authors and admins.
+func RegisterSeriesRoutes(router *mux.Router) {
+	router.HandleFunc("/api/v1/series", controllers.ListSeries).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/series/{id}", controllers.GetSeries).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/collections", controllers.ListCollections).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/collections/{id}", controllers.GetCollection).Methods(http.MethodGet)
+
+	api := router.PathPrefix("/api/v1").Subrouter()
+	api.Use(middleware.JWTMiddleware)
+	api.Use(middleware.RBAC("author", "admin"))
+
+	api.HandleFunc("/series", controllers.CreateSeries).Methods(http.MethodPost)
+	api.HandleFunc("/series/{id}", controllers.UpdateSeries).Methods(http.MethodPut)
+	api.HandleFunc("/series/{id}", controllers.DeleteSeries).Methods(http.MethodDelete)
+	api.HandleFunc("/series/{id}/posts/{postId}", controllers.AddToSeries).Methods(http.MethodPut)
+	api.HandleFunc("/series/{id}/posts/{postId}", controllers.RemoveFromSeries).Methods(http.MethodDelete)
+
+	api.HandleFunc("/collections", controllers.CreateCollection).Methods(http.MethodPost)
+	api.HandleFunc("/collections/{id}", controllers.UpdateCollection).Methods(http.MethodPut)
+	api.HandleFunc("/collections/{id}", controllers.DeleteCollection).Methods(http.MethodDelete)
+	api.HandleFunc("/collections/{id}/posts/{postId}", controllers.AddToCollection).Methods(http.MethodPut)
+	api.HandleFunc("/collections/{id}/posts/{postId}", controllers.RemoveFromCollection).Methods(http.MethodDelete)
+}
@@ -0,0 +1,491 @@
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-backend/config"
+	"go-backend/models"
+
+	"github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Views are counted in Redis as they happen: a counter and a HyperLogLog of
+// viewers per post and hour and per post and day, plus referrer hosts per
+// day. A rollup job copies the buckets into post_stats, where reports read
+// them. Rollups overwrite rather than add, so running one twice is harmless.
+
+const (
+	viewBucketTTL      = 49 * time.Hour // live buckets outlive their final rollup
+	viewsDirtyKey      = "views:dirty"  // buckets changed since their last rollup
+	viewRollupLockKey  = "lock:view-rollup"
+	viewRollupInterval = 5 * time.Minute
+	viewRecordTimeout  = 2 * time.Second
+)
+
+var botAgents = []string{"bot", "crawl", "spider", "slurp", "preview", "headless", "monitor"}
+
+// PageView is one read of a post.
+type PageView struct {
+	PostID   primitive.ObjectID
+	Viewer   string // stable per reader; hashed for anonymous readers
+	Referrer string // referring host, "direct" or "internal"
+	At       time.Time
+}
+
+// NewPageView describes a read of post by r. ok is false for reads that
+// should not count: crawlers and authors looking at their own post.
+func NewPageView(r *http.Request, post models.Post, viewerID primitive.ObjectID, now time.Time) (PageView, bool) {
+	agent := strings.ToLower(r.UserAgent())
+	if agent == "" {
+		return PageView{}, false
+	}
+	for _, bot := range botAgents {
+		if strings.Contains(agent, bot) {
+			return PageView{}, false
+		}
+	}
+	if !viewerID.IsZero() && viewerID == post.AuthorID {
+		return PageView{}, false
+	}
+
+	viewer := "u:" + viewerID.Hex()
+	if viewerID.IsZero() {
+		sum := sha256.Sum256([]byte(ClientIP(r) + "|" + agent))
+		viewer = "a:" + hex.EncodeToString(sum[:16])
+	}
+	return PageView{
+		PostID:   post.ID,
+		Viewer:   viewer,
+		Referrer: ReferrerHost(r.Referer()),
+		At:       now,
+	}, true
+}
+
+// ClientIP returns the address of the client. Forwarding headers are only
+// believed when the request comes from one of config.TrustedProxies, since
+// anyone else can set them.
+func ClientIP(r *http.Request) string {
+	return clientIP(r, config.TrustedProxies())
+}
+
+func clientIP(r *http.Request, trusted []*net.IPNet) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !isTrustedProxy(host, trusted) {
+		return host
+	}
+
+	// Each proxy appends the address it got the request from, so the client
+	// is the last hop that is not one of ours.
+	if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+		hops := strings.Split(fwd, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if hop != "" && (i == 0 || !isTrustedProxy(hop, trusted)) {
+				return hop
+			}
+		}
+	}
+	if ip := r.Header.Get("X-Real-IP"); ip != "" {
+		return ip
+	}
+	return host
+}
+
+func isTrustedProxy(addr string, trusted []*net.IPNet) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, n := range trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReferrerHost reduces a Referer header to its host. Links from our own
+// pages count as "internal" and requests without one as "direct".
+func ReferrerHost(referer string) string {
+	u, err := url.Parse(referer)
+	if referer == "" || err != nil || u.Hostname() == "" {
+		return "direct"
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if own, err := url.Parse(config.PublicBaseURL()); err == nil && strings.TrimPrefix(strings.ToLower(own.Hostname()), "www.") == host {
+		return "internal"
+	}
+	return host
+}
+
+// viewBucket is one post's views within one hour or day (UTC).
+type viewBucket struct {
+	Granularity string
+	PostID      primitive.ObjectID
+	Start       time.Time
+}
+
+func viewBucketLayout(granularity string) string {
+	if granularity == models.StatsDaily {
+		return "20060102"
+	}
+	return "2006010215"
+}
+
+// bucketStart rounds t down to the start of its hour or day in UTC.
+func bucketStart(granularity string, t time.Time) time.Time {
+	t = t.UTC()
+	if granularity == models.StatsDaily {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return t.Truncate(time.Hour)
+}
+
+func viewBucketsAt(postID primitive.ObjectID, at time.Time) []viewBucket {
+	return []viewBucket{
+		{Granularity: models.StatsHourly, PostID: postID, Start: bucketStart(models.StatsHourly, at)},
+		{Granularity: models.StatsDaily, PostID: postID, Start: bucketStart(models.StatsDaily, at)},
+	}
+}
+
+func (b viewBucket) member() string {
+	return b.Granularity + ":" + b.PostID.Hex() + ":" + b.Start.Format(viewBucketLayout(b.Granularity))
+}
+
+func parseViewBucket(member string) (viewBucket, error) {
+	parts := strings.Split(member, ":")
+	if len(parts) != 3 || (parts[0] != models.StatsHourly && parts[0] != models.StatsDaily) {
+		return viewBucket{}, fmt.Errorf("invalid view bucket %q", member)
+	}
+	postID, err := primitive.ObjectIDFromHex(parts[1])
+	if err != nil {
+		return viewBucket{}, fmt.Errorf("invalid view bucket %q", member)
+	}
+	start, err := time.Parse(viewBucketLayout(parts[0]), parts[2])
+	if err != nil {
+		return viewBucket{}, fmt.Errorf("invalid view bucket %q", member)
+	}
+	return viewBucket{Granularity: parts[0], PostID: postID, Start: start}, nil
+}
+
+func (b viewBucket) end() time.Time {
+	if b.Granularity == models.StatsDaily {
+		return b.Start.AddDate(0, 0, 1)
+	}
+	return b.Start.Add(time.Hour)
+}
+
+func (b viewBucket) countKey() string     { return "views:n:" + b.member() }
+func (b viewBucket) uniquesKey() string   { return "views:u:" + b.member() }
+func (b viewBucket) referrersKey() string { return "views:r:" + b.member() }
+
+func totalUniquesKey(postID primitive.ObjectID) string { return "views:u:all:" + postID.Hex() }
+
+// ViewCounter records views and maintains the post_stats rollup.
+type ViewCounter struct {
+	Client *redis.Client
+	Stats  *mongo.Collection
+	Lock   Locker
+	Now    func() time.Time
+}
+
+// Views is the process wide view counter, configured in main. Recording is
+// a no-op while it is nil.
+var Views *ViewCounter
+
+func NewViewCounter(client *redis.Client, db *mongo.Database) *ViewCounter {
+	return &ViewCounter{
+		Client: client,
+		Stats:  db.Collection("post_stats"),
+		Lock:   &RedisLock{Client: client},
+		Now:    time.Now,
+	}
+}
+
+// Record counts v in one round trip.
+func (c *ViewCounter) Record(ctx context.Context, v PageView) error {
+	_, err := c.Client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for _, b := range viewBucketsAt(v.PostID, v.At) {
+			p.Incr(ctx, b.countKey())
+			p.Expire(ctx, b.countKey(), viewBucketTTL)
+			p.PFAdd(ctx, b.uniquesKey(), v.Viewer)
+			p.Expire(ctx, b.uniquesKey(), viewBucketTTL)
+			if b.Granularity == models.StatsDaily {
+				p.HIncrBy(ctx, b.referrersKey(), v.Referrer, 1)
+				p.Expire(ctx, b.referrersKey(), viewBucketTTL)
+			}
+			p.SAdd(ctx, viewsDirtyKey, b.member())
+		}
+		p.PFAdd(ctx, totalUniquesKey(v.PostID), v.Viewer)
+		return nil
+	})
+	return err
+}
+
+// RecordRequest counts a read of post by r in the background, so a slow or
+// unavailable Redis never delays the response.
+func (c *ViewCounter) RecordRequest(r *http.Request, post models.Post, viewerID primitive.ObjectID) {
+	if c == nil {
+		return
+	}
+	v, ok := NewPageView(r, post, viewerID, c.Now())
+	if !ok {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), viewRecordTimeout)
+		defer cancel()
+		if err := c.Record(ctx, v); err != nil {
+			log.Printf("Failed to record view of post %s: %v", v.PostID.Hex(), err)
+		}
+	}()
+}
+
+// Rollup copies every bucket changed since the last rollup into post_stats.
+// Buckets stay marked until they have closed, so their final counts are
+// copied too.
+func (c *ViewCounter) Rollup(ctx context.Context) (int, error) {
+	members, err := c.Client.SMembers(ctx, viewsDirtyKey).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	now := c.Now()
+	rolled := 0
+	for _, member := range members {
+		b, err := parseViewBucket(member)
+		if err != nil {
+			c.Client.SRem(ctx, viewsDirtyKey, member)
+			continue
+		}
+
+		bucket, ok, err := c.liveBucket(ctx, b)
+		if err != nil {
+			return rolled, err
+		}
+		if ok {
+			bucket.UpdatedAt = now
+			_, err = c.Stats.UpdateOne(ctx,
+				bson.M{"post_id": b.PostID, "granularity": b.Granularity, "start": b.Start},
+				bson.M{"$set": bucket},
+				options.Update().SetUpsert(true))
+			if err != nil {
+				return rolled, err
+			}
+			rolled++
+		}
+
+		// A minute of slack covers replicas whose clocks run a little behind.
+		if !ok || now.After(b.end().Add(time.Minute)) {
+			c.Client.SRem(ctx, viewsDirtyKey, member)
+		}
+	}
+	return rolled, nil
+}
+
+// liveBucket reads a bucket from Redis. ok is false once it has expired.
+func (c *ViewCounter) liveBucket(ctx context.Context, b viewBucket) (models.PostStatsBucket, bool, error) {
+	var referrers *redis.StringStringMapCmd
+	cmds, err := c.Client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		p.Get(ctx, b.countKey())
+		p.PFCount(ctx, b.uniquesKey())
+		if b.Granularity == models.StatsDaily {
+			referrers = p.HGetAll(ctx, b.referrersKey())
+		}
+		return nil
+	})
+	if errors.Is(err, redis.Nil) {
+		return models.PostStatsBucket{}, false, nil
+	}
+	if err != nil {
+		return models.PostStatsBucket{}, false, err
+	}
+
+	views, _ := cmds[0].(*redis.StringCmd).Int64()
+	bucket := models.PostStatsBucket{
+		PostID:      b.PostID,
+		Granularity: b.Granularity,
+		Start:       b.Start,
+		Views:       views,
+		Uniques:     cmds[1].(*redis.IntCmd).Val(),
+	}
+	if referrers != nil {
+		for host, n := range referrers.Val() {
+			count, _ := strconv.ParseInt(n, 10, 64)
+			bucket.Referrers = append(bucket.Referrers, models.ReferrerCount{Host: host, Views: count})
+		}
+		sortReferrers(bucket.Referrers)
+	}
+	return bucket, true, nil
+}
+
+func sortReferrers(refs []models.ReferrerCount) {
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Views != refs[j].Views {
+			return refs[i].Views > refs[j].Views
+		}
+		return refs[i].Host < refs[j].Host
+	})
+}
+
+// PostStats reports the views of postID in [from, to) by hour or day.
+// Buckets without views are included with zero counts.
+func (c *ViewCounter) PostStats(ctx context.Context, postID primitive.ObjectID, interval string, from, to time.Time) (models.PostStats, error) {
+	from, to = from.UTC(), to.UTC()
+	stats := models.PostStats{PostID: postID, Interval: interval, From: from, To: to, Referrers: []models.ReferrerCount{}}
+
+	cursor, err := c.Stats.Find(ctx, bson.M{
+		"post_id":     postID,
+		"granularity": interval,
+		"start":       bson.M{"$gte": from, "$lt": to},
+	}, options.Find().SetSort(bson.D{{Key: "start", Value: 1}}))
+	if err != nil {
+		return stats, err
+	}
+	var buckets []models.PostStatsBucket
+	if err := cursor.All(ctx, &buckets); err != nil {
+		return stats, err
+	}
+	for _, b := range buckets {
+		stats.Views += b.Views
+	}
+	stats.Series = FillStatsSeries(buckets, interval, from, to)
+
+	// Referrers are only kept per day.
+	if interval != models.StatsDaily {
+		cursor, err = c.Stats.Find(ctx, bson.M{
+			"post_id":     postID,
+			"granularity": models.StatsDaily,
+			"start":       bson.M{"$gte": bucketStart(models.StatsDaily, from), "$lt": to},
+		})
+		if err != nil {
+			return stats, err
+		}
+		buckets = nil
+		if err := cursor.All(ctx, &buckets); err != nil {
+			return stats, err
+		}
+	}
+	stats.Referrers = mergeReferrers(buckets)
+
+	stats.TotalUniques, err = c.Client.PFCount(ctx, totalUniquesKey(postID)).Result()
+	return stats, err
+}
+
+// FillStatsSeries lays buckets out on every interval step in [from, to),
+// adding empty buckets where there were no views. from is rounded down to
+// the start of its bucket.
+func FillStatsSeries(buckets []models.PostStatsBucket, interval string, from, to time.Time) []models.PostStatsBucket {
+	byStart := make(map[time.Time]models.PostStatsBucket, len(buckets))
+	for _, b := range buckets {
+		b.Referrers = nil
+		byStart[b.Start.UTC()] = b
+	}
+
+	series := []models.PostStatsBucket{}
+	for t := bucketStart(interval, from); t.Before(to); {
+		b, ok := byStart[t]
+		if !ok {
+			b = models.PostStatsBucket{Start: t}
+		}
+		series = append(series, b)
+		t = viewBucket{Granularity: interval, Start: t}.end()
+	}
+	return series
+}
+
+func mergeReferrers(buckets []models.PostStatsBucket) []models.ReferrerCount {
+	totals := map[string]int64{}
+	for _, b := range buckets {
+		for _, r := range b.Referrers {
+			totals[r.Host] += r.Views
+		}
+	}
+	refs := make([]models.ReferrerCount, 0, len(totals))
+	for host, n := range totals {
+		refs = append(refs, models.ReferrerCount{Host: host, Views: n})
+	}
+	sortReferrers(refs)
+	return refs
+}
+
+// TopPosts returns the most viewed posts since from, by day buckets.
+func (c *ViewCounter) TopPosts(ctx context.Context, from time.Time, limit int) ([]models.TopPost, error) {
+	cursor, err := c.Stats.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"granularity": models.StatsDaily, "start": bson.M{"$gte": from}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":     "$post_id",
+			"views":   bson.M{"$sum": "$views"},
+			"uniques": bson.M{"$sum": "$uniques"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "views", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$lookup", Value: bson.M{"from": "posts", "localField": "_id", "foreignField": "_id", "as": "post"}}},
+		{{Key: "$unwind", Value: "$post"}}, // deleted posts drop out
+		{{Key: "$project", Value: bson.M{
+			"views":     1,
+			"uniques":   1,
+			"title":     "$post.title",
+			"slug":      "$post.slug",
+			"author_id": "$post.author_id",
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	top := []models.TopPost{}
+	err = cursor.All(ctx, &top)
+	return top, err
+}
+
+// TopReferrers returns the hosts that sent the most views since from.
+func (c *ViewCounter) TopReferrers(ctx context.Context, from time.Time, limit int) ([]models.ReferrerCount, error) {
+	cursor, err := c.Stats.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"granularity": models.StatsDaily, "start": bson.M{"$gte": from}}}},
+		{{Key: "$unwind", Value: "$referrers"}},
+		{{Key: "$group", Value: bson.M{"_id": "$referrers.host", "views": bson.M{"$sum": "$referrers.views"}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "views", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$project", Value: bson.M{"_id": 0, "host": "$_id", "views": 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	top := []models.ReferrerCount{}
+	err = cursor.All(ctx, &top)
+	return top, err
+}
+
+// StartViewRollupJob copies live view counters into post_stats every few
+// minutes on one replica until ctx is done. The returned channel is closed
+// after any rollup in progress.
+func StartViewRollupJob(ctx context.Context, c *ViewCounter) <-chan struct{} {
+	return runJob(ctx, every(viewRollupInterval), func() {
+		ctx, cancel := context.WithTimeout(context.Background(), viewRollupInterval)
+		defer cancel()
+		WithLock(ctx, c.Lock, viewRollupLockKey, viewRollupInterval, func() {
+			if _, err := c.Rollup(ctx); err != nil {
+				log.Printf("Failed to roll up post views: %v", err)
+			}
+		})
+	})
+}
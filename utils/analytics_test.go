@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-backend/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestNewPageView(t *testing.T) {
+	now := time.Now()
+	post := models.Post{ID: primitive.NewObjectID(), AuthorID: primitive.NewObjectID()}
+
+	r := httptest.NewRequest("GET", "/api/v1/posts/x", nil)
+	r.Header.Set("User-Agent", "Mozilla/5.0")
+	r.Header.Set("Referer", "https://www.Example.com/links?id=1")
+	anon, ok := NewPageView(r, post, primitive.NilObjectID, now)
+	assert.True(t, ok)
+	assert.Equal(t, "example.com", anon.Referrer)
+	assert.Contains(t, anon.Viewer, "a:")
+
+	// The same browser is the same anonymous viewer; a signed in one is keyed by ID.
+	again, _ := NewPageView(r, post, primitive.NilObjectID, now)
+	assert.Equal(t, anon.Viewer, again.Viewer)
+	reader := primitive.NewObjectID()
+	signedIn, _ := NewPageView(r, post, reader, now)
+	assert.Equal(t, "u:"+reader.Hex(), signedIn.Viewer)
+
+	_, ok = NewPageView(r, post, post.AuthorID, now)
+	assert.False(t, ok, "authors reading their own post")
+
+	r.Header.Set("User-Agent", "Googlebot/2.1")
+	_, ok = NewPageView(r, post, primitive.NilObjectID, now)
+	assert.False(t, ok)
+}
+
+func TestClientIP(t *testing.T) {
+	_, proxies, _ := net.ParseCIDR("10.0.0.0/8")
+	trusted := []*net.IPNet{proxies}
+
+	r := httptest.NewRequest("GET", "/api/v1/posts/x", nil)
+	r.RemoteAddr = "203.0.113.7:4321"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	assert.Equal(t, "203.0.113.7", clientIP(r, trusted), "headers from untrusted peers are ignored")
+	assert.Equal(t, "203.0.113.7", clientIP(r, nil))
+
+	r.RemoteAddr = "10.0.0.2:4321"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1, 203.0.113.9, 10.0.0.5")
+	assert.Equal(t, "203.0.113.9", clientIP(r, trusted), "a client cannot prepend hops")
+
+	r.Header.Set("X-Forwarded-For", "10.0.0.9, 10.0.0.5")
+	assert.Equal(t, "10.0.0.9", clientIP(r, trusted))
+
+	r.Header.Del("X-Forwarded-For")
+	r.Header.Set("X-Real-IP", "198.51.100.4")
+	assert.Equal(t, "198.51.100.4", clientIP(r, trusted))
+}
+
+func TestReferrerHost(t *testing.T) {
+	t.Setenv("PUBLIC_BASE_URL", "https://blog.example.org")
+
+	assert.Equal(t, "direct", ReferrerHost(""))
+	assert.Equal(t, "direct", ReferrerHost("not a url"))
+	assert.Equal(t, "internal", ReferrerHost("https://blog.example.org/posts"))
+	assert.Equal(t, "news.ycombinator.com", ReferrerHost("https://news.ycombinator.com/item?id=1"))
+}
+
+func TestViewBucket_MemberRoundTrip(t *testing.T) {
+	at := time.Date(2026, 3, 4, 15, 42, 0, 0, time.UTC)
+	for _, b := range viewBucketsAt(primitive.NewObjectID(), at) {
+		parsed, err := parseViewBucket(b.member())
+		assert.NoError(t, err)
+		assert.Equal(t, b, parsed)
+	}
+	hour, day := viewBucketsAt(primitive.NilObjectID, at)[0], viewBucketsAt(primitive.NilObjectID, at)[1]
+	assert.Equal(t, time.Date(2026, 3, 4, 16, 0, 0, 0, time.UTC), hour.end())
+	assert.Equal(t, time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC), day.end())
+
+	_, err := parseViewBucket("week:abc:2026")
+	assert.Error(t, err)
+}
+
+func TestFillStatsSeries(t *testing.T) {
+	from := time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC)
+	to := from.Add(3 * time.Hour)
+	buckets := []models.PostStatsBucket{
+		{Start: time.Date(2026, 3, 4, 11, 0, 0, 0, time.UTC), Views: 5, Uniques: 3},
+	}
+
+	series := FillStatsSeries(buckets, models.StatsHourly, from, to)
+	assert.Len(t, series, 4) // 10:00 through 13:00
+	assert.Equal(t, int64(0), series[0].Views)
+	assert.Equal(t, int64(5), series[1].Views)
+
+	days := FillStatsSeries(nil, models.StatsDaily, from, from.AddDate(0, 0, 2))
+	assert.Len(t, days, 3)
+}
+
+func TestMergeReferrers(t *testing.T) {
+	refs := mergeReferrers([]models.PostStatsBucket{
+		{Referrers: []models.ReferrerCount{{Host: "direct", Views: 2}, {Host: "a.com", Views: 1}}},
+		{Referrers: []models.ReferrerCount{{Host: "a.com", Views: 4}}},
+	})
+	assert.Equal(t, []models.ReferrerCount{{Host: "a.com", Views: 5}, {Host: "direct", Views: 2}}, refs)
+}
@@ -0,0 +1,260 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"go-backend/config"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore stores uploaded files under opaque keys such as
+// "<owner>/<media id>.png".
+type BlobStore interface {
+	// Put stores r under key. size may be -1 when the length is not known.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open returns a seekable reader so callers can serve range requests.
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	Delete(ctx context.Context, key string) error
+	// URL is where clients can fetch the blob.
+	URL(key string) string
+}
+
+// Blobs is the store used for media, configured in main.
+var Blobs BlobStore = NewMemoryBlobStore()
+
+// NewBlobStoreFromEnv picks the backend from MEDIA_STORAGE ("local" or "s3").
+func NewBlobStoreFromEnv() (BlobStore, error) {
+	switch strings.ToLower(os.Getenv("MEDIA_STORAGE")) {
+	case "", "local":
+		dir := os.Getenv("MEDIA_DIR")
+		if dir == "" {
+			dir = "uploads"
+		}
+		return NewLocalBlobStore(dir)
+	case "s3":
+		return NewS3BlobStore(
+			os.Getenv("S3_ENDPOINT"),
+			os.Getenv("S3_ACCESS_KEY"),
+			os.Getenv("S3_SECRET_KEY"),
+			os.Getenv("S3_BUCKET"),
+			os.Getenv("S3_USE_SSL") == "true",
+		)
+	default:
+		return nil, errors.New("unknown MEDIA_STORAGE " + os.Getenv("MEDIA_STORAGE"))
+	}
+}
+
+// mediaURL is the public address of a blob served by the media file handler.
+func mediaURL(key string) string {
+	return config.PublicBaseURL() + "/media/" + key
+}
+
+// validBlobKey rejects keys that could escape the store's root.
+func validBlobKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// LocalBlobStore keeps blobs on the local filesystem below Dir.
+type LocalBlobStore struct {
+	Dir string
+}
+
+func NewLocalBlobStore(dir string) (*LocalBlobStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalBlobStore{Dir: dir}, nil
+}
+
+func (s *LocalBlobStore) path(key string) (string, error) {
+	if !validBlobKey(key) {
+		return "", errors.New("invalid blob key")
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(key)), nil
+}
+
+// Put writes to a temporary file first so readers never see partial blobs.
+func (s *LocalBlobStore) Put(_ context.Context, key string, r io.Reader, _ int64, _ string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *LocalBlobStore) Open(_ context.Context, key string) (io.ReadSeekCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	return f, err
+}
+
+func (s *LocalBlobStore) Delete(_ context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalBlobStore) URL(key string) string { return mediaURL(key) }
+
+// s3StreamPartSize is the multipart chunk size for blobs of unknown length.
+// minio buffers a whole part in memory and would otherwise pick parts of
+// hundreds of MiB, sized for the largest object S3 allows.
+const s3StreamPartSize = 16 << 20
+
+// S3BlobStore keeps blobs in an S3-compatible bucket (AWS, MinIO, R2...).
+type S3BlobStore struct {
+	Client *minio.Client
+	Bucket string
+}
+
+func NewS3BlobStore(endpoint, accessKey, secretKey, bucket string, useSSL bool) (*S3BlobStore, error) {
+	if endpoint == "" || bucket == "" {
+		return nil, errors.New("S3_ENDPOINT and S3_BUCKET are required")
+	}
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure: useSSL,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &S3BlobStore{Client: client, Bucket: bucket}, nil
+}
+
+func (s *S3BlobStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	opts := minio.PutObjectOptions{ContentType: contentType}
+	if size < 0 {
+		opts.PartSize = s3StreamPartSize
+	}
+	_, err := s.Client.PutObject(ctx, s.Bucket, key, r, size, opts)
+	return err
+}
+
+func (s *S3BlobStore) Open(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	obj, err := s.Client.GetObject(ctx, s.Bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	// GetObject is lazy; Stat surfaces a missing key.
+	if _, err := obj.Stat(); err != nil {
+		obj.Close()
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrBlobNotFound
+		}
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (s *S3BlobStore) Delete(ctx context.Context, key string) error {
+	return s.Client.RemoveObject(ctx, s.Bucket, key, minio.RemoveObjectOptions{})
+}
+
+// URL goes through the API so access rules apply regardless of bucket policy.
+func (s *S3BlobStore) URL(key string) string { return mediaURL(key) }
+
+// MemoryBlobStore keeps blobs in memory. It is meant for tests and local
+// development without a writable disk.
+type MemoryBlobStore struct {
+	mu    sync.RWMutex
+	blobs map[string][]byte
+}
+
+func NewMemoryBlobStore() *MemoryBlobStore {
+	return &MemoryBlobStore{blobs: map[string][]byte{}}
+}
+
+func (s *MemoryBlobStore) Put(_ context.Context, key string, r io.Reader, _ int64, _ string) error {
+	if !validBlobKey(key) {
+		return errors.New("invalid blob key")
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.blobs[key] = data
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryBlobStore) Open(_ context.Context, key string) (io.ReadSeekCloser, error) {
+	s.mu.RLock()
+	data, ok := s.blobs[key]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrBlobNotFound
+	}
+	return nopSeekCloser{bytes.NewReader(data)}, nil
+}
+
+func (s *MemoryBlobStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	delete(s.blobs, key)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryBlobStore) URL(key string) string { return mediaURL(key) }
+
+// Keys lists the stored keys.
+func (s *MemoryBlobStore) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0, len(s.blobs))
+	for k := range s.blobs {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+type nopSeekCloser struct{ *bytes.Reader }
+
+func (nopSeekCloser) Close() error { return nil }
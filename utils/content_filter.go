@@ -0,0 +1,418 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"go-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The content filter screens the title and content of posts as they are
+// created and updated. It is a pipeline of rules, each reporting findings
+// with an action; the strongest action decides whether the post is
+// rejected, accepted and flagged for moderation, or accepted with the
+// offending text masked. Admins manage the configured rules at runtime and
+// code may append its own.
+
+const (
+	contentFilterID  = "content_filter"
+	contentFilterTTL = 30 * time.Second
+	linkMask         = "[link removed]"
+	maxBlockedTerm   = 100 // characters
+)
+
+// Rule names reported in findings.
+const (
+	RuleBlockedTerm = "blocked_term"
+	RuleLinkDomain  = "link_domain"
+	RuleMaxLinks    = "max_links"
+)
+
+// PostText is the text of a post the filter looks at. Rules that mask
+// rewrite it in place.
+type PostText struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+// ContentFinding is one thing a rule objected to.
+type ContentFinding struct {
+	Rule   string              `json:"rule"`
+	Action models.FilterAction `json:"action"`
+	Match  string              `json:"match"`
+}
+
+func (f ContentFinding) String() string {
+	switch f.Rule {
+	case RuleBlockedTerm:
+		return fmt.Sprintf("blocked term %q", f.Match)
+	case RuleLinkDomain:
+		return fmt.Sprintf("link to %s", f.Match)
+	}
+	return f.Match
+}
+
+// A ContentRule inspects a post and reports its findings, masking the text
+// itself for findings with the mask action.
+type ContentRule interface {
+	Check(text *PostText) []ContentFinding
+}
+
+// ContentVerdict is the outcome of screening a post.
+type ContentVerdict struct {
+	Action   models.FilterAction `json:"action,omitempty"` // strongest action found, empty when clean
+	Findings []ContentFinding    `json:"findings"`
+}
+
+// Reason describes the findings for the author or a moderator.
+func (v ContentVerdict) Reason() string {
+	parts := make([]string, len(v.Findings))
+	for i, f := range v.Findings {
+		parts[i] = f.String()
+	}
+	return strings.Join(parts, "; ")
+}
+
+// ContentFilter runs its rules in order over a post.
+type ContentFilter struct {
+	Rules []ContentRule
+}
+
+// Screen runs every rule, even after a rejecting one, so the author learns
+// about all problems at once.
+func (f *ContentFilter) Screen(text *PostText) ContentVerdict {
+	v := ContentVerdict{Findings: []ContentFinding{}}
+	for _, rule := range f.Rules {
+		for _, finding := range rule.Check(text) {
+			v.Findings = append(v.Findings, finding)
+			if finding.Action.Severity() > v.Action.Severity() {
+				v.Action = finding.Action
+			}
+		}
+	}
+	return v
+}
+
+// NewContentFilter compiles the configured rules. ownHost, the host of our
+// own links, is always allowed.
+func NewContentFilter(cfg models.ContentFilterConfig, ownHost string) (*ContentFilter, error) {
+	f := &ContentFilter{}
+	if len(cfg.BlockedTerms) > 0 {
+		rule, err := NewBlockedTermsRule(cfg.BlockedTerms)
+		if err != nil {
+			return nil, err
+		}
+		f.Rules = append(f.Rules, rule)
+	}
+	if len(cfg.AllowedDomains) > 0 || len(cfg.DeniedDomains) > 0 {
+		if !cfg.DomainAction.Valid() {
+			return nil, fmt.Errorf("domain_action must be reject, flag or mask")
+		}
+		f.Rules = append(f.Rules, &LinkDomainRule{
+			Allowed: normalizeDomains(cfg.AllowedDomains),
+			Denied:  normalizeDomains(cfg.DeniedDomains),
+			OwnHost: strings.ToLower(ownHost),
+			Action:  cfg.DomainAction,
+		})
+	}
+	if cfg.MaxLinks < 0 {
+		return nil, fmt.Errorf("max_links cannot be negative")
+	}
+	if cfg.MaxLinks > 0 {
+		if !cfg.MaxLinksAction.Valid() {
+			return nil, fmt.Errorf("max_links_action must be reject, flag or mask")
+		}
+		f.Rules = append(f.Rules, &MaxLinksRule{Max: cfg.MaxLinks, Action: cfg.MaxLinksAction})
+	}
+	return f, nil
+}
+
+type blockedTerm struct {
+	models.BlockedTerm
+	pattern *regexp.Regexp
+}
+
+// BlockedTermsRule finds blocked words and phrases.
+type BlockedTermsRule struct {
+	terms []blockedTerm
+}
+
+// NewBlockedTermsRule compiles terms into case-insensitive patterns bounded
+// by word breaks, allowing any run of whitespace inside phrases.
+func NewBlockedTermsRule(terms []models.BlockedTerm) (*BlockedTermsRule, error) {
+	rule := &BlockedTermsRule{}
+	for _, t := range terms {
+		words := strings.Fields(strings.ToLower(t.Term))
+		if len(words) == 0 {
+			return nil, fmt.Errorf("blocked terms cannot be empty")
+		}
+		if len(t.Term) > maxBlockedTerm {
+			return nil, fmt.Errorf("blocked terms are limited to %d characters", maxBlockedTerm)
+		}
+		if !t.Action.Valid() {
+			return nil, fmt.Errorf("action of %q must be reject, flag or mask", t.Term)
+		}
+		term := strings.Join(words, " ")
+		for i, w := range words {
+			words[i] = regexp.QuoteMeta(w)
+		}
+		expr := strings.Join(words, `\s+`)
+		if isWordByte(expr[0]) {
+			expr = `\b` + expr
+		}
+		if isWordByte(expr[len(expr)-1]) {
+			expr += `\b`
+		}
+		rule.terms = append(rule.terms, blockedTerm{
+			BlockedTerm: models.BlockedTerm{Term: term, Action: t.Action},
+			pattern:     regexp.MustCompile(`(?i)` + expr),
+		})
+	}
+	return rule, nil
+}
+
+// isWordByte tells whether \b can bound b, which is only true of ASCII
+// letters, digits and underscores.
+func isWordByte(b byte) bool {
+	return b == '_' || ('0' <= b && b <= '9') || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z')
+}
+
+func (r *BlockedTermsRule) Check(text *PostText) []ContentFinding {
+	var findings []ContentFinding
+	for _, t := range r.terms {
+		if !t.pattern.MatchString(text.Title) && !t.pattern.MatchString(text.Content) {
+			continue
+		}
+		findings = append(findings, ContentFinding{Rule: RuleBlockedTerm, Action: t.Action, Match: t.Term})
+		if t.Action == models.FilterMask {
+			text.Title = t.pattern.ReplaceAllStringFunc(text.Title, maskTerm)
+			text.Content = t.pattern.ReplaceAllStringFunc(text.Content, maskTerm)
+		}
+	}
+	return findings
+}
+
+// maskTerm keeps the first letter and the spacing of a match.
+func maskTerm(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if i == 0 || r == ' ' || r == '\t' || r == '\n' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('*')
+		}
+	}
+	return b.String()
+}
+
+// linkPattern finds http(s) links in plain text and markdown.
+var linkPattern = regexp.MustCompile(`(?i)\bhttps?://[^\s<>()\[\]"'` + "`" + `]+`)
+
+// linkHost returns the lowercased host of a link without a leading www.
+func linkHost(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+func normalizeDomains(domains []string) []string {
+	out := make([]string, 0, len(domains))
+	for _, d := range domains {
+		d = strings.TrimPrefix(strings.Trim(strings.ToLower(strings.TrimSpace(d)), "."), "www.")
+		if d != "" {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// inDomains tells whether host is one of domains or a subdomain of one.
+func inDomains(host string, domains []string) bool {
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// LinkDomainRule objects to links to denied domains, and to links outside
+// the allowed domains when any are set.
+type LinkDomainRule struct {
+	Allowed []string
+	Denied  []string
+	OwnHost string
+	Action  models.FilterAction
+}
+
+func (r *LinkDomainRule) allowed(host string) bool {
+	if host == "" {
+		return false
+	}
+	if r.OwnHost != "" && inDomains(host, []string{strings.TrimPrefix(r.OwnHost, "www.")}) {
+		return true
+	}
+	if inDomains(host, r.Denied) {
+		return false
+	}
+	return len(r.Allowed) == 0 || inDomains(host, r.Allowed)
+}
+
+func (r *LinkDomainRule) Check(text *PostText) []ContentFinding {
+	var findings []ContentFinding
+	seen := map[string]bool{}
+	check := func(s string) string {
+		return linkPattern.ReplaceAllStringFunc(s, func(link string) string {
+			host := linkHost(link)
+			if r.allowed(host) {
+				return link
+			}
+			if !seen[host] {
+				seen[host] = true
+				findings = append(findings, ContentFinding{Rule: RuleLinkDomain, Action: r.Action, Match: host})
+			}
+			if r.Action == models.FilterMask {
+				return linkMask
+			}
+			return link
+		})
+	}
+	text.Title = check(text.Title)
+	text.Content = check(text.Content)
+	return findings
+}
+
+// MaxLinksRule objects to posts with more than Max links. Masking removes
+// the links past the limit.
+type MaxLinksRule struct {
+	Max    int
+	Action models.FilterAction
+}
+
+func (r *MaxLinksRule) Check(text *PostText) []ContentFinding {
+	n := len(linkPattern.FindAllStringIndex(text.Title, -1)) + len(linkPattern.FindAllStringIndex(text.Content, -1))
+	if n <= r.Max {
+		return nil
+	}
+	if r.Action == models.FilterMask {
+		kept := 0
+		trim := func(link string) string {
+			if kept++; kept > r.Max {
+				return linkMask
+			}
+			return link
+		}
+		text.Title = linkPattern.ReplaceAllStringFunc(text.Title, trim)
+		text.Content = linkPattern.ReplaceAllStringFunc(text.Content, trim)
+	}
+	return []ContentFinding{{Rule: RuleMaxLinks, Action: r.Action, Match: fmt.Sprintf("%d links, at most %d allowed", n, r.Max)}}
+}
+
+// ContentPolicy keeps the filter configuration in Mongo and the compiled
+// filter in memory. It reloads after a short while so changes made through
+// another replica apply everywhere.
+type ContentPolicy struct {
+	Settings *mongo.Collection
+	OwnHost  string
+	Extra    []ContentRule // run after the configured rules
+	TTL      time.Duration
+	Now      func() time.Time
+
+	mu     sync.Mutex
+	config models.ContentFilterConfig
+	filter *ContentFilter
+	loaded time.Time
+}
+
+// Content is the process wide content policy, configured in main. Posts are
+// not filtered while it is nil.
+var Content *ContentPolicy
+
+func NewContentPolicy(db *mongo.Database, publicBaseURL string) *ContentPolicy {
+	p := &ContentPolicy{
+		Settings: db.Collection("settings"),
+		TTL:      contentFilterTTL,
+		Now:      time.Now,
+	}
+	if u, err := url.Parse(publicBaseURL); err == nil {
+		p.OwnHost = u.Hostname()
+	}
+	return p
+}
+
+// current returns the configuration and filter, reloading them when stale.
+// A failed reload keeps serving the previous filter.
+func (p *ContentPolicy) current(ctx context.Context) (models.ContentFilterConfig, *ContentFilter, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.filter != nil && p.Now().Sub(p.loaded) < p.TTL {
+		return p.config, p.filter, nil
+	}
+
+	var cfg models.ContentFilterConfig
+	err := p.Settings.FindOne(ctx, bson.M{"_id": contentFilterID}).Decode(&cfg)
+	if err != nil && err != mongo.ErrNoDocuments {
+		if p.filter != nil {
+			log.Printf("Failed to reload content filter, keeping the previous one: %v", err)
+			return p.config, p.filter, nil
+		}
+		return cfg, nil, err
+	}
+	filter, err := NewContentFilter(cfg, p.OwnHost)
+	if err != nil {
+		return cfg, nil, err
+	}
+	filter.Rules = append(filter.Rules, p.Extra...)
+	p.config, p.filter, p.loaded = cfg, filter, p.Now()
+	return cfg, filter, nil
+}
+
+// Config returns the current configuration.
+func (p *ContentPolicy) Config(ctx context.Context) (models.ContentFilterConfig, error) {
+	cfg, _, err := p.current(ctx)
+	return cfg, err
+}
+
+// Screen runs the filter over text, masking it in place.
+func (p *ContentPolicy) Screen(ctx context.Context, text *PostText) (ContentVerdict, error) {
+	if p == nil {
+		return ContentVerdict{Findings: []ContentFinding{}}, nil
+	}
+	_, filter, err := p.current(ctx)
+	if err != nil {
+		return ContentVerdict{}, err
+	}
+	return filter.Screen(text), nil
+}
+
+// Update validates and stores a new configuration, which applies here at
+// once and on other replicas within the TTL.
+func (p *ContentPolicy) Update(ctx context.Context, cfg models.ContentFilterConfig) (models.ContentFilterConfig, error) {
+	filter, err := NewContentFilter(cfg, p.OwnHost)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.UpdatedAt = p.Now()
+	_, err = p.Settings.ReplaceOne(ctx, bson.M{"_id": contentFilterID}, cfg, options.Replace().SetUpsert(true))
+	if err != nil {
+		return cfg, err
+	}
+	filter.Rules = append(filter.Rules, p.Extra...)
+
+	p.mu.Lock()
+	p.config, p.filter, p.loaded = cfg, filter, p.Now()
+	p.mu.Unlock()
+	return cfg, nil
+}
@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"testing"
+
+	"go-backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockedTermsRule_WordBoundaries(t *testing.T) {
+	rule, err := NewBlockedTermsRule([]models.BlockedTerm{
+		{Term: "ass", Action: models.FilterMask},
+		{Term: "Pump  and Dump", Action: models.FilterFlag},
+	})
+	assert.NoError(t, err)
+
+	text := &PostText{Title: "A classic setup", Content: "Don't be an ass. ASS!"}
+	findings := rule.Check(text)
+	if !assert.Len(t, findings, 1) {
+		return
+	}
+	assert.Equal(t, "ass", findings[0].Match)
+	assert.Equal(t, "A classic setup", text.Title, "class is not ass")
+	assert.Equal(t, "Don't be an a**. A**!", text.Content)
+
+	text = &PostText{Content: "Classic pump\nand   dump scheme"}
+	findings = rule.Check(text)
+	if !assert.Len(t, findings, 1) {
+		return
+	}
+	assert.Equal(t, models.FilterFlag, findings[0].Action)
+	assert.Equal(t, "pump and dump", findings[0].Match)
+	assert.Equal(t, "Classic pump\nand   dump scheme", text.Content, "flagged text is left alone")
+}
+
+func TestBlockedTermsRule_Invalid(t *testing.T) {
+	_, err := NewBlockedTermsRule([]models.BlockedTerm{{Term: "  ", Action: models.FilterMask}})
+	assert.Error(t, err)
+	_, err = NewBlockedTermsRule([]models.BlockedTerm{{Term: "scam", Action: "shout"}})
+	assert.Error(t, err)
+}
+
+func TestLinkDomainRule(t *testing.T) {
+	rule := &LinkDomainRule{
+		Allowed: []string{"tradingview.com"},
+		Denied:  []string{"bad.tradingview.com"},
+		OwnHost: "example.com",
+		Action:  models.FilterMask,
+	}
+	text := &PostText{Content: "Chart: https://www.tradingview.com/x, see [this](https://bad.tradingview.com/y) " +
+		"and https://spam.io/a and https://spam.io/b or http://example.com/posts/1"}
+
+	findings := rule.Check(text)
+	if !assert.Len(t, findings, 2) {
+		return
+	}
+	assert.Equal(t, "bad.tradingview.com", findings[0].Match)
+	assert.Equal(t, "spam.io", findings[1].Match, "one finding per host")
+	assert.Equal(t, "Chart: https://www.tradingview.com/x, see [this]([link removed]) "+
+		"and [link removed] and [link removed] or http://example.com/posts/1", text.Content)
+}
+
+func TestMaxLinksRule(t *testing.T) {
+	rule := &MaxLinksRule{Max: 1, Action: models.FilterMask}
+	text := &PostText{Title: "http://a.com", Content: "http://b.com http://c.com"}
+
+	findings := rule.Check(text)
+	if !assert.Len(t, findings, 1) {
+		return
+	}
+	assert.Equal(t, "3 links, at most 1 allowed", findings[0].Match)
+	assert.Equal(t, "http://a.com", text.Title)
+	assert.Equal(t, "[link removed] [link removed]", text.Content)
+
+	assert.Empty(t, rule.Check(&PostText{Content: "http://a.com"}))
+}
+
+func TestContentFilter_StrongestActionWins(t *testing.T) {
+	filter, err := NewContentFilter(models.ContentFilterConfig{
+		BlockedTerms:   []models.BlockedTerm{{Term: "darn", Action: models.FilterMask}},
+		DeniedDomains:  []string{"Scam.example."},
+		DomainAction:   models.FilterReject,
+		MaxLinks:       5,
+		MaxLinksAction: models.FilterFlag,
+	}, "localhost")
+	assert.NoError(t, err)
+
+	v := filter.Screen(&PostText{Content: "darn"})
+	assert.Equal(t, models.FilterMask, v.Action)
+
+	v = filter.Screen(&PostText{Content: "darn, see https://scam.example/win"})
+	assert.Equal(t, models.FilterReject, v.Action)
+	assert.Equal(t, `blocked term "darn"; link to scam.example`, v.Reason())
+
+	v = filter.Screen(&PostText{Content: "clean"})
+	assert.Empty(t, v.Action)
+	assert.Empty(t, v.Findings)
+}
+
+func TestNewContentFilter_Validates(t *testing.T) {
+	_, err := NewContentFilter(models.ContentFilterConfig{DeniedDomains: []string{"x.com"}}, "")
+	assert.Error(t, err, "a domain list needs an action")
+	_, err = NewContentFilter(models.ContentFilterConfig{MaxLinks: -1}, "")
+	assert.Error(t, err)
+
+	filter, err := NewContentFilter(models.ContentFilterConfig{}, "")
+	assert.NoError(t, err)
+	assert.Empty(t, filter.Rules)
+}
@@ -5,41 +5,386 @@ import (
 	"log"
 	"time"
 
+	"go-backend/config"
+	"go-backend/models"
+
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	schedulerLockKey     = "lock:post-scheduler"
+	schedulerInterval    = time.Minute
+	schedulerBatchSize   = 500
+	schedulerMaxAttempts = 3
 )
 
-func StartPostScheduler(db *mongo.Database) {
-	ticker := time.NewTicker(1 * time.Minute) // Runs every 1 min
+// ScheduledPostStore is the persistence the scheduler needs.
+type ScheduledPostStore interface {
+	// DueScheduled returns scheduled posts whose scheduled_at is not after now.
+	DueScheduled(ctx context.Context, now time.Time) ([]models.Post, error)
+	// MarkPublished publishes post if it is still scheduled. ok is false when
+	// it was changed in the meantime.
+	MarkPublished(ctx context.Context, post models.Post, now time.Time) (ok bool, err error)
+	// DueExpired returns published posts whose expires_at is not after now.
+	DueExpired(ctx context.Context, now time.Time) ([]models.Post, error)
+	// MarkExpired unpublishes post if it is still published.
+	MarkExpired(ctx context.Context, post models.Post, now time.Time) (ok bool, err error)
+}
+
+// MongoScheduledPosts is the ScheduledPostStore backed by the posts collection.
+type MongoScheduledPosts struct {
+	Posts *mongo.Collection
+}
+
+func (s *MongoScheduledPosts) DueScheduled(ctx context.Context, now time.Time) ([]models.Post, error) {
+	filter := bson.M{
+		"status":       models.PostStatusScheduled,
+		"scheduled_at": bson.M{"$lte": now},
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "scheduled_at", Value: 1}}).
+		SetLimit(schedulerBatchSize)
+
+	cursor, err := s.Posts.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	posts := []models.Post{}
+	err = cursor.All(ctx, &posts)
+	return posts, err
+}
+
+func (s *MongoScheduledPosts) MarkPublished(ctx context.Context, post models.Post, now time.Time) (bool, error) {
+	change := models.StatusChange{
+		Action: models.ActionPublish,
+		From:   models.PostStatusScheduled,
+		To:     models.PostStatusPublished,
+		By:     primitive.NilObjectID,
+		Role:   "system",
+		At:     now,
+	}
+
+	result, err := s.Posts.UpdateOne(ctx,
+		bson.M{"_id": post.ID, "status": models.PostStatusScheduled},
+		bson.M{
+			"$set":  bson.M{"status": models.PostStatusPublished, "published_at": now, "updated_at": now},
+			"$push": bson.M{"status_history": change},
+			"$inc":  bson.M{"version": 1},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+func (s *MongoScheduledPosts) DueExpired(ctx context.Context, now time.Time) ([]models.Post, error) {
+	filter := bson.M{
+		"status":     models.PostStatusPublished,
+		"expires_at": bson.M{"$lte": now},
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "expires_at", Value: 1}}).
+		SetLimit(schedulerBatchSize)
+
+	cursor, err := s.Posts.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	posts := []models.Post{}
+	err = cursor.All(ctx, &posts)
+	return posts, err
+}
+
+// MarkExpired moves an expired post back to draft like a manual unpublish and
+// clears expires_at so republishing it does not expire it straight away.
+func (s *MongoScheduledPosts) MarkExpired(ctx context.Context, post models.Post, now time.Time) (bool, error) {
+	change := models.StatusChange{
+		Action: models.ActionExpire,
+		From:   models.PostStatusPublished,
+		To:     models.PostStatusDraft,
+		By:     primitive.NilObjectID,
+		Role:   "system",
+		Reason: "expired at " + post.ExpiresAt.UTC().Format(time.RFC3339),
+		At:     now,
+	}
+
+	result, err := s.Posts.UpdateOne(ctx,
+		bson.M{"_id": post.ID, "status": models.PostStatusPublished},
+		bson.M{
+			"$set":   bson.M{"status": models.PostStatusDraft, "updated_at": now},
+			"$unset": bson.M{"published_at": "", "expires_at": ""},
+			"$push":  bson.M{"status_history": change},
+			"$inc":   bson.M{"version": 1},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// PostScheduler publishes scheduled posts once their scheduled_at passes and
+// unpublishes posts once their expires_at passes. With a Lock configured
+// only one replica does so per tick.
+type PostScheduler struct {
+	Store       ScheduledPostStore
+	Lock        Locker // nil runs without cross-replica coordination
+	Events      *EventBus
+	Now         func() time.Time
+	Interval    time.Duration
+	MaxAttempts int
+	RetryDelay  time.Duration
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewPostScheduler(db *mongo.Database) *PostScheduler {
+	s := &PostScheduler{
+		Store:       &MongoScheduledPosts{Posts: db.Collection("posts")},
+		Events:      Events,
+		Now:         time.Now,
+		Interval:    schedulerInterval,
+		MaxAttempts: schedulerMaxAttempts,
+		RetryDelay:  time.Second,
+	}
+	if config.Cache != nil {
+		s.Lock = &RedisLock{Client: config.Cache}
+	}
+	return s
+}
+
+// Start runs the scheduler immediately and then every Interval until Stop.
+func (s *PostScheduler) Start() {
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+
 	go func() {
-		for range ticker.C {
-			publishScheduledPosts(db)
+		defer close(s.done)
+		ticker := time.NewTicker(s.Interval)
+		defer ticker.Stop()
+
+		for {
+			s.tick()
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
 		}
 	}()
 }
 
-func publishScheduledPosts(db *mongo.Database) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// Stop signals the scheduler to exit and waits for an in-flight run to end.
+func (s *PostScheduler) Stop() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+}
+
+func (s *PostScheduler) tick() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.Interval)
 	defer cancel()
 
-	postsColl := db.Collection("posts")
+	if s.Lock != nil {
+		release, ok, err := s.Lock.Acquire(ctx, schedulerLockKey, s.Interval)
+		if err != nil {
+			log.Printf("Post scheduler could not take lock: %v", err)
+			return
+		}
+		if !ok {
+			return // another replica is publishing
+		}
+		defer release()
+	}
 
-	filter := bson.M{
-		"published":    false,
-		"scheduledFor": bson.M{"$lte": time.Now()},
+	published, err := s.RunOnce(ctx)
+	if err != nil {
+		log.Printf("Failed to publish scheduled posts: %v", err)
+	}
+	if published > 0 {
+		log.Printf("✅ Published %d scheduled post(s)", published)
 	}
 
-	update := bson.M{
-		"$set": bson.M{"published": true, "publishedAt": time.Now()},
+	expired, err := s.ExpireOnce(ctx)
+	if err != nil {
+		log.Printf("Failed to unpublish expired posts: %v", err)
 	}
+	if expired > 0 {
+		log.Printf("✅ Unpublished %d expired post(s)", expired)
+	}
+}
+
+// RunOnce publishes every post that is due at s.Now() and emits a
+// post.published event for each. It returns how many were published and the
+// last error seen; one failing post does not stop the rest.
+func (s *PostScheduler) RunOnce(ctx context.Context) (int, error) {
+	now := s.Now()
 
-	result, err := postsColl.UpdateMany(ctx, filter, update)
+	posts, err := s.Store.DueScheduled(ctx, now)
 	if err != nil {
-		log.Printf("Failed to publish scheduled posts: %v", err)
-		return
+		return 0, err
+	}
+
+	published := 0
+	var lastErr error
+	for _, post := range posts {
+		ok, err := s.withRetry(ctx, func() (bool, error) { return s.Store.MarkPublished(ctx, post, now) })
+		if err != nil {
+			log.Printf("Giving up publishing post %s for now: %v", post.ID.Hex(), err)
+			lastErr = err
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		published++
+		if s.Events != nil {
+			s.Events.Emit(ctx, PostPublishedEvent(post, now))
+		}
+	}
+
+	return published, lastErr
+}
+
+// ExpireOnce unpublishes every post whose expires_at has passed at s.Now()
+// and emits a post.unpublished event for each.
+func (s *PostScheduler) ExpireOnce(ctx context.Context) (int, error) {
+	now := s.Now()
+
+	posts, err := s.Store.DueExpired(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	var lastErr error
+	for _, post := range posts {
+		ok, err := s.withRetry(ctx, func() (bool, error) { return s.Store.MarkExpired(ctx, post, now) })
+		if err != nil {
+			log.Printf("Giving up expiring post %s for now: %v", post.ID.Hex(), err)
+			lastErr = err
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		expired++
+		if s.Events != nil {
+			s.Events.Emit(ctx, PostUnpublishedEvent(post, models.ActionExpire, now))
+		}
+	}
+
+	return expired, lastErr
+}
+
+// withRetry retries transient store failures with exponential backoff.
+// Posts that still fail are picked up again on the next tick.
+func (s *PostScheduler) withRetry(ctx context.Context, fn func() (bool, error)) (bool, error) {
+	attempts := s.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := s.RetryDelay
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		var ok bool
+		if ok, err = fn(); err == nil {
+			return ok, nil
+		}
+		if attempt == attempts {
+			break
+		}
+
+		select {
+		case <-time.After(delay):
+			delay *= 2
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-s.stop:
+			return false, err
+		}
+	}
+	return false, err
+}
+
+// PostUnpublishedEvent builds the post.unpublished event for post. reason is
+// the workflow action that took it down, e.g. unpublish, archive or expire.
+func PostUnpublishedEvent(post models.Post, reason string, at time.Time) Event {
+	return Event{
+		Name: EventPostUnpublished,
+		At:   at,
+		Data: map[string]interface{}{
+			"post_id":   post.ID.Hex(),
+			"author_id": post.AuthorID.Hex(),
+			"reason":    reason,
+		},
 	}
+}
 
-	if result.ModifiedCount > 0 {
-		log.Printf("✅ Published %d scheduled post(s)", result.ModifiedCount)
+// PostPublishedEvent builds the post.published event for post.
+func PostPublishedEvent(post models.Post, at time.Time) Event {
+	return Event{
+		Name: EventPostPublished,
+		At:   at,
+		Data: map[string]interface{}{
+			"post_id":    post.ID.Hex(),
+			"author_id":  post.AuthorID.Hex(),
+			"slug":       post.Slug,
+			"visibility": post.Visibility,
+			"type":       post.Type,
+		},
+	}
+}
+
+// runJob calls run in the background until ctx is done, sleeping for wait
+// before each call; wait gets the current time. Runs are not interrupted by
+// ctx, so they should use a context of their own. The returned channel is
+// closed once the job has stopped, after any run in progress.
+func runJob(ctx context.Context, wait func(now time.Time) time.Duration, run func()) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			timer := time.NewTimer(wait(time.Now()))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			run()
+		}
+	}()
+	return done
+}
+
+// every is a wait for runJob that runs each interval.
+func every(interval time.Duration) func(time.Time) time.Duration {
+	return func(time.Time) time.Duration { return interval }
+}
+
+// immediately makes runJob run once right away and then wait as usual.
+func immediately(wait func(time.Time) time.Duration) func(time.Time) time.Duration {
+	started := false
+	return func(now time.Time) time.Duration {
+		if !started {
+			started = true
+			return 0
+		}
+		return wait(now)
 	}
 }
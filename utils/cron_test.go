@@ -0,0 +1,200 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go-backend/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fakeScheduledStore struct {
+	mu        sync.Mutex
+	posts     []models.Post
+	failures  map[primitive.ObjectID]int // remaining failures per post
+	attempts  int
+	published []primitive.ObjectID
+	expired   []primitive.ObjectID
+}
+
+func (s *fakeScheduledStore) DueScheduled(_ context.Context, now time.Time) ([]models.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	due := []models.Post{}
+	for _, p := range s.posts {
+		if p.Status == models.PostStatusScheduled && !p.ScheduledAt.After(now) {
+			due = append(due, p)
+		}
+	}
+	return due, nil
+}
+
+func (s *fakeScheduledStore) MarkPublished(_ context.Context, post models.Post, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts++
+	if s.failures[post.ID] > 0 {
+		s.failures[post.ID]--
+		return false, errors.New("transient")
+	}
+	for i := range s.posts {
+		if s.posts[i].ID == post.ID && s.posts[i].Status == models.PostStatusScheduled {
+			s.posts[i].Status = models.PostStatusPublished
+			s.posts[i].PublishedAt = &now
+			s.published = append(s.published, post.ID)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *fakeScheduledStore) DueExpired(_ context.Context, now time.Time) ([]models.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	due := []models.Post{}
+	for _, p := range s.posts {
+		if p.Status == models.PostStatusPublished && p.ExpiresAt != nil && !p.ExpiresAt.After(now) {
+			due = append(due, p)
+		}
+	}
+	return due, nil
+}
+
+func (s *fakeScheduledStore) MarkExpired(_ context.Context, post models.Post, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.posts {
+		if s.posts[i].ID == post.ID && s.posts[i].Status == models.PostStatusPublished {
+			s.posts[i].Status = models.PostStatusDraft
+			s.posts[i].PublishedAt, s.posts[i].ExpiresAt = nil, nil
+			s.expired = append(s.expired, post.ID)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func scheduledPost(at time.Time) models.Post {
+	return models.Post{ID: primitive.NewObjectID(), Status: models.PostStatusScheduled, ScheduledAt: &at}
+}
+
+func newTestScheduler(store ScheduledPostStore, now time.Time) (*PostScheduler, *[]Event) {
+	var events []Event
+	bus := &EventBus{}
+	record := func(_ context.Context, e Event) { events = append(events, e) }
+	bus.Subscribe(EventPostPublished, record)
+	bus.Subscribe(EventPostUnpublished, record)
+
+	return &PostScheduler{
+		Store:       store,
+		Events:      bus,
+		Now:         func() time.Time { return now },
+		Interval:    time.Minute,
+		MaxAttempts: 3,
+		RetryDelay:  time.Millisecond,
+	}, &events
+}
+
+func TestPostScheduler_PublishesDuePosts(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	due, later := scheduledPost(now.Add(-time.Minute)), scheduledPost(now.Add(time.Hour))
+	store := &fakeScheduledStore{posts: []models.Post{due, later}}
+
+	s, events := newTestScheduler(store, now)
+	n, err := s.RunOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []primitive.ObjectID{due.ID}, store.published)
+	assert.Len(t, *events, 1)
+	assert.Equal(t, due.ID.Hex(), (*events)[0].Data["post_id"])
+
+	// Advancing the clock picks up the later post.
+	s.Now = func() time.Time { return now.Add(2 * time.Hour) }
+	n, _ = s.RunOnce(context.Background())
+	assert.Equal(t, 1, n)
+	assert.Len(t, *events, 2)
+}
+
+func TestPostScheduler_RetriesTransientFailures(t *testing.T) {
+	now := time.Now()
+	post := scheduledPost(now.Add(-time.Second))
+	store := &fakeScheduledStore{posts: []models.Post{post}, failures: map[primitive.ObjectID]int{post.ID: 2}}
+
+	s, _ := newTestScheduler(store, now)
+	n, err := s.RunOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 3, store.attempts)
+}
+
+func TestPostScheduler_GivesUpAfterMaxAttempts(t *testing.T) {
+	now := time.Now()
+	post := scheduledPost(now.Add(-time.Second))
+	store := &fakeScheduledStore{posts: []models.Post{post}, failures: map[primitive.ObjectID]int{post.ID: 5}}
+
+	s, events := newTestScheduler(store, now)
+	n, err := s.RunOnce(context.Background())
+
+	assert.Error(t, err)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, 3, store.attempts)
+	assert.Empty(t, *events)
+}
+
+func TestPostScheduler_UnpublishesExpiredPosts(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Minute), now.Add(time.Hour)
+	expired := models.Post{ID: primitive.NewObjectID(), Status: models.PostStatusPublished, ExpiresAt: &past}
+	live := models.Post{ID: primitive.NewObjectID(), Status: models.PostStatusPublished, ExpiresAt: &future}
+	store := &fakeScheduledStore{posts: []models.Post{expired, live}}
+
+	s, events := newTestScheduler(store, now)
+	n, err := s.ExpireOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []primitive.ObjectID{expired.ID}, store.expired)
+	assert.Len(t, *events, 1)
+	assert.Equal(t, EventPostUnpublished, (*events)[0].Name)
+	assert.Equal(t, models.ActionExpire, (*events)[0].Data["reason"])
+}
+
+type denyLock struct{}
+
+func (denyLock) Acquire(context.Context, string, time.Duration) (func(), bool, error) {
+	return nil, false, nil
+}
+
+func TestPostScheduler_SkipsWithoutLockAndStops(t *testing.T) {
+	now := time.Now()
+	store := &fakeScheduledStore{posts: []models.Post{scheduledPost(now.Add(-time.Second))}}
+
+	s, _ := newTestScheduler(store, now)
+	s.Lock = denyLock{}
+	s.Start()
+	s.Stop()
+
+	assert.Empty(t, store.published)
+}
+
+func TestRunJob_StopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	runs := make(chan struct{}, 10)
+	done := runJob(ctx, immediately(every(time.Hour)), func() { runs <- struct{}{} })
+
+	<-runs
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("job did not stop")
+	}
+	assert.Empty(t, runs, "no run after the first")
+}
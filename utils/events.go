@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Event names emitted by the API.
+const (
+	EventPostPublished   = "post.published"
+	EventPostUnpublished = "post.unpublished"
+)
+
+// Event is a domain event. Subscribers in this process receive it directly;
+// other services can listen on the Redis channel "events:<name>".
+type Event struct {
+	Name string                 `json:"name"`
+	At   time.Time              `json:"at"`
+	Data map[string]interface{} `json:"data"`
+}
+
+type EventHandler func(ctx context.Context, e Event)
+
+// EventBus fans events out to in-process handlers and, when Redis is set,
+// publishes them for other replicas and services.
+type EventBus struct {
+	Redis *redis.Client
+
+	mu       sync.RWMutex
+	handlers map[string][]EventHandler
+}
+
+// Events is the process wide bus, wired to Redis in main.
+var Events = &EventBus{}
+
+func (b *EventBus) Subscribe(name string, h EventHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.handlers == nil {
+		b.handlers = map[string][]EventHandler{}
+	}
+	b.handlers[name] = append(b.handlers[name], h)
+}
+
+// Emit delivers e synchronously to local handlers. A panicking handler is
+// logged and does not stop the others.
+func (b *EventBus) Emit(ctx context.Context, e Event) {
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+
+	b.mu.RLock()
+	handlers := b.handlers[e.Name]
+	b.mu.RUnlock()
+
+	for _, h := range handlers {
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					log.Printf("Event handler for %s panicked: %v", e.Name, err)
+				}
+			}()
+			h(ctx, e)
+		}()
+	}
+
+	if b.Redis != nil {
+		data, err := json.Marshal(e)
+		if err == nil {
+			err = b.Redis.Publish(ctx, "events:"+e.Name, data).Err()
+		}
+		if err != nil {
+			log.Printf("Failed to publish %s event: %v", e.Name, err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sampleFeed() Feed {
+	published := time.Date(2026, 4, 1, 9, 0, 0, 0, time.UTC)
+	return Feed{
+		Title:   "Latest posts",
+		Link:    "http://example.com/api/v1/posts",
+		FeedURL: "http://example.com/feeds/posts.rss",
+		Updated: published,
+		Items: []FeedItem{{
+			ID:          "abc",
+			Title:       "Long <AAPL>",
+			Link:        "http://example.com/api/v1/posts/abc",
+			ContentHTML: "<p>Buy &amp; hold</p>",
+			Author:      "Jane",
+			CoAuthors:   []string{"Raj"},
+			Tags:        []string{"stocks"},
+			Published:   published,
+			Updated:     published,
+		}},
+	}
+}
+
+func TestFeedRSS(t *testing.T) {
+	feed := sampleFeed()
+	body, err := feed.RSS()
+	assert.NoError(t, err)
+
+	var doc rssDoc
+	assert.NoError(t, xml.Unmarshal(body, &doc))
+	assert.Equal(t, "2.0", doc.Version)
+	assert.Len(t, doc.Channel.Items, 1)
+	assert.Equal(t, "Long <AAPL>", doc.Channel.Items[0].Title)
+	assert.Equal(t, "<p>Buy &amp; hold</p>", doc.Channel.Items[0].Description)
+	assert.Equal(t, "Wed, 01 Apr 2026 09:00:00 +0000", doc.Channel.Items[0].PubDate)
+}
+
+func TestFeedAtom(t *testing.T) {
+	feed := sampleFeed()
+	body, err := feed.Atom()
+	assert.NoError(t, err)
+
+	var doc atomDoc
+	assert.NoError(t, xml.Unmarshal(body, &doc))
+	assert.Len(t, doc.Entries, 1)
+	assert.Equal(t, "html", doc.Entries[0].Content.Type)
+	assert.Equal(t, []atomAuthor{{Name: "Jane"}, {Name: "Raj"}}, doc.Entries[0].Authors)
+	assert.Equal(t, "2026-04-01T09:00:00Z", doc.Updated)
+}
+
+func TestFeedJSON(t *testing.T) {
+	feed := sampleFeed()
+	body, err := feed.JSON()
+	assert.NoError(t, err)
+
+	var doc map[string]interface{}
+	assert.NoError(t, json.Unmarshal(body, &doc))
+	assert.Equal(t, "https://jsonfeed.org/version/1.1", doc["version"])
+	item := doc["items"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "<p>Buy &amp; hold</p>", item["content_html"])
+	assert.Len(t, item["authors"], 2)
+}
+
+func TestSetCacheValidators(t *testing.T) {
+	modified := time.Date(2026, 4, 1, 9, 0, 0, 500, time.UTC)
+
+	req := httptest.NewRequest(http.MethodGet, "/feeds/posts.rss", nil)
+	req.Header.Set("If-None-Match", `W/"other", "v1"`)
+	rec := httptest.NewRecorder()
+	assert.True(t, SetCacheValidators(rec, req, `"v1"`, modified))
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/feeds/posts.rss", nil)
+	req.Header.Set("If-Modified-Since", modified.Format(http.TimeFormat))
+	rec = httptest.NewRecorder()
+	assert.True(t, SetCacheValidators(rec, req, `"v1"`, modified))
+
+	req = httptest.NewRequest(http.MethodGet, "/feeds/posts.rss", nil)
+	req.Header.Set("If-None-Match", `"v0"`)
+	req.Header.Set("If-Modified-Since", modified.Format(http.TimeFormat))
+	rec = httptest.NewRecorder()
+	assert.False(t, SetCacheValidators(rec, req, `"v1"`, modified))
+	assert.Equal(t, `"v1"`, rec.Header().Get("ETag"))
+}
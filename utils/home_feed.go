@@ -0,0 +1,378 @@
+package utils
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-backend/models"
+
+	"github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The home feed is a hybrid of fan-out on write and on read. Every author has
+// an outbox of recent posts and every reader an inbox, both Redis sorted sets
+// scored by publish time. Publishing pushes the post into the inboxes of the
+// author's followers, unless the author has more than FanoutLimit followers;
+// such authors are "celebrities" and readers merge their outboxes in when
+// reading instead. Inboxes and outboxes are caches: they are built from
+// MongoDB when missing and expire when unused.
+
+const (
+	feedInboxSize     = 800
+	feedOutboxSize    = 200
+	feedCacheTTL      = 7 * 24 * time.Hour
+	feedFanoutLimit   = 1000
+	feedCelebrities   = "feed:celebrities"
+	feedBuiltMarker   = "-" // keeps a built but empty feed from looking missing
+	feedFanoutTimeout = time.Minute
+)
+
+// feedPushScript adds a post to a feed that has been built and trims it.
+// Missing feeds are left alone; they are built in full on first read.
+var feedPushScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	redis.call("ZADD", KEYS[1], ARGV[1], ARGV[2])
+	redis.call("ZREMRANGEBYRANK", KEYS[1], 0, -(tonumber(ARGV[3]) + 1))
+	return 1
+end
+return 0`)
+
+func feedInboxKey(userID primitive.ObjectID) string    { return "feed:inbox:" + userID.Hex() }
+func feedOutboxKey(authorID primitive.ObjectID) string { return "feed:outbox:" + authorID.Hex() }
+
+// FeedEntry is a post in a feed, scored by its publish time in milliseconds.
+type FeedEntry struct {
+	PostID primitive.ObjectID
+	Score  int64
+}
+
+// Cursor is where the next page starts after this entry.
+func (e FeedEntry) Cursor() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(e.Score, 10) + ":" + e.PostID.Hex()))
+}
+
+// ParseFeedCursor reads a cursor made by FeedEntry.Cursor.
+func ParseFeedCursor(cursor string) (*FeedEntry, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	score, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, errors.New("invalid cursor")
+	}
+	e := FeedEntry{}
+	if e.Score, err = strconv.ParseInt(score, 10, 64); err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	if e.PostID, err = primitive.ObjectIDFromHex(id); err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	return &e, nil
+}
+
+// feedBefore orders entries newest first, ties broken by post ID.
+func feedBefore(a, b FeedEntry) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	return a.PostID.Hex() > b.PostID.Hex()
+}
+
+// mergeFeedEntries merges feeds into one, newest first, without duplicates,
+// keeping only entries after the cursor entry when it is set.
+func mergeFeedEntries(feeds [][]FeedEntry, after *FeedEntry, limit int) []FeedEntry {
+	seen := map[primitive.ObjectID]bool{}
+	merged := []FeedEntry{}
+	for _, feed := range feeds {
+		for _, e := range feed {
+			if seen[e.PostID] || (after != nil && !feedBefore(*after, e)) {
+				continue
+			}
+			seen[e.PostID] = true
+			merged = append(merged, e)
+		}
+	}
+	sort.Slice(merged, func(i, j int) bool { return feedBefore(merged[i], merged[j]) })
+	if len(merged) > limit {
+		merged = merged[:limit]
+	}
+	return merged
+}
+
+// HomeFeed builds the personalized feeds of readers.
+type HomeFeed struct {
+	Client      *redis.Client
+	Follows     *mongo.Collection
+	Posts       *mongo.Collection
+	FanoutLimit int64
+}
+
+// Feeds is the process wide home feed, configured in main.
+var Feeds *HomeFeed
+
+func NewHomeFeed(client *redis.Client, db *mongo.Database) *HomeFeed {
+	f := &HomeFeed{
+		Client:      client,
+		Follows:     db.Collection("follows"),
+		Posts:       db.Collection("posts"),
+		FanoutLimit: feedFanoutLimit,
+	}
+	if n, err := strconv.ParseInt(os.Getenv("FEED_FANOUT_LIMIT"), 10, 64); err == nil && n >= 0 {
+		f.FanoutLimit = n
+	}
+	return f
+}
+
+// Listen keeps feeds up to date with publishing on bus.
+func (f *HomeFeed) Listen(bus *EventBus) {
+	bus.Subscribe(EventPostPublished, f.onPublished)
+	bus.Subscribe(EventPostUnpublished, f.onUnpublished)
+}
+
+func eventPostIDs(e Event) (postID, authorID primitive.ObjectID, err error) {
+	p, _ := e.Data["post_id"].(string)
+	a, _ := e.Data["author_id"].(string)
+	if postID, err = primitive.ObjectIDFromHex(p); err != nil {
+		return postID, authorID, fmt.Errorf("%s event without post_id", e.Name)
+	}
+	if authorID, err = primitive.ObjectIDFromHex(a); err != nil {
+		return postID, authorID, fmt.Errorf("%s event without author_id", e.Name)
+	}
+	return postID, authorID, nil
+}
+
+// onPublished fans the post out in the background; the request or job that
+// published it does not wait for thousands of inbox writes.
+func (f *HomeFeed) onPublished(_ context.Context, e Event) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), feedFanoutTimeout)
+		defer cancel()
+		if err := f.Apply(ctx, e); err != nil {
+			log.Printf("Feed: %v", err)
+		}
+	}()
+}
+
+func (f *HomeFeed) onUnpublished(ctx context.Context, e Event) {
+	if err := f.Apply(ctx, e); err != nil {
+		log.Printf("Feed: %v", err)
+	}
+}
+
+// Apply updates feeds for a post.published or post.unpublished event and
+// returns when done. The API reacts to events through Listen; tools that
+// exit right after publishing call Apply so the fan-out is not cut short.
+func (f *HomeFeed) Apply(ctx context.Context, e Event) error {
+	postID, authorID, err := eventPostIDs(e)
+	if err != nil {
+		return err
+	}
+	switch e.Name {
+	case EventPostPublished:
+		if visibility, _ := e.Data["visibility"].(models.Visibility); visibility == models.VisibilityPrivate {
+			return nil
+		}
+		if err := f.fanOut(ctx, authorID, FeedEntry{PostID: postID, Score: e.At.UnixMilli()}); err != nil {
+			return fmt.Errorf("failed to fan out post %s: %w", postID.Hex(), err)
+		}
+	case EventPostUnpublished:
+		// Inboxes are filtered on read, only the outbox needs fixing.
+		if err := f.Client.ZRem(ctx, feedOutboxKey(authorID), postID.Hex()).Err(); err != nil {
+			return fmt.Errorf("failed to remove post %s from outbox: %w", postID.Hex(), err)
+		}
+	}
+	return nil
+}
+
+func (f *HomeFeed) fanOut(ctx context.Context, authorID primitive.ObjectID, entry FeedEntry) error {
+	err := feedPushScript.Run(ctx, f.Client, []string{feedOutboxKey(authorID)}, entry.Score, entry.PostID.Hex(), feedOutboxSize).Err()
+	if err != nil {
+		return err
+	}
+
+	followers, err := f.Follows.CountDocuments(ctx, bson.M{"author_id": authorID})
+	if err != nil {
+		return err
+	}
+	if followers > f.FanoutLimit {
+		return f.Client.SAdd(ctx, feedCelebrities, authorID.Hex()).Err()
+	}
+	demoted, err := f.Client.SRem(ctx, feedCelebrities, authorID.Hex()).Result()
+	if err != nil {
+		return err
+	}
+
+	cursor, err := f.Follows.Find(ctx, bson.M{"author_id": authorID}, options.Find().SetProjection(bson.M{"follower_id": 1}))
+	if err != nil {
+		return err
+	}
+	var follows []models.Follow
+	if err := cursor.All(ctx, &follows); err != nil {
+		return err
+	}
+	// Pipelines cannot fall back from EVALSHA to EVAL, so load it first.
+	if err := feedPushScript.Load(ctx, f.Client).Err(); err != nil {
+		return err
+	}
+	_, err = f.Client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for _, follow := range follows {
+			key := feedInboxKey(follow.FollowerID)
+			if demoted > 0 {
+				// Inboxes were built without this author; rebuild them.
+				p.Del(ctx, key)
+				continue
+			}
+			feedPushScript.EvalSha(ctx, p, []string{key}, entry.Score, entry.PostID.Hex(), feedInboxSize)
+		}
+		return nil
+	})
+	return err
+}
+
+// Following returns the IDs of the authors userID follows.
+func (f *HomeFeed) Following(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	cursor, err := f.Follows.Find(ctx, bson.M{"follower_id": userID}, options.Find().SetProjection(bson.M{"author_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var follows []models.Follow
+	if err := cursor.All(ctx, &follows); err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, 0, len(follows))
+	for _, follow := range follows {
+		ids = append(ids, follow.AuthorID)
+	}
+	return ids, nil
+}
+
+// Invalidate drops the inbox of userID, e.g. after they follow or unfollow
+// someone. It is rebuilt on the next read.
+func (f *HomeFeed) Invalidate(ctx context.Context, userID primitive.ObjectID) error {
+	return f.Client.Del(ctx, feedInboxKey(userID)).Err()
+}
+
+// Page returns up to limit entries of the feed of userID after the cursor
+// entry, newest first. Entries may point at posts that have since been
+// unpublished; callers filter them when loading the posts.
+func (f *HomeFeed) Page(ctx context.Context, userID primitive.ObjectID, after *FeedEntry, limit int) ([]FeedEntry, error) {
+	following, err := f.Following(ctx, userID)
+	if err != nil || len(following) == 0 {
+		return nil, err
+	}
+	celebrities, err := f.Client.SMembers(ctx, feedCelebrities).Result()
+	if err != nil {
+		return nil, err
+	}
+	isCelebrity := map[string]bool{}
+	for _, id := range celebrities {
+		isCelebrity[id] = true
+	}
+	var regular, famous []primitive.ObjectID
+	for _, id := range following {
+		if isCelebrity[id.Hex()] {
+			famous = append(famous, id)
+		} else {
+			regular = append(regular, id)
+		}
+	}
+
+	inbox := feedInboxKey(userID)
+	if err := f.ensureBuilt(ctx, inbox, regular, feedInboxSize); err != nil {
+		return nil, err
+	}
+	keys := []string{inbox}
+	for _, authorID := range famous {
+		outbox := feedOutboxKey(authorID)
+		if err := f.ensureBuilt(ctx, outbox, []primitive.ObjectID{authorID}, feedOutboxSize); err != nil {
+			return nil, err
+		}
+		keys = append(keys, outbox)
+	}
+
+	// Entries sharing the cursor's score may sit on either side of it, so
+	// read from that score inclusive with some room for ties.
+	max := "+inf"
+	if after != nil {
+		max = strconv.FormatInt(after.Score, 10)
+	}
+	cmds := make([]*redis.ZSliceCmd, len(keys))
+	_, err = f.Client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = p.ZRevRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{Max: max, Min: "1", Count: int64(limit) + 50})
+			p.Expire(ctx, key, feedCacheTTL)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	feeds := make([][]FeedEntry, len(cmds))
+	for i, cmd := range cmds {
+		for _, z := range cmd.Val() {
+			id, err := primitive.ObjectIDFromHex(z.Member.(string))
+			if err != nil {
+				continue
+			}
+			feeds[i] = append(feeds[i], FeedEntry{PostID: id, Score: int64(z.Score)})
+		}
+	}
+	return mergeFeedEntries(feeds, after, limit), nil
+}
+
+// ensureBuilt fills the feed at key from the published posts of authors
+// unless it exists.
+func (f *HomeFeed) ensureBuilt(ctx context.Context, key string, authors []primitive.ObjectID, size int64) error {
+	exists, err := f.Client.Exists(ctx, key).Result()
+	if err != nil || exists > 0 {
+		return err
+	}
+
+	members := []*redis.Z{{Score: 0, Member: feedBuiltMarker}}
+	if len(authors) > 0 {
+		cursor, err := f.Posts.Find(ctx, bson.M{
+			"author_id":  bson.M{"$in": authors},
+			"status":     "published",
+			"visibility": bson.M{"$ne": models.VisibilityPrivate},
+		}, options.Find().
+			SetSort(bson.D{{Key: "published_at", Value: -1}}).
+			SetLimit(size).
+			SetProjection(bson.M{"_id": 1, "published_at": 1, "created_at": 1}))
+		if err != nil {
+			return err
+		}
+		var posts []models.Post
+		if err := cursor.All(ctx, &posts); err != nil {
+			return err
+		}
+		for _, p := range posts {
+			publishedAt := p.CreatedAt
+			if p.PublishedAt != nil {
+				publishedAt = *p.PublishedAt
+			}
+			members = append(members, &redis.Z{Score: float64(publishedAt.UnixMilli()), Member: p.ID.Hex()})
+		}
+	}
+
+	_, err = f.Client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.Del(ctx, key)
+		p.ZAdd(ctx, key, members...)
+		p.Expire(ctx, key, feedCacheTTL)
+		return nil
+	})
+	return err
+}
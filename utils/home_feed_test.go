@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestFeedCursor_RoundTrip(t *testing.T) {
+	e := FeedEntry{PostID: primitive.NewObjectID(), Score: 1767225600123}
+	parsed, err := ParseFeedCursor(e.Cursor())
+	assert.NoError(t, err)
+	assert.Equal(t, e, *parsed)
+
+	_, err = ParseFeedCursor("not-a-cursor")
+	assert.Error(t, err)
+}
+
+func TestMergeFeedEntries(t *testing.T) {
+	a, b, c, d := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	inbox := []FeedEntry{{PostID: a, Score: 400}, {PostID: c, Score: 200}}
+	outbox := []FeedEntry{{PostID: b, Score: 300}, {PostID: c, Score: 200}, {PostID: d, Score: 100}}
+
+	page := mergeFeedEntries([][]FeedEntry{inbox, outbox}, nil, 3)
+	assert.Equal(t, []FeedEntry{{PostID: a, Score: 400}, {PostID: b, Score: 300}, {PostID: c, Score: 200}}, page)
+
+	next := mergeFeedEntries([][]FeedEntry{inbox, outbox}, &page[2], 3)
+	assert.Equal(t, []FeedEntry{{PostID: d, Score: 100}}, next)
+}
+
+func TestMergeFeedEntries_TiesAcrossPages(t *testing.T) {
+	x, y := primitive.NewObjectID(), primitive.NewObjectID()
+	feed := []FeedEntry{{PostID: x, Score: 100}, {PostID: y, Score: 100}}
+
+	first := mergeFeedEntries([][]FeedEntry{feed}, nil, 1)
+	second := mergeFeedEntries([][]FeedEntry{feed}, &first[0], 1)
+	assert.Len(t, second, 1)
+	assert.NotEqual(t, first[0].PostID, second[0].PostID)
+}
@@ -0,0 +1,422 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-backend/models"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/buckket/go-blurhash"
+	xdraw "golang.org/x/image/draw"
+)
+
+// ImageVariant is a named size images are scaled down to fit within.
+type ImageVariant struct {
+	Name      string
+	MaxWidth  int
+	MaxHeight int
+}
+
+var DefaultImageVariants = []ImageVariant{
+	{Name: "thumb", MaxWidth: 320, MaxHeight: 320},
+	{Name: "medium", MaxWidth: 960, MaxHeight: 960},
+	{Name: "large", MaxWidth: 1920, MaxHeight: 1920},
+}
+
+// ParseImageVariants reads a spec like "thumb=320x320,medium=960x960".
+func ParseImageVariants(spec string) ([]ImageVariant, error) {
+	var variants []ImageVariant
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, size, ok := strings.Cut(item, "=")
+		w, h, ok2 := strings.Cut(size, "x")
+		width, err1 := strconv.Atoi(w)
+		height, err2 := strconv.Atoi(h)
+		if !ok || !ok2 || err1 != nil || err2 != nil || name == "" || width <= 0 || height <= 0 {
+			return nil, fmt.Errorf("invalid image variant %q, want name=WIDTHxHEIGHT", item)
+		}
+		variants = append(variants, ImageVariant{Name: name, MaxWidth: width, MaxHeight: height})
+	}
+	if len(variants) == 0 {
+		return nil, errors.New("no image variants configured")
+	}
+	return variants, nil
+}
+
+// ImageVariantsFromEnv reads MEDIA_VARIANTS, falling back to the defaults.
+func ImageVariantsFromEnv() []ImageVariant {
+	spec := os.Getenv("MEDIA_VARIANTS")
+	if spec == "" {
+		return DefaultImageVariants
+	}
+	variants, err := ParseImageVariants(spec)
+	if err != nil {
+		log.Printf("Ignoring MEDIA_VARIANTS: %v", err)
+		return DefaultImageVariants
+	}
+	return variants
+}
+
+// ProcessImage strips metadata from the stored original of media and writes
+// WebP variants plus blurhash and LQIP placeholders, updating media in place.
+// The WebP encoder is lossless, so a variant that comes out no smaller than
+// the original points at the original instead. It does not persist the
+// record. Images over MaxImagePixels fail with ErrImageTooLarge without being
+// decoded.
+func ProcessImage(ctx context.Context, store BlobStore, media *models.Media, variants []ImageVariant) error {
+	blob, err := store.Open(ctx, media.Key)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(blob)
+	blob.Close()
+	if err != nil {
+		return err
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if err := checkImagePixels(cfg); err != nil {
+		return err
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	orientation := 1
+	if format == "jpeg" {
+		orientation = ExifOrientation(data)
+	}
+	img = ApplyOrientation(img, orientation)
+
+	clean, err := stripImageMetadata(data, format, img, orientation)
+	if err != nil {
+		return err
+	}
+	media.Size = int64(len(data))
+	if clean != nil {
+		if err := store.Put(ctx, media.Key, bytes.NewReader(clean), int64(len(clean)), media.ContentType); err != nil {
+			return err
+		}
+		media.Size = int64(len(clean))
+	}
+	b := img.Bounds()
+	media.Width, media.Height = b.Dx(), b.Dy()
+
+	media.Variants = media.Variants[:0]
+	for _, v := range variants {
+		resized := ResizeToFit(img, v.MaxWidth, v.MaxHeight)
+		var buf bytes.Buffer
+		if err := nativewebp.Encode(&buf, resized, nil); err != nil {
+			return err
+		}
+		if int64(buf.Len()) >= media.Size {
+			media.Variants = append(media.Variants, models.MediaVariant{
+				Name:        v.Name,
+				Key:         media.Key,
+				URL:         store.URL(media.Key),
+				ContentType: media.ContentType,
+				Width:       media.Width,
+				Height:      media.Height,
+				Size:        media.Size,
+			})
+			continue
+		}
+
+		key := fmt.Sprintf("%s/%s/%s.webp", media.OwnerID.Hex(), media.ID.Hex(), v.Name)
+		if err := store.Put(ctx, key, bytes.NewReader(buf.Bytes()), int64(buf.Len()), "image/webp"); err != nil {
+			return err
+		}
+		rb := resized.Bounds()
+		media.Variants = append(media.Variants, models.MediaVariant{
+			Name:        v.Name,
+			Key:         key,
+			URL:         store.URL(key),
+			ContentType: "image/webp",
+			Width:       rb.Dx(),
+			Height:      rb.Dy(),
+			Size:        int64(buf.Len()),
+		})
+	}
+
+	if media.Blurhash, err = blurhash.Encode(4, 3, ResizeToFit(img, 32, 32)); err != nil {
+		return err
+	}
+	var lqip bytes.Buffer
+	if err := jpeg.Encode(&lqip, ResizeToFit(img, 16, 16), &jpeg.Options{Quality: 40}); err != nil {
+		return err
+	}
+	media.LQIP = "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(lqip.Bytes())
+
+	now := time.Now()
+	media.Status = models.MediaStatusReady
+	media.ProcessedAt = &now
+	media.Error = ""
+	return nil
+}
+
+// stripImageMetadata returns the original without EXIF, XMP and text
+// metadata, or nil when the format carries none worth removing. JPEG, PNG and
+// WebP are rewritten losslessly unless the pixels had to be rotated.
+func stripImageMetadata(data []byte, format string, img image.Image, orientation int) ([]byte, error) {
+	var buf bytes.Buffer
+	switch {
+	case format == "jpeg" && orientation > 1:
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case format == "jpeg":
+		return StripJPEGMetadata(data)
+	case format == "png":
+		return StripPNGMetadata(data)
+	case format == "webp":
+		return StripWebPMetadata(data)
+	}
+	return nil, nil
+}
+
+// ResizeToFit scales img down to fit within maxW x maxH keeping its aspect
+// ratio. Images that already fit are returned unchanged.
+func ResizeToFit(img image.Image, maxW, maxH int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxW && h <= maxH {
+		return img
+	}
+
+	scale := float64(maxW) / float64(w)
+	if s := float64(maxH) / float64(h); s < scale {
+		scale = s
+	}
+	nw, nh := int(float64(w)*scale+0.5), int(float64(h)*scale+0.5)
+	if nw < 1 {
+		nw = 1
+	}
+	if nh < 1 {
+		nh = 1
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, nw, nh))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// ApplyOrientation rotates and flips img as described by an EXIF
+// orientation value (1-8) so it displays upright without metadata.
+func ApplyOrientation(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	b := img.Bounds()
+	src := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	w, h := b.Dx(), b.Dy()
+
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirrored
+				sx, sy = w-1-x, y
+			case 3: // rotated 180
+				sx, sy = w-1-x, h-1-y
+			case 4: // flipped vertically
+				sx, sy = x, h-1-y
+			case 5: // transposed
+				sx, sy = y, x
+			case 6: // needs 90 clockwise
+				sx, sy = y, h-1-x
+			case 7: // transversed
+				sx, sy = w-1-y, h-1-x
+			case 8: // needs 90 counter-clockwise
+				sx, sy = w-1-y, x
+			}
+			dst.SetNRGBA(x, y, src.NRGBAAt(sx, sy))
+		}
+	}
+	return dst
+}
+
+var errBadJPEG = errors.New("malformed JPEG")
+
+// jpegSegments calls fn with each marker and its payload up to the start of
+// scan, and returns the offset of the SOS marker.
+func jpegSegments(data []byte, fn func(marker byte, start, end int)) (int, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 0, errBadJPEG
+	}
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return 0, errBadJPEG
+		}
+		marker := data[i+1]
+		if marker == 0xFF { // fill byte
+			i++
+			continue
+		}
+		if marker == 0xDA {
+			return i, nil
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		end := i + 2 + length
+		if length < 2 || end > len(data) {
+			return 0, errBadJPEG
+		}
+		fn(marker, i, end)
+		i = end
+	}
+	return 0, errBadJPEG
+}
+
+// StripJPEGMetadata drops APP1 (EXIF, XMP), APP13 (IPTC) and comment
+// segments. Color related segments such as ICC profiles are kept.
+func StripJPEGMetadata(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, 0xD8)
+	sos, err := jpegSegments(data, func(marker byte, start, end int) {
+		switch marker {
+		case 0xE1, 0xED, 0xFE:
+		default:
+			out = append(out, data[start:end]...)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(out, data[sos:]...), nil
+}
+
+// ExifOrientation returns the EXIF orientation of a JPEG, or 1 if it has none.
+func ExifOrientation(data []byte) int {
+	orientation := 1
+	jpegSegments(data, func(marker byte, start, end int) {
+		payload := data[start+4 : end]
+		if marker != 0xE1 || !bytes.HasPrefix(payload, []byte("Exif\x00\x00")) {
+			return
+		}
+		if o := tiffOrientation(payload[6:]); o > 0 {
+			orientation = o
+		}
+	})
+	return orientation
+}
+
+// tiffOrientation reads tag 0x0112 from the first IFD of a TIFF header.
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 0
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for n := 0; n < count; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 0
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// StripPNGMetadata drops EXIF, text and timestamp chunks from a PNG.
+func StripPNGMetadata(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, errors.New("malformed PNG")
+	}
+	out := append([]byte{}, pngSignature...)
+	for i := len(pngSignature); i < len(data); {
+		if i+8 > len(data) {
+			return nil, errors.New("malformed PNG")
+		}
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		end := i + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, errors.New("malformed PNG")
+		}
+		switch string(data[i+4 : i+8]) {
+		case "eXIf", "tEXt", "zTXt", "iTXt", "tIME":
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	return out, nil
+}
+
+// StripWebPMetadata drops the EXIF and XMP chunks from a WebP and clears
+// their flags in the VP8X header, leaving the image data as it was.
+func StripWebPMetadata(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, errors.New("malformed WebP")
+	}
+	out := append([]byte{}, data[:12]...)
+	for i := 12; i < len(data); {
+		if i+8 > len(data) {
+			return nil, errors.New("malformed WebP")
+		}
+		length := int(binary.LittleEndian.Uint32(data[i+4:]))
+		end := i + 8 + length + length%2 // chunks are padded to even sizes
+		if length < 0 || end > len(data) {
+			return nil, errors.New("malformed WebP")
+		}
+		switch string(data[i : i+4]) {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte{}, data[i:end]...)
+			if length > 0 {
+				chunk[8] &^= 0x0C // EXIF and XMP present
+			}
+			out = append(out, chunk...)
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out, nil
+}
@@ -0,0 +1,205 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"strings"
+	"testing"
+
+	"go-backend/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// exifSegment builds an APP1 segment carrying a little-endian TIFF header
+// with a single orientation tag.
+func exifSegment(orientation uint16) []byte {
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+	entry := make([]byte, 2+12+4)
+	binary.LittleEndian.PutUint16(entry[0:], 1)      // one entry
+	binary.LittleEndian.PutUint16(entry[2:], 0x0112) // orientation
+	binary.LittleEndian.PutUint16(entry[4:], 3)      // SHORT
+	binary.LittleEndian.PutUint32(entry[6:], 1)
+	binary.LittleEndian.PutUint16(entry[10:], orientation)
+	payload := append([]byte("Exif\x00\x00"), append(tiff, entry...)...)
+
+	seg := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(payload)+2))
+	return append(seg, payload...)
+}
+
+func jpegWithExif(t *testing.T, w, h int, orientation uint16) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	var buf bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&buf, img, nil))
+	data := buf.Bytes()
+	return append(append([]byte{0xFF, 0xD8}, exifSegment(orientation)...), data[2:]...)
+}
+
+func TestExifOrientationAndStrip(t *testing.T) {
+	data := jpegWithExif(t, 4, 2, 6)
+	assert.Equal(t, 6, ExifOrientation(data))
+
+	clean, err := StripJPEGMetadata(data)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(clean), "Exif")
+	assert.Equal(t, 1, ExifOrientation(clean))
+
+	_, err = jpeg.Decode(bytes.NewReader(clean))
+	assert.NoError(t, err)
+}
+
+func TestStripPNGMetadata(t *testing.T) {
+	data := pngBytes(t, 2, 2)
+	// Insert a tEXt chunk right after IHDR (8 byte signature + 25 byte chunk).
+	text := []byte("\x00\x00\x00\x07tEXtGPS=1,2\x00\x00\x00\x00")
+	withText := append(append(append([]byte{}, data[:33]...), text...), data[33:]...)
+
+	clean, err := StripPNGMetadata(withText)
+	assert.NoError(t, err)
+	assert.Equal(t, data, clean)
+}
+
+func TestApplyOrientation(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	red := color.NRGBA{R: 255, A: 255}
+	img.SetNRGBA(0, 1, red) // bottom left
+
+	rotated := ApplyOrientation(img, 6).(*image.NRGBA)
+	assert.Equal(t, image.Rect(0, 0, 2, 3), rotated.Bounds())
+	assert.Equal(t, red, rotated.NRGBAAt(0, 0)) // bottom left ends up top left
+
+	assert.Same(t, image.Image(img), ApplyOrientation(img, 1))
+}
+
+func TestResizeToFit(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 400, 200))
+	assert.Equal(t, image.Rect(0, 0, 100, 50), ResizeToFit(img, 100, 100).Bounds())
+	assert.Equal(t, image.Rect(0, 0, 400, 200), ResizeToFit(img, 1000, 1000).Bounds())
+}
+
+func TestParseImageVariants(t *testing.T) {
+	v, err := ParseImageVariants("thumb=100x80, large=1200x1200")
+	assert.NoError(t, err)
+	assert.Equal(t, []ImageVariant{{"thumb", 100, 80}, {"large", 1200, 1200}}, v)
+
+	_, err = ParseImageVariants("thumb=100")
+	assert.Error(t, err)
+	_, err = ParseImageVariants("")
+	assert.Error(t, err)
+}
+
+func TestProcessImage(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryBlobStore()
+	data := jpegWithExif(t, 400, 200, 6)
+
+	media := models.Media{ID: primitive.NewObjectID(), OwnerID: primitive.NewObjectID(), ContentType: "image/jpeg", Status: models.MediaStatusPending}
+	media.Key = media.OwnerID.Hex() + "/" + media.ID.Hex() + ".jpg"
+	assert.NoError(t, store.Put(ctx, media.Key, bytes.NewReader(data), int64(len(data)), media.ContentType))
+
+	variants := []ImageVariant{{"thumb", 50, 50}, {"large", 1000, 1000}}
+	assert.NoError(t, ProcessImage(ctx, store, &media, variants))
+
+	assert.Equal(t, models.MediaStatusReady, media.Status)
+	assert.Equal(t, 200, media.Width) // rotated upright
+	assert.Equal(t, 400, media.Height)
+	assert.NotEmpty(t, media.Blurhash)
+	assert.True(t, strings.HasPrefix(media.LQIP, "data:image/jpeg;base64,"))
+
+	assert.Len(t, media.Variants, 2)
+	assert.Equal(t, "thumb", media.Variants[0].Name)
+	assert.Equal(t, 25, media.Variants[0].Width)
+	assert.Equal(t, 50, media.Variants[0].Height)
+	assert.Equal(t, 400, media.Variants[1].Height) // never upscaled
+
+	blob, err := store.Open(ctx, media.Variants[0].Key)
+	assert.NoError(t, err)
+	head, _ := io.ReadAll(blob)
+	contentType, err := SniffMediaType(head)
+	assert.NoError(t, err)
+	assert.Equal(t, "image/webp", contentType)
+
+	blob, _ = store.Open(ctx, media.Key)
+	original, _ := io.ReadAll(blob)
+	assert.Equal(t, 1, ExifOrientation(original))
+	assert.NotContains(t, string(original), "Exif")
+}
+
+func TestProcessImage_PixelLimit(t *testing.T) {
+	t.Setenv("MEDIA_MAX_PIXELS", "10000")
+	ctx := context.Background()
+	store := NewMemoryBlobStore()
+	data := jpegWithExif(t, 400, 200, 1)
+
+	media := models.Media{ID: primitive.NewObjectID(), OwnerID: primitive.NewObjectID(), ContentType: "image/jpeg", Status: models.MediaStatusPending}
+	media.Key = media.OwnerID.Hex() + "/" + media.ID.Hex() + ".jpg"
+	assert.NoError(t, store.Put(ctx, media.Key, bytes.NewReader(data), int64(len(data)), media.ContentType))
+
+	err := ProcessImage(ctx, store, &media, DefaultImageVariants)
+	assert.ErrorIs(t, err, ErrImageTooLarge)
+	assert.Empty(t, media.Variants)
+}
+
+func TestProcessImage_KeepsSmallerOriginal(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryBlobStore()
+
+	// Noise compresses badly losslessly, so a full size WebP of a small JPEG
+	// of it is larger than the JPEG.
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 7919 % 251)
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: 30}))
+	data := buf.Bytes()
+
+	media := models.Media{ID: primitive.NewObjectID(), OwnerID: primitive.NewObjectID(), ContentType: "image/jpeg"}
+	media.Key = media.OwnerID.Hex() + "/" + media.ID.Hex() + ".jpg"
+	assert.NoError(t, store.Put(ctx, media.Key, bytes.NewReader(data), int64(len(data)), media.ContentType))
+
+	assert.NoError(t, ProcessImage(ctx, store, &media, []ImageVariant{{"large", 1000, 1000}}))
+	assert.Len(t, media.Variants, 1)
+	assert.Equal(t, media.Key, media.Variants[0].Key)
+	assert.Equal(t, "image/jpeg", media.Variants[0].ContentType)
+	assert.Equal(t, media.Size, media.Variants[0].Size)
+	assert.Equal(t, []string{media.Key}, store.Keys(), "no WebP is stored")
+}
+
+func TestStripWebPMetadata(t *testing.T) {
+	chunk := func(fourCC string, payload []byte) []byte {
+		c := append([]byte(fourCC), 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(c[4:], uint32(len(payload)))
+		c = append(c, payload...)
+		if len(payload)%2 == 1 {
+			c = append(c, 0)
+		}
+		return c
+	}
+	var body []byte
+	body = append(body, chunk("VP8X", []byte{0x0C, 0, 0, 0, 0, 0, 0, 0, 0, 0})...)
+	body = append(body, chunk("VP8L", []byte{1, 2, 3})...)
+	body = append(body, chunk("EXIF", []byte("Exif data"))...)
+	body = append(body, chunk("XMP ", []byte("<x/>"))...)
+	data := append([]byte("RIFF\x00\x00\x00\x00WEBP"), body...)
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(data)-8))
+
+	clean, err := StripWebPMetadata(data)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(clean), "EXIF")
+	assert.NotContains(t, string(clean), "XMP ")
+	assert.Equal(t, byte(0), clean[20], "metadata flags cleared")
+	assert.Contains(t, string(clean), "VP8L")
+	assert.Equal(t, uint32(len(clean)-8), binary.LittleEndian.Uint32(clean[4:]))
+
+	_, err = StripWebPMetadata([]byte("RIFF\x00\x00\x00\x00WAVE"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,138 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"time"
+
+	"go-backend/config"
+	"go-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	LeaderboardCacheKey   = "leaderboard:authors"
+	leaderboardWindowDays = 90
+	leaderboardMinTrades  = 5
+	leaderboardSize       = 50
+	leaderboardTimeout    = time.Minute
+	leaderboardLockKey    = "lock:leaderboard"
+)
+
+// ClosedTradesFilter matches published, non-private trade posts closed
+// within [from, to]. Either bound may be nil.
+func ClosedTradesFilter(from, to *time.Time) bson.M {
+	filter := bson.M{
+		"type":         models.PostTypeTrade,
+		"status":       "published",
+		"visibility":   bson.M{"$in": []models.Visibility{models.VisibilityPublic, models.VisibilityPremium}},
+		"trade.status": models.TradeStatusClosed,
+	}
+
+	closedAt := bson.M{}
+	if from != nil {
+		closedAt["$gte"] = *from
+	}
+	if to != nil {
+		closedAt["$lte"] = *to
+	}
+	if len(closedAt) > 0 {
+		filter["trade.closed_at"] = closedAt
+	}
+
+	return filter
+}
+
+// StartLeaderboardJob builds the author leaderboard on startup and then every
+// night at midnight UTC, caching the result in Redis, on one replica at a
+// time. It stops once ctx is done; the returned channel is closed after any
+// refresh in progress.
+func StartLeaderboardJob(ctx context.Context, db *mongo.Database, lock Locker) <-chan struct{} {
+	untilMidnight := func(now time.Time) time.Duration { return nextMidnightUTC(now).Sub(now) }
+	return runJob(ctx, immediately(untilMidnight), func() {
+		ctx, cancel := context.WithTimeout(context.Background(), leaderboardTimeout)
+		defer cancel()
+		WithLock(ctx, lock, leaderboardLockKey, leaderboardTimeout, func() { refreshLeaderboard(ctx, db) })
+	})
+}
+
+func nextMidnightUTC(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+}
+
+func refreshLeaderboard(ctx context.Context, db *mongo.Database) {
+	now := time.Now()
+	from := now.AddDate(0, 0, -leaderboardWindowDays)
+
+	cursor, err := db.Collection("posts").Find(ctx, ClosedTradesFilter(&from, &now))
+	if err != nil {
+		log.Printf("Failed to load trades for leaderboard: %v", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var posts []models.Post
+	if err := cursor.All(ctx, &posts); err != nil {
+		log.Printf("Failed to decode trades for leaderboard: %v", err)
+		return
+	}
+
+	board := BuildLeaderboard(posts)
+	board.GeneratedAt = now
+	board.WindowDays = leaderboardWindowDays
+
+	data, err := json.Marshal(board)
+	if err != nil {
+		log.Printf("Failed to encode leaderboard: %v", err)
+		return
+	}
+
+	// Keep it around for two nights so a failed run still serves the last board.
+	if err := config.Cache.Set(ctx, LeaderboardCacheKey, data, 48*time.Hour).Err(); err != nil {
+		log.Printf("Failed to cache leaderboard: %v", err)
+		return
+	}
+
+	log.Printf("✅ Leaderboard refreshed with %d author(s)", len(board.Entries))
+}
+
+// BuildLeaderboard ranks authors by expectancy, ignoring authors with too few
+// closed trades for the numbers to mean anything.
+func BuildLeaderboard(posts []models.Post) models.Leaderboard {
+	byAuthor := map[primitive.ObjectID][]models.Post{}
+	for _, p := range posts {
+		byAuthor[p.AuthorID] = append(byAuthor[p.AuthorID], p)
+	}
+
+	entries := []models.LeaderboardEntry{}
+	for authorID, authorPosts := range byAuthor {
+		perf := ComputePerformance(authorPosts)
+		if perf.Trades < leaderboardMinTrades {
+			continue
+		}
+		perf.AuthorID = authorID
+		entries = append(entries, models.LeaderboardEntry{AuthorPerformance: perf})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Expectancy != entries[j].Expectancy {
+			return entries[i].Expectancy > entries[j].Expectancy
+		}
+		return entries[i].Trades > entries[j].Trades
+	})
+
+	if len(entries) > leaderboardSize {
+		entries = entries[:leaderboardSize]
+	}
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+
+	return models.Leaderboard{Entries: entries}
+}
@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Locker hands out exclusive, expiring locks so that only one replica runs a
+// background job at a time.
+type Locker interface {
+	// Acquire returns ok=false when another holder has the lock. release is
+	// only non-nil when ok is true.
+	Acquire(ctx context.Context, key string, ttl time.Duration) (release func(), ok bool, err error)
+}
+
+// RedisLock implements Locker with SET NX PX and a token checked on release,
+// so a holder whose lock expired cannot delete someone else's.
+type RedisLock struct {
+	Client *redis.Client
+}
+
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+var renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+func (l *RedisLock) Acquire(ctx context.Context, key string, ttl time.Duration) (func(), bool, error) {
+	token, ok, err := l.acquire(ctx, key, ttl)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return func() { l.release(key, token) }, true, nil
+}
+
+// Hold takes key like Acquire and renews it every third of ttl until it is
+// released, so a holder whose work takes longer than ttl keeps the lock. ttl
+// then only bounds how long a crashed holder blocks everyone else.
+func (l *RedisLock) Hold(ctx context.Context, key string, ttl time.Duration) (func(), bool, error) {
+	token, ok, err := l.acquire(ctx, key, ttl)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			renewScript.Run(ctx, l.Client, []string{key}, token, ttl.Milliseconds())
+			cancel()
+		}
+	}()
+
+	release := func() {
+		close(stop)
+		<-stopped
+		l.release(key, token)
+	}
+	return release, true, nil
+}
+
+func (l *RedisLock) acquire(ctx context.Context, key string, ttl time.Duration) (string, bool, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", false, err
+	}
+	token := hex.EncodeToString(buf)
+
+	ok, err := l.Client.SetNX(ctx, key, token, ttl).Result()
+	return token, ok, err
+}
+
+func (l *RedisLock) release(key, token string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	releaseScript.Run(ctx, l.Client, []string{key}, token)
+}
+
+// Holder is implemented by lockers that can keep a lock alive for as long as
+// its holder runs, see RedisLock.Hold.
+type Holder interface {
+	Hold(ctx context.Context, key string, ttl time.Duration) (release func(), ok bool, err error)
+}
+
+// HoldLock takes key with lock, renewed until released when lock is a
+// Holder and expiring after ttl otherwise.
+func HoldLock(ctx context.Context, lock Locker, key string, ttl time.Duration) (func(), bool, error) {
+	if h, ok := lock.(Holder); ok {
+		return h.Hold(ctx, key, ttl)
+	}
+	return lock.Acquire(ctx, key, ttl)
+}
+
+// WithLock runs fn while holding key, or skips it when another holder has
+// the lock. A nil lock runs fn unconditionally.
+func WithLock(ctx context.Context, lock Locker, key string, ttl time.Duration, fn func()) error {
+	if lock == nil {
+		fn()
+		return nil
+	}
+	release, ok, err := lock.Acquire(ctx, key, ttl)
+	if err != nil || !ok {
+		return err
+	}
+	defer release()
+	fn()
+	return nil
+}
@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"html"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go-backend/config"
+	"go-backend/models"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+const (
+	excerptLength  = 280 // runes
+	wordsPerMinute = 200
+	renderCacheTTL = 7 * 24 * time.Hour
+	// Bump the version when rendering or sanitizing rules change.
+	renderCachePrefix = "post:render:v1:"
+)
+
+// markdown renders GitHub flavoured markdown. Raw HTML and dangerous link
+// schemes are dropped because the renderer is not created WithUnsafe.
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// htmlPolicy is the allowlist applied to everything markdown produces, as a
+// second line of defence against XSS.
+var htmlPolicy = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.RequireNoFollowOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	p.AllowAttrs("class").Matching(bluemonday.SpaceSeparatedTokens).OnElements("code")
+	return p
+}()
+
+var textPolicy = bluemonday.StrictPolicy()
+
+// RenderMarkdown converts post markdown into sanitized HTML.
+func RenderMarkdown(src string) (string, error) {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(src), &buf); err != nil {
+		return "", err
+	}
+	return htmlPolicy.Sanitize(buf.String()), nil
+}
+
+// PlainText strips all markup from rendered HTML and collapses whitespace.
+func PlainText(renderedHTML string) string {
+	text := html.UnescapeString(textPolicy.Sanitize(renderedHTML))
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// Excerpt shortens text to at most max runes, cutting at a word boundary.
+func Excerpt(text string, max int) string {
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	runes := []rune(text)
+	cut := string(runes[:max])
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:") + "…"
+}
+
+// ReadingTime estimates minutes to read a number of words, at least one.
+func ReadingTime(words int) int {
+	if words == 0 {
+		return 0
+	}
+	return int(math.Max(1, math.Ceil(float64(words)/wordsPerMinute)))
+}
+
+// RenderContent produces the HTML, excerpt and reading stats for markdown.
+func RenderContent(src string) (models.RenderedContent, error) {
+	contentHTML, err := RenderMarkdown(src)
+	if err != nil {
+		return models.RenderedContent{}, err
+	}
+
+	text := PlainText(contentHTML)
+	words := len(strings.Fields(text))
+
+	return models.RenderedContent{
+		ContentHTML: contentHTML,
+		Excerpt:     Excerpt(text, excerptLength),
+		WordCount:   words,
+		ReadingTime: ReadingTime(words),
+	}, nil
+}
+
+// CachedRenderContent is RenderContent backed by Redis. Entries are keyed by
+// a hash of the markdown, so editing a post naturally misses the cache.
+func CachedRenderContent(ctx context.Context, src string) (models.RenderedContent, error) {
+	sum := sha256.Sum256([]byte(src))
+	key := renderCachePrefix + hex.EncodeToString(sum[:])
+
+	if config.Cache != nil {
+		if data, err := config.Cache.Get(ctx, key).Bytes(); err == nil {
+			var rc models.RenderedContent
+			if json.Unmarshal(data, &rc) == nil {
+				return rc, nil
+			}
+		}
+	}
+
+	rc, err := RenderContent(src)
+	if err != nil {
+		return rc, err
+	}
+
+	if config.Cache != nil {
+		if data, err := json.Marshal(rc); err == nil {
+			config.Cache.Set(ctx, key, data, renderCacheTTL)
+		}
+	}
+	return rc, nil
+}
+
+// RenderPosts fills in the rendered content of each post.
+func RenderPosts(ctx context.Context, posts []models.Post) error {
+	for i := range posts {
+		rc, err := CachedRenderContent(ctx, posts[i].Content)
+		if err != nil {
+			return err
+		}
+		posts[i].RenderedContent = rc
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderMarkdown_StripsScripts(t *testing.T) {
+	cases := []string{
+		"<script>alert(1)</script>",
+		"[click](javascript:alert(1))",
+		`<img src=x onerror="alert(1)">`,
+		`<a href="#" onclick="alert(1)">x</a>`,
+	}
+
+	for _, src := range cases {
+		out, err := RenderMarkdown(src)
+		assert.NoError(t, err)
+		lower := strings.ToLower(out)
+		assert.NotContains(t, lower, "<script", src)
+		assert.NotContains(t, lower, "javascript:", src)
+		assert.NotContains(t, lower, "onerror", src)
+		assert.NotContains(t, lower, "onclick", src)
+	}
+}
+
+func TestRenderMarkdown_KeepsFormatting(t *testing.T) {
+	out, err := RenderMarkdown("# Setup\n\n**Long** [chart](https://example.com/c.png)\n\n```go\nx := 1\n```")
+	assert.NoError(t, err)
+
+	assert.Contains(t, out, "<h1>Setup</h1>")
+	assert.Contains(t, out, "<strong>Long</strong>")
+	assert.Contains(t, out, `href="https://example.com/c.png"`)
+	assert.Contains(t, out, `rel="nofollow noopener"`)
+	assert.Contains(t, out, `class="language-go"`)
+}
+
+func TestRenderContent(t *testing.T) {
+	src := "# Title\n\n" + strings.Repeat("word ", 450)
+	rc, err := RenderContent(src)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 451, rc.WordCount)
+	assert.Equal(t, 3, rc.ReadingTime)
+	assert.True(t, strings.HasPrefix(rc.Excerpt, "Title word word"))
+	assert.True(t, strings.HasSuffix(rc.Excerpt, "…"))
+	assert.LessOrEqual(t, len([]rune(rc.Excerpt)), excerptLength+1)
+}
+
+func TestExcerpt_ShortTextUnchanged(t *testing.T) {
+	assert.Equal(t, "Buy the dip", Excerpt("Buy the dip", 20))
+	assert.Equal(t, "Buy the…", Excerpt("Buy the dip, then sell", 10))
+}
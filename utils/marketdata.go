@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bar is a single OHLC price bar.
+type Bar struct {
+	Time  time.Time
+	Open  float64
+	High  float64
+	Low   float64
+	Close float64
+}
+
+// MarketDataProvider supplies price bars used to resolve open trade posts.
+type MarketDataProvider interface {
+	// Bars returns the bars for instrument with from < Time <= to, oldest first.
+	Bars(ctx context.Context, instrument string, from, to time.Time) ([]Bar, error)
+}
+
+// ErrUnknownInstrument is returned when a provider has no data for an instrument.
+var ErrUnknownInstrument = errors.New("unknown instrument")
+
+// CSVMarketData reads bars from <dir>/<INSTRUMENT>.csv files with a header row
+// and the columns time,open,high,low,close. Time may be RFC3339 or unix seconds.
+// Files are re-read on every call, so they can be edited while the server runs.
+type CSVMarketData struct {
+	Dir string
+}
+
+func NewCSVMarketData(dir string) *CSVMarketData {
+	return &CSVMarketData{Dir: dir}
+}
+
+func (p *CSVMarketData) Bars(ctx context.Context, instrument string, from, to time.Time) ([]Bar, error) {
+	name := strings.ToUpper(filepath.Base(instrument)) + ".csv"
+	f, err := os.Open(filepath.Join(p.Dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrUnknownInstrument
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	all, err := ParseBarsCSV(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	bars := []Bar{}
+	for _, b := range all {
+		if b.Time.After(from) && !b.Time.After(to) {
+			bars = append(bars, b)
+		}
+	}
+	return bars, nil
+}
+
+// ParseBarsCSV parses time,open,high,low,close rows after a header row and
+// returns them sorted by time.
+func ParseBarsCSV(r io.Reader) ([]Bar, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	bars := make([]Bar, 0, len(records))
+	for i, rec := range records {
+		if i == 0 {
+			continue // header
+		}
+		if len(rec) < 5 {
+			return nil, fmt.Errorf("line %d: expected at least 5 columns", i+1)
+		}
+
+		ts, err := parseBarTime(rec[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+
+		var prices [4]float64
+		for j := range prices {
+			prices[j], err = strconv.ParseFloat(strings.TrimSpace(rec[j+1]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+		}
+
+		bars = append(bars, Bar{Time: ts, Open: prices[0], High: prices[1], Low: prices[2], Close: prices[3]})
+	}
+
+	sort.Slice(bars, func(i, j int) bool { return bars[i].Time.Before(bars[j].Time) })
+	return bars, nil
+}
+
+func parseBarTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(secs, 0).UTC(), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
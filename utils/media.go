@@ -0,0 +1,275 @@
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"go-backend/config"
+	"go-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrMediaTooLarge    = errors.New("file exceeds the upload limit for your role")
+	ErrMediaType        = errors.New("file type is not allowed")
+	ErrMediaEmpty       = errors.New("file is empty")
+	ErrMediaUnreadable  = errors.New("image could not be decoded")
+	ErrImageTooLarge    = errors.New("image has too many pixels")
+	ErrUploadNotAllowed = errors.New("your role may not upload media")
+)
+
+// MediaSizeLimits caps upload size per role, in bytes.
+var MediaSizeLimits = map[string]int64{
+	"user":    2 << 20,
+	"premium": 10 << 20,
+	"author":  25 << 20,
+	"admin":   100 << 20,
+}
+
+// MediaSizeLimit returns the upload cap for role, or 0 if it may not upload.
+func MediaSizeLimit(role string) int64 {
+	return MediaSizeLimits[strings.ToLower(role)]
+}
+
+// defaultMaxImagePixels keeps a small file that decodes to a huge canvas from
+// exhausting memory; 50 megapixels is well above any camera we expect.
+const defaultMaxImagePixels = 50_000_000
+
+// MaxImagePixels is the largest width*height accepted for an image. Set
+// MEDIA_MAX_PIXELS to change it.
+func MaxImagePixels() int64 {
+	if n, err := strconv.ParseInt(os.Getenv("MEDIA_MAX_PIXELS"), 10, 64); err == nil && n > 0 {
+		return n
+	}
+	return defaultMaxImagePixels
+}
+
+// checkImagePixels returns ErrImageTooLarge when an image of cfg's size
+// exceeds MaxImagePixels, before any of it is decoded.
+func checkImagePixels(cfg image.Config) error {
+	if int64(cfg.Width)*int64(cfg.Height) > MaxImagePixels() {
+		return ErrImageTooLarge
+	}
+	return nil
+}
+
+// mediaExtensions maps the accepted sniffed content types to the extension
+// used in blob keys. The client's file name is never used for either.
+var mediaExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"video/mp4":       ".mp4",
+	"application/pdf": ".pdf",
+}
+
+// SniffMediaType detects the content type from the first bytes of a file and
+// returns ErrMediaType unless it is one we accept.
+func SniffMediaType(head []byte) (string, error) {
+	contentType := http.DetectContentType(head)
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	if _, ok := mediaExtensions[contentType]; !ok {
+		return "", ErrMediaType
+	}
+	return contentType, nil
+}
+
+// SpooledUpload is an upload buffered to a temporary file and inspected, but
+// not stored yet.
+type SpooledUpload struct {
+	File        *os.File
+	Size        int64
+	SHA256      string
+	ContentType string
+	Width       int
+	Height      int
+}
+
+// SpoolUpload copies at most limit bytes of r to a temporary file, hashing it
+// on the way, then sniffs its type and reads image dimensions, refusing images
+// over MaxImagePixels. Callers must Close the result.
+func SpoolUpload(r io.Reader, limit int64) (*SpooledUpload, error) {
+	f, err := os.CreateTemp("", "media-*")
+	if err != nil {
+		return nil, err
+	}
+	u := &SpooledUpload{File: f}
+
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, hash), io.LimitReader(r, limit+1))
+	if err != nil {
+		u.Close()
+		return nil, err
+	}
+	switch {
+	case n > limit:
+		u.Close()
+		return nil, ErrMediaTooLarge
+	case n == 0:
+		u.Close()
+		return nil, ErrMediaEmpty
+	}
+	u.Size = n
+	u.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	head := make([]byte, 512)
+	read, _ := f.ReadAt(head, 0)
+	if u.ContentType, err = SniffMediaType(head[:read]); err != nil {
+		u.Close()
+		return nil, err
+	}
+
+	if strings.HasPrefix(u.ContentType, "image/") {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			u.Close()
+			return nil, err
+		}
+		cfg, _, err := image.DecodeConfig(f)
+		if err != nil {
+			u.Close()
+			return nil, ErrMediaUnreadable
+		}
+		if err := checkImagePixels(cfg); err != nil {
+			u.Close()
+			return nil, err
+		}
+		u.Width, u.Height = cfg.Width, cfg.Height
+	}
+	return u, nil
+}
+
+// Store writes the upload to store as the blob of media and fills in the
+// fields derived from the file.
+func (u *SpooledUpload) Store(ctx context.Context, store BlobStore, media *models.Media) error {
+	if media.ID.IsZero() {
+		media.ID = primitive.NewObjectID()
+	}
+	media.Key = media.OwnerID.Hex() + "/" + media.ID.Hex() + mediaExtensions[u.ContentType]
+	media.URL = store.URL(media.Key)
+	media.ContentType = u.ContentType
+	media.Size = u.Size
+	media.SHA256 = u.SHA256
+	media.Width, media.Height = u.Width, u.Height
+
+	if _, err := u.File.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return store.Put(ctx, media.Key, u.File, u.Size, u.ContentType)
+}
+
+// Close removes the temporary file.
+func (u *SpooledUpload) Close() error {
+	u.File.Close()
+	return os.Remove(u.File.Name())
+}
+
+// AttachMedia fills in Post.Media with the uploads each post's MediaURLs
+// point at, matching either the original or a variant URL. External URLs
+// are left alone.
+func AttachMedia(ctx context.Context, posts []models.Post) error {
+	if config.MediaCollection == nil {
+		return nil
+	}
+
+	var urls []string
+	for _, p := range posts {
+		urls = append(urls, p.MediaURLs...)
+	}
+	if len(urls) == 0 {
+		return nil
+	}
+
+	cursor, err := config.MediaCollection.Find(ctx, bson.M{"$or": []bson.M{
+		{"url": bson.M{"$in": urls}},
+		{"variants.url": bson.M{"$in": urls}},
+	}})
+	if err != nil {
+		return err
+	}
+	var found []models.Media
+	if err := cursor.All(ctx, &found); err != nil {
+		return err
+	}
+
+	byURL := map[string]models.Media{}
+	for _, m := range found {
+		byURL[m.URL] = m
+		for _, v := range m.Variants {
+			byURL[v.URL] = m
+		}
+	}
+	for i := range posts {
+		posts[i].Media = nil
+		seen := map[primitive.ObjectID]bool{}
+		for _, u := range posts[i].MediaURLs {
+			if m, ok := byURL[u]; ok && !seen[m.ID] {
+				seen[m.ID] = true
+				posts[i].Media = append(posts[i].Media, m)
+			}
+		}
+	}
+	return nil
+}
+
+// ForeignMediaURLs returns the links in urls that point at our media but not
+// at an upload of ownerID, original or variant. External URLs are not ours
+// to check and are left out.
+func ForeignMediaURLs(ctx context.Context, ownerID primitive.ObjectID, urls []string) ([]string, error) {
+	prefix := mediaURL("")
+	var ours []string
+	for _, u := range urls {
+		if strings.HasPrefix(u, prefix) {
+			ours = append(ours, u)
+		}
+	}
+	if len(ours) == 0 {
+		return nil, nil
+	}
+
+	cursor, err := config.MediaCollection.Find(ctx, bson.M{
+		"owner_id": ownerID,
+		"$or": []bson.M{
+			{"url": bson.M{"$in": ours}},
+			{"variants.url": bson.M{"$in": ours}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var owned []models.Media
+	if err := cursor.All(ctx, &owned); err != nil {
+		return nil, err
+	}
+
+	mine := map[string]bool{}
+	for _, m := range owned {
+		mine[m.URL] = true
+		for _, v := range m.Variants {
+			mine[v.URL] = true
+		}
+	}
+	var foreign []string
+	for _, u := range ours {
+		if !mine[u] {
+			foreign = append(foreign, u)
+		}
+	}
+	return foreign, nil
+}
@@ -0,0 +1,275 @@
+package utils
+
+import (
+	"context"
+	"expvar"
+	"log"
+	"os"
+	"time"
+
+	"go-backend/config"
+	"go-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	mediaGCLockKey     = "lock:media-gc"
+	mediaGCInterval    = time.Hour
+	mediaGCGrace       = 7 * 24 * time.Hour
+	mediaGCReportLimit = 1000
+)
+
+// mediaGCStats is published under "media_gc" on the metrics endpoint.
+var mediaGCStats = expvar.NewMap("media_gc")
+
+// SyncMediaRefs records that postID references exactly the media behind
+// urls. Media that loses its last reference is marked unreferenced, which
+// starts its grace period before garbage collection.
+func SyncMediaRefs(ctx context.Context, postID primitive.ObjectID, urls []string) error {
+	if config.MediaCollection == nil {
+		return nil
+	}
+	now := time.Now()
+
+	matches := bson.M{"$or": []bson.M{
+		{"url": bson.M{"$in": urls}},
+		{"variants.url": bson.M{"$in": urls}},
+	}}
+	if len(urls) == 0 {
+		matches = bson.M{"_id": bson.M{"$exists": false}}
+	}
+
+	_, err := config.MediaCollection.UpdateMany(ctx,
+		bson.M{"post_ids": postID, "$nor": []bson.M{matches}},
+		bson.M{"$pull": bson.M{"post_ids": postID}})
+	if err != nil {
+		return err
+	}
+
+	if len(urls) > 0 {
+		_, err = config.MediaCollection.UpdateMany(ctx, matches, bson.M{
+			"$addToSet": bson.M{"post_ids": postID},
+			"$unset":    bson.M{"unreferenced_at": ""},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = config.MediaCollection.UpdateMany(ctx,
+		bson.M{"post_ids": bson.M{"$size": 0}, "unreferenced_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"unreferenced_at": now}})
+	return err
+}
+
+// MediaGCItem is one upload the collector would delete.
+type MediaGCItem struct {
+	ID             primitive.ObjectID `json:"id"`
+	OwnerID        primitive.ObjectID `json:"owner_id"`
+	Key            string             `json:"key"`
+	Bytes          int64              `json:"bytes"` // original plus variants
+	UnreferencedAt time.Time          `json:"unreferenced_at"`
+}
+
+type MediaGCReport struct {
+	DryRun     bool          `json:"dry_run"`
+	Cutoff     time.Time     `json:"cutoff"`
+	Count      int           `json:"count"`
+	Bytes      int64         `json:"bytes"`
+	Items      []MediaGCItem `json:"items"`
+	Rescued    int           `json:"rescued"` // still referenced, tracking repaired
+	Incomplete bool          `json:"incomplete,omitempty"`
+}
+
+func mediaBytes(m models.Media) int64 {
+	total := m.Size
+	for _, v := range m.Variants {
+		if v.Key != m.Key { // variants that are the original
+			total += v.Size
+		}
+	}
+	return total
+}
+
+// MediaGC deletes uploads no post has referenced for longer than Grace.
+type MediaGC struct {
+	Media *mongo.Collection
+	Posts *mongo.Collection
+	Store BlobStore
+	Grace time.Duration
+	Lock  Locker
+	Now   func() time.Time
+}
+
+// MediaCollector is the process wide collector, configured in main.
+var MediaCollector *MediaGC
+
+func NewMediaGC(db *mongo.Database, store BlobStore) *MediaGC {
+	gc := &MediaGC{
+		Media: db.Collection("media"),
+		Posts: db.Collection("posts"),
+		Store: store,
+		Grace: mediaGCGrace,
+		Now:   time.Now,
+	}
+	if d, err := time.ParseDuration(os.Getenv("MEDIA_GC_GRACE")); err == nil && d > 0 {
+		gc.Grace = d
+	}
+	if config.Cache != nil {
+		gc.Lock = &RedisLock{Client: config.Cache}
+	}
+	return gc
+}
+
+// Run finds unreferenced media past the grace period and, unless dryRun,
+// deletes it. Before deleting, posts are checked directly so drifted
+// reference tracking never costs a file that is still in use.
+func (g *MediaGC) Run(ctx context.Context, dryRun bool) (MediaGCReport, error) {
+	cutoff := g.Now().Add(-g.Grace)
+	report := MediaGCReport{DryRun: dryRun, Cutoff: cutoff, Items: []MediaGCItem{}}
+
+	cursor, err := g.Media.Find(ctx, bson.M{
+		"unreferenced_at": bson.M{"$lte": cutoff},
+		"$or": []bson.M{
+			{"post_ids": bson.M{"$exists": false}},
+			{"post_ids": bson.M{"$size": 0}},
+		},
+	}, options.Find().SetSort(bson.D{{Key: "unreferenced_at", Value: 1}}).SetLimit(mediaGCReportLimit+1))
+	if err != nil {
+		return report, err
+	}
+	var candidates []models.Media
+	if err := cursor.All(ctx, &candidates); err != nil {
+		return report, err
+	}
+	if len(candidates) > mediaGCReportLimit {
+		candidates = candidates[:mediaGCReportLimit]
+		report.Incomplete = true
+	}
+
+	for _, m := range candidates {
+		referencing, err := g.referencingPosts(ctx, m)
+		if err != nil {
+			return report, err
+		}
+		if len(referencing) > 0 {
+			report.Rescued++
+			if !dryRun {
+				g.Media.UpdateOne(ctx, bson.M{"_id": m.ID}, bson.M{
+					"$addToSet": bson.M{"post_ids": bson.M{"$each": referencing}},
+					"$unset":    bson.M{"unreferenced_at": ""},
+				})
+			}
+			continue
+		}
+
+		if !dryRun {
+			deleted, err := g.delete(ctx, m)
+			if err != nil {
+				log.Printf("Media GC could not delete %s: %v", m.ID.Hex(), err)
+				mediaGCStats.Add("errors", 1)
+				continue
+			}
+			if !deleted {
+				continue // picked up by a post in the meantime
+			}
+			mediaGCStats.Add("deleted", 1)
+			mediaGCStats.Add("reclaimed_bytes", mediaBytes(m))
+		}
+
+		report.Count++
+		report.Bytes += mediaBytes(m)
+		report.Items = append(report.Items, MediaGCItem{
+			ID:             m.ID,
+			OwnerID:        m.OwnerID,
+			Key:            m.Key,
+			Bytes:          mediaBytes(m),
+			UnreferencedAt: *m.UnreferencedAt,
+		})
+	}
+	return report, nil
+}
+
+func (g *MediaGC) referencingPosts(ctx context.Context, m models.Media) ([]primitive.ObjectID, error) {
+	urls := []string{m.URL}
+	for _, v := range m.Variants {
+		urls = append(urls, v.URL)
+	}
+	cursor, err := g.Posts.Find(ctx, bson.M{"media_urls": bson.M{"$in": urls}},
+		options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var posts []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &posts); err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, 0, len(posts))
+	for _, p := range posts {
+		ids = append(ids, p.ID)
+	}
+	return ids, nil
+}
+
+// delete removes the record first (guarded on it still being unreferenced)
+// and then the blobs, so a concurrent attach never points at missing files.
+func (g *MediaGC) delete(ctx context.Context, m models.Media) (bool, error) {
+	result, err := g.Media.DeleteOne(ctx, bson.M{
+		"_id":             m.ID,
+		"unreferenced_at": bson.M{"$exists": true},
+		"$or": []bson.M{
+			{"post_ids": bson.M{"$exists": false}},
+			{"post_ids": bson.M{"$size": 0}},
+		},
+	})
+	if err != nil || result.DeletedCount == 0 {
+		return false, err
+	}
+	DeleteMediaBlobs(ctx, g.Store, m)
+	return true, nil
+}
+
+// DeleteMediaBlobs removes the original and every variant of m, logging
+// failures.
+func DeleteMediaBlobs(ctx context.Context, store BlobStore, m models.Media) {
+	keys := []string{m.Key}
+	for _, v := range m.Variants {
+		if v.Key != m.Key {
+			keys = append(keys, v.Key)
+		}
+	}
+	for _, key := range keys {
+		if err := store.Delete(ctx, key); err != nil {
+			log.Printf("Failed to delete media blob %s: %v", key, err)
+		}
+	}
+}
+
+// StartMediaGCJob sweeps unreferenced media every hour on one replica until
+// ctx is done. The returned channel is closed after any sweep in progress.
+func StartMediaGCJob(ctx context.Context, gc *MediaGC) <-chan struct{} {
+	return runJob(ctx, every(mediaGCInterval), func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mediaGCInterval)
+		defer cancel()
+		WithLock(ctx, gc.Lock, mediaGCLockKey, mediaGCInterval, func() { runMediaGC(ctx, gc) })
+	})
+}
+
+func runMediaGC(ctx context.Context, gc *MediaGC) {
+	mediaGCStats.Add("runs", 1)
+	report, err := gc.Run(ctx, false)
+	if err != nil {
+		log.Printf("Media GC failed: %v", err)
+		mediaGCStats.Add("errors", 1)
+		return
+	}
+	if report.Count > 0 {
+		log.Printf("✅ Media GC deleted %d file(s), reclaimed %d bytes", report.Count, report.Bytes)
+	}
+}
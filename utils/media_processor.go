@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"go-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	mediaProcessLease    = 5 * time.Minute
+	mediaProcessAttempts = 3
+	mediaSweepInterval   = time.Minute
+)
+
+// MediaProcessor runs the image pipeline on uploaded images in the
+// background. Uploads are queued as they arrive; a periodic sweep picks up
+// anything left pending, e.g. after a restart or a failed attempt. A lease on
+// the media record keeps replicas from processing the same upload twice.
+type MediaProcessor struct {
+	Media    *mongo.Collection
+	Store    BlobStore
+	Variants []ImageVariant
+	Workers  int
+
+	queue chan primitive.ObjectID
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// MediaJobs is the process wide processor, started in main.
+var MediaJobs *MediaProcessor
+
+func NewMediaProcessor(db *mongo.Database, store BlobStore) *MediaProcessor {
+	return &MediaProcessor{
+		Media:    db.Collection("media"),
+		Store:    store,
+		Variants: ImageVariantsFromEnv(),
+		Workers:  2,
+	}
+}
+
+func (p *MediaProcessor) Start() {
+	p.queue = make(chan primitive.ObjectID, 256)
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+
+	finished := make(chan struct{}, p.Workers+1)
+	for i := 0; i < p.Workers; i++ {
+		go func() {
+			defer func() { finished <- struct{}{} }()
+			for {
+				select {
+				case <-p.stop:
+					return
+				case id := <-p.queue:
+					p.process(id)
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer func() { finished <- struct{}{} }()
+		ticker := time.NewTicker(mediaSweepInterval)
+		defer ticker.Stop()
+		for {
+			p.sweep()
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	go func() {
+		for i := 0; i < p.Workers+1; i++ {
+			<-finished
+		}
+		close(p.done)
+	}()
+}
+
+// Stop waits for in-flight images to finish. Queued ones are left pending for
+// the next start.
+func (p *MediaProcessor) Stop() {
+	if p.stop == nil {
+		return
+	}
+	close(p.stop)
+	<-p.done
+}
+
+// Enqueue schedules media for processing. It never blocks; when the queue is
+// full the sweep picks the upload up later.
+func (p *MediaProcessor) Enqueue(id primitive.ObjectID) {
+	if p == nil || p.queue == nil {
+		return
+	}
+	select {
+	case p.queue <- id:
+	default:
+	}
+}
+
+func (p *MediaProcessor) sweep() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cursor, err := p.Media.Find(ctx, claimableMediaFilter(time.Now()),
+		options.Find().SetProjection(bson.M{"_id": 1}).SetLimit(100))
+	if err != nil {
+		log.Printf("Media sweep failed: %v", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var m struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if cursor.Decode(&m) == nil {
+			p.Enqueue(m.ID)
+		}
+	}
+}
+
+func claimableMediaFilter(now time.Time) bson.M {
+	return bson.M{
+		"status": models.MediaStatusPending,
+		"$or": []bson.M{
+			{"lease_until": bson.M{"$exists": false}},
+			{"lease_until": bson.M{"$lt": now}},
+		},
+	}
+}
+
+func (p *MediaProcessor) process(id primitive.ObjectID) {
+	ctx, cancel := context.WithTimeout(context.Background(), mediaProcessLease)
+	defer cancel()
+
+	// Claim the record so no other worker or replica picks it up meanwhile.
+	now := time.Now()
+	filter := claimableMediaFilter(now)
+	filter["_id"] = id
+	var media models.Media
+	err := p.Media.FindOneAndUpdate(ctx, filter,
+		bson.M{"$set": bson.M{"lease_until": now.Add(mediaProcessLease)}, "$inc": bson.M{"attempts": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&media)
+	if err != nil {
+		return // processed or claimed elsewhere
+	}
+
+	if err := ProcessImage(ctx, p.Store, &media, p.Variants); err != nil {
+		log.Printf("Processing media %s failed (attempt %d): %v", id.Hex(), media.Attempts, err)
+		set := bson.M{"error": err.Error()}
+		if media.Attempts >= mediaProcessAttempts || errors.Is(err, ErrImageTooLarge) {
+			set["status"] = models.MediaStatusFailed
+		}
+		p.Media.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set, "$unset": bson.M{"lease_until": ""}})
+		return
+	}
+
+	_, err = p.Media.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{
+			"status":       media.Status,
+			"size":         media.Size,
+			"width":        media.Width,
+			"height":       media.Height,
+			"variants":     media.Variants,
+			"blurhash":     media.Blurhash,
+			"lqip":         media.LQIP,
+			"processed_at": media.ProcessedAt,
+		},
+		"$unset": bson.M{"lease_until": "", "error": ""},
+	})
+	if err != nil {
+		log.Printf("Saving processed media %s failed: %v", id.Hex(), err)
+		return
+	}
+	log.Printf("✅ Processed media %s into %d variant(s)", id.Hex(), len(media.Variants))
+}
@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"go-backend/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func pngBytes(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h))))
+	return buf.Bytes()
+}
+
+func TestSpoolUpload_ImageDimensionsAndHash(t *testing.T) {
+	data := pngBytes(t, 32, 16)
+
+	u, err := SpoolUpload(bytes.NewReader(data), 1<<20)
+	assert.NoError(t, err)
+	defer u.Close()
+
+	assert.Equal(t, "image/png", u.ContentType)
+	assert.Equal(t, int64(len(data)), u.Size)
+	assert.Equal(t, 32, u.Width)
+	assert.Equal(t, 16, u.Height)
+	assert.Len(t, u.SHA256, 64)
+}
+
+func TestSpoolUpload_Rejects(t *testing.T) {
+	_, err := SpoolUpload(bytes.NewReader(pngBytes(t, 64, 64)), 10)
+	assert.ErrorIs(t, err, ErrMediaTooLarge)
+
+	_, err = SpoolUpload(strings.NewReader("<html><script>alert(1)</script></html>"), 1<<20)
+	assert.ErrorIs(t, err, ErrMediaType)
+
+	_, err = SpoolUpload(strings.NewReader(""), 1<<20)
+	assert.ErrorIs(t, err, ErrMediaEmpty)
+
+	// A PNG signature with a broken body is not accepted as an image.
+	_, err = SpoolUpload(bytes.NewReader([]byte("\x89PNG\x0d\x0a\x1a\x0agarbage")), 1<<20)
+	assert.ErrorIs(t, err, ErrMediaUnreadable)
+}
+
+func TestSpoolUpload_PixelLimit(t *testing.T) {
+	t.Setenv("MEDIA_MAX_PIXELS", "1000")
+	_, err := SpoolUpload(bytes.NewReader(pngBytes(t, 64, 64)), 1<<20)
+	assert.ErrorIs(t, err, ErrImageTooLarge)
+
+	u, err := SpoolUpload(bytes.NewReader(pngBytes(t, 20, 20)), 1<<20)
+	assert.NoError(t, err)
+	u.Close()
+}
+
+func TestMediaSizeLimit(t *testing.T) {
+	assert.Greater(t, MediaSizeLimit("Admin"), MediaSizeLimit("user"))
+	assert.Zero(t, MediaSizeLimit("guest"))
+}
+
+func TestSpooledUpload_Store(t *testing.T) {
+	data := pngBytes(t, 8, 8)
+	u, err := SpoolUpload(bytes.NewReader(data), 1<<20)
+	assert.NoError(t, err)
+	defer u.Close()
+
+	store := NewMemoryBlobStore()
+	media := models.Media{OwnerID: primitive.NewObjectID()}
+	assert.NoError(t, u.Store(context.Background(), store, &media))
+
+	assert.Equal(t, media.OwnerID.Hex()+"/"+media.ID.Hex()+".png", media.Key)
+	assert.True(t, strings.HasSuffix(media.URL, "/media/"+media.Key))
+
+	blob, err := store.Open(context.Background(), media.Key)
+	assert.NoError(t, err)
+	stored, _ := io.ReadAll(blob)
+	assert.Equal(t, data, stored)
+}
+
+func TestLocalBlobStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocalBlobStore(t.TempDir())
+	assert.NoError(t, err)
+
+	assert.NoError(t, store.Put(ctx, "owner/file.txt", strings.NewReader("hello"), 5, "text/plain"))
+	blob, err := store.Open(ctx, "owner/file.txt")
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(blob)
+	blob.Close()
+	assert.Equal(t, "hello", string(data))
+
+	assert.NoError(t, store.Delete(ctx, "owner/file.txt"))
+	_, err = store.Open(ctx, "owner/file.txt")
+	assert.ErrorIs(t, err, ErrBlobNotFound)
+
+	assert.Error(t, store.Put(ctx, "../escape", strings.NewReader("x"), 1, "text/plain"))
+}
+
+func TestDeleteMediaBlobs_RemovesVariants(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryBlobStore()
+	m := models.Media{
+		Key:  "owner/a.png",
+		Size: 100,
+		Variants: []models.MediaVariant{
+			{Name: "thumb", Key: "owner/a/thumb.webp", Size: 10},
+			{Name: "medium", Key: "owner/a/medium.webp", Size: 40},
+		},
+	}
+	for _, key := range []string{m.Key, m.Variants[0].Key, m.Variants[1].Key, "owner/b.png"} {
+		assert.NoError(t, store.Put(ctx, key, strings.NewReader("x"), 1, "image/png"))
+	}
+
+	assert.Equal(t, int64(150), mediaBytes(m))
+	DeleteMediaBlobs(ctx, store, m)
+	assert.Equal(t, []string{"owner/b.png"}, store.Keys())
+}
+
+func TestWithLock(t *testing.T) {
+	ran := false
+	assert.NoError(t, WithLock(context.Background(), nil, "k", time.Minute, func() { ran = true }))
+	assert.True(t, ran)
+
+	ran = false
+	assert.NoError(t, WithLock(context.Background(), denyLock{}, "k", time.Minute, func() { ran = true }))
+	assert.False(t, ran)
+}
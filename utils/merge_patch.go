@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// MergePatchContentType is the media type of JSON Merge Patch (RFC 7396)
+// documents.
+const MergePatchContentType = "application/merge-patch+json"
+
+var (
+	ErrPatchMediaType = errors.New("Content-Type must be " + MergePatchContentType)
+	ErrPatchNotObject = errors.New("Patch must be a JSON object")
+)
+
+// MergePatch applies patch to target as RFC 7396 describes: members of a
+// patch object replace those of the target, recursively for objects, and
+// null members remove them. Anything but an object replaces the target
+// whole. Maps in target are modified.
+func MergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = MergePatch(t[k], v)
+	}
+	return t
+}
+
+// ReadMergePatch decodes a merge patch from the request body and checks that
+// it only touches the given top-level fields.
+func ReadMergePatch(r *http.Request, fields []string) (map[string]interface{}, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != MergePatchContentType {
+		return nil, ErrPatchMediaType
+	}
+
+	var patch interface{}
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&patch); err != nil {
+		return nil, errors.New("Invalid input")
+	}
+	obj, ok := patch.(map[string]interface{})
+	if !ok {
+		return nil, ErrPatchNotObject
+	}
+
+	allowed := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		allowed[f] = true
+	}
+	var denied []string
+	for k := range obj {
+		if !allowed[k] {
+			denied = append(denied, k)
+		}
+	}
+	if len(denied) > 0 {
+		sort.Strings(denied)
+		return nil, fmt.Errorf("Cannot change %s; mutable fields are %s", strings.Join(denied, ", "), strings.Join(fields, ", "))
+	}
+	return obj, nil
+}
+
+// ApplyMergePatch applies patch to the JSON form of v, a pointer to a
+// struct, and decodes the result back into it. Fields the patch removes end
+// up as zero values; fields hidden from JSON are zeroed too, so callers
+// should only store the patched fields.
+func ApplyMergePatch(v interface{}, patch map[string]interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return err
+	}
+	if raw, err = json.Marshal(MergePatch(doc, patch)); err != nil {
+		return err
+	}
+
+	target := reflect.ValueOf(v).Elem()
+	target.Set(reflect.Zero(target.Type()))
+	return json.Unmarshal(raw, v)
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergePatch_RFC7396Examples(t *testing.T) {
+	cases := []struct{ target, patch, want string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, c := range cases {
+		var target, patch, want interface{}
+		json.Unmarshal([]byte(c.target), &target)
+		json.Unmarshal([]byte(c.patch), &patch)
+		json.Unmarshal([]byte(c.want), &want)
+		assert.Equal(t, want, MergePatch(target, patch), "%s + %s", c.target, c.patch)
+	}
+}
+
+func TestReadMergePatch(t *testing.T) {
+	r := httptest.NewRequest("PATCH", "/", strings.NewReader(`{"title":"x","tags":null}`))
+	r.Header.Set("Content-Type", MergePatchContentType)
+	patch, err := ReadMergePatch(r, []string{"title", "tags"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"title": "x", "tags": nil}, patch)
+
+	r = httptest.NewRequest("PATCH", "/", strings.NewReader(`{"title":"x"}`))
+	r.Header.Set("Content-Type", "application/json")
+	_, err = ReadMergePatch(r, []string{"title"})
+	assert.ErrorIs(t, err, ErrPatchMediaType)
+
+	r = httptest.NewRequest("PATCH", "/", strings.NewReader(`{"title":"x","author_id":"y","created_at":null}`))
+	r.Header.Set("Content-Type", MergePatchContentType+"; charset=utf-8")
+	_, err = ReadMergePatch(r, []string{"title"})
+	assert.EqualError(t, err, "Cannot change author_id, created_at; mutable fields are title")
+
+	r = httptest.NewRequest("PATCH", "/", strings.NewReader(`["title"]`))
+	r.Header.Set("Content-Type", MergePatchContentType)
+	_, err = ReadMergePatch(r, []string{"title"})
+	assert.ErrorIs(t, err, ErrPatchNotObject)
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	type doc struct {
+		Title   string     `json:"title"`
+		Tags    []string   `json:"tags"`
+		Secret  string     `json:"-"`
+		Expires *time.Time `json:"expires_at,omitempty"`
+	}
+	expires := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	d := doc{Title: "Old", Tags: []string{"a"}, Secret: "s", Expires: &expires}
+
+	assert.NoError(t, ApplyMergePatch(&d, map[string]interface{}{"title": "New", "expires_at": nil}))
+	assert.Equal(t, "New", d.Title)
+	assert.Equal(t, []string{"a"}, d.Tags)
+	assert.Nil(t, d.Expires)
+	assert.Empty(t, d.Secret, "fields hidden from JSON are not carried over")
+
+	assert.Error(t, ApplyMergePatch(&d, map[string]interface{}{"tags": "not a list"}))
+}
+
+func TestIfMatch(t *testing.T) {
+	r := httptest.NewRequest("PATCH", "/", nil)
+	assert.False(t, IfMatch(r, VersionETag(3)), "a missing header does not match")
+
+	r.Header.Set("If-Match", `"2", "3"`)
+	assert.True(t, IfMatch(r, VersionETag(3)))
+	assert.False(t, IfMatch(r, VersionETag(4)))
+
+	r.Header.Set("If-Match", `W/"3"`)
+	assert.False(t, IfMatch(r, VersionETag(3)), "weak tags never match")
+
+	r.Header.Set("If-Match", "*")
+	assert.True(t, IfMatch(r, VersionETag(0)))
+}
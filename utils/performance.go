@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"math"
+	"sort"
+
+	"go-backend/models"
+)
+
+// RMultiple returns the result of a closed trade in units of initial risk.
+// ok is false for trades that never had a fill or an exit price.
+func RMultiple(t *models.Trade) (r float64, ok bool) {
+	if t == nil || t.Status != models.TradeStatusClosed || t.ExitPrice == nil || !t.Filled() {
+		return 0, false
+	}
+
+	entry := t.AverageEntry()
+	risk := math.Abs(entry - t.Stop)
+	if risk == 0 {
+		return 0, false
+	}
+
+	if t.Direction == models.TradeDirectionShort {
+		return (entry - *t.ExitPrice) / risk, true
+	}
+	return (*t.ExitPrice - entry) / risk, true
+}
+
+// ComputePerformance aggregates closed trade posts into an AuthorPerformance.
+// Posts are evaluated in the order they were closed so the drawdown follows
+// the author's equity curve.
+func ComputePerformance(posts []models.Post) models.AuthorPerformance {
+	closed := make([]models.Post, 0, len(posts))
+	for _, p := range posts {
+		if _, ok := RMultiple(p.Trade); ok {
+			closed = append(closed, p)
+		}
+	}
+	sort.SliceStable(closed, func(i, j int) bool {
+		return closed[i].Trade.ClosedAt.Before(*closed[j].Trade.ClosedAt)
+	})
+
+	perf := models.AuthorPerformance{ByTicker: []models.TickerPerformance{}}
+	tickers := map[string]*models.TickerPerformance{}
+	var winSum, lossSum, equity, peak float64
+
+	for _, p := range closed {
+		r, _ := RMultiple(p.Trade)
+
+		perf.Trades++
+		perf.TotalR += r
+		if r > 0 {
+			perf.Wins++
+			winSum += r
+		} else {
+			perf.Losses++
+			lossSum += r
+		}
+
+		equity += r
+		peak = math.Max(peak, equity)
+		perf.MaxDrawdown = math.Max(perf.MaxDrawdown, peak-equity)
+
+		tp, ok := tickers[p.Trade.Instrument]
+		if !ok {
+			tp = &models.TickerPerformance{Instrument: p.Trade.Instrument}
+			tickers[p.Trade.Instrument] = tp
+		}
+		tp.Trades++
+		tp.TotalR += r
+		if r > 0 {
+			tp.Wins++
+		}
+	}
+
+	if perf.Trades == 0 {
+		return perf
+	}
+
+	perf.WinRate = float64(perf.Wins) / float64(perf.Trades)
+	perf.AvgR = perf.TotalR / float64(perf.Trades)
+	if perf.Wins > 0 {
+		perf.AvgWinR = winSum / float64(perf.Wins)
+	}
+	if perf.Losses > 0 {
+		perf.AvgLossR = lossSum / float64(perf.Losses)
+	}
+	perf.Expectancy = perf.WinRate*perf.AvgWinR + (1-perf.WinRate)*perf.AvgLossR
+
+	for _, tp := range tickers {
+		tp.WinRate = float64(tp.Wins) / float64(tp.Trades)
+		tp.AvgR = tp.TotalR / float64(tp.Trades)
+		perf.ByTicker = append(perf.ByTicker, *tp)
+	}
+	sort.Slice(perf.ByTicker, func(i, j int) bool {
+		if perf.ByTicker[i].Trades != perf.ByTicker[j].Trades {
+			return perf.ByTicker[i].Trades > perf.ByTicker[j].Trades
+		}
+		return perf.ByTicker[i].Instrument < perf.ByTicker[j].Instrument
+	})
+
+	return perf
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"go-backend/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func closedTrade(author primitive.ObjectID, ticker string, dir models.TradeDirection, entry, stop, exit float64, closedAt time.Time) models.Post {
+	return models.Post{
+		AuthorID: author,
+		Type:     models.PostTypeTrade,
+		Trade: &models.Trade{
+			Instrument: ticker,
+			Direction:  dir,
+			Entry:      entry,
+			Stop:       stop,
+			Targets:    []float64{entry * 2},
+			Fills:      []models.TradeFill{{Price: entry, Quantity: 1}},
+			Status:     models.TradeStatusClosed,
+			ExitPrice:  &exit,
+			ClosedAt:   &closedAt,
+		},
+	}
+}
+
+func TestRMultiple(t *testing.T) {
+	long := closedTrade(primitive.NilObjectID, "AAPL", models.TradeDirectionLong, 100, 90, 120, time.Now())
+	r, ok := RMultiple(long.Trade)
+	assert.True(t, ok)
+	assert.InDelta(t, 2.0, r, 1e-9)
+
+	short := closedTrade(primitive.NilObjectID, "AAPL", models.TradeDirectionShort, 100, 105, 110, time.Now())
+	r, ok = RMultiple(short.Trade)
+	assert.True(t, ok)
+	assert.InDelta(t, -2.0, r, 1e-9)
+
+	open := &models.Trade{Status: models.TradeStatusOpen}
+	_, ok = RMultiple(open)
+	assert.False(t, ok)
+
+	unfilled := closedTrade(primitive.NilObjectID, "AAPL", models.TradeDirectionLong, 100, 90, 120, time.Now())
+	unfilled.Trade.Fills = nil
+	_, ok = RMultiple(unfilled.Trade)
+	assert.False(t, ok, "a trade that never filled has no result")
+}
+
+func TestComputePerformance(t *testing.T) {
+	author := primitive.NewObjectID()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// R sequence in close order: +2, -1, -1, +3
+	posts := []models.Post{
+		closedTrade(author, "MSFT", models.TradeDirectionLong, 100, 90, 130, start.Add(72*time.Hour)),
+		closedTrade(author, "AAPL", models.TradeDirectionLong, 100, 90, 120, start),
+		closedTrade(author, "AAPL", models.TradeDirectionLong, 100, 90, 90, start.Add(24*time.Hour)),
+		closedTrade(author, "AAPL", models.TradeDirectionShort, 100, 110, 110, start.Add(48*time.Hour)),
+	}
+
+	perf := ComputePerformance(posts)
+
+	assert.Equal(t, 4, perf.Trades)
+	assert.Equal(t, 2, perf.Wins)
+	assert.InDelta(t, 0.5, perf.WinRate, 1e-9)
+	assert.InDelta(t, 0.75, perf.AvgR, 1e-9)
+	assert.InDelta(t, 0.75, perf.Expectancy, 1e-9)
+	assert.InDelta(t, 2.0, perf.MaxDrawdown, 1e-9)
+	assert.Len(t, perf.ByTicker, 2)
+	assert.Equal(t, "AAPL", perf.ByTicker[0].Instrument)
+	assert.Equal(t, 3, perf.ByTicker[0].Trades)
+}
+
+func TestBuildLeaderboard_SkipsThinRecords(t *testing.T) {
+	good, thin := primitive.NewObjectID(), primitive.NewObjectID()
+	now := time.Now()
+
+	var posts []models.Post
+	for i := 0; i < leaderboardMinTrades; i++ {
+		posts = append(posts, closedTrade(good, "AAPL", models.TradeDirectionLong, 100, 90, 110, now))
+	}
+	posts = append(posts, closedTrade(thin, "AAPL", models.TradeDirectionLong, 100, 90, 150, now))
+
+	board := BuildLeaderboard(posts)
+
+	assert.Len(t, board.Entries, 1)
+	assert.Equal(t, good, board.Entries[0].AuthorID)
+	assert.Equal(t, 1, board.Entries[0].Rank)
+}
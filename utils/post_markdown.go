@@ -0,0 +1,232 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"gopkg.in/yaml.v3"
+)
+
+// Posts move in and out of the database as markdown files with YAML front
+// matter, e.g. for migrating a blog or taking a backup:
+//
+//	---
+//	title: Gold breaks out
+//	tags: [gold, macro]
+//	visibility: public
+//	type: idea
+//	scheduled_at: 2026-05-01T09:00:00Z
+//	---
+//	The post itself, in markdown.
+
+const frontMatterFence = "---"
+
+var ErrNoFrontMatter = errors.New("file does not start with YAML front matter")
+
+// PostFrontMatter is the metadata of a post file. Only Title is required.
+type PostFrontMatter struct {
+	Title       string            `yaml:"title"`
+	Slug        string            `yaml:"slug,omitempty"`   // defaults to the file name
+	Author      string            `yaml:"author,omitempty"` // handle or email, defaults to the importing author
+	Tags        []string          `yaml:"tags,omitempty"`
+	Visibility  models.Visibility `yaml:"visibility,omitempty"`
+	Type        models.PostType   `yaml:"type,omitempty"`
+	ScheduledAt *time.Time        `yaml:"scheduled_at,omitempty"`
+	PublishedAt *time.Time        `yaml:"published_at,omitempty"`
+	Trade       *TradeSetup       `yaml:"trade,omitempty"` // required on trade posts
+}
+
+// TradeSetup is the part of a trade an author writes; its lifecycle stays in
+// the database.
+type TradeSetup struct {
+	Instrument string                `yaml:"instrument"`
+	Direction  models.TradeDirection `yaml:"direction"`
+	Entry      float64               `yaml:"entry"`
+	Stop       float64               `yaml:"stop"`
+	Targets    []float64             `yaml:"targets,flow"`
+	Timeframe  string                `yaml:"timeframe"`
+	Expiry     *time.Time            `yaml:"expiry,omitempty"`
+}
+
+// PostDocument is a post file: front matter and markdown content.
+type PostDocument struct {
+	PostFrontMatter
+	Content string
+}
+
+// ParsePostMarkdown reads a post file, filling in the default visibility and
+// type and checking the values.
+func ParsePostMarkdown(data []byte) (PostDocument, error) {
+	var doc PostDocument
+
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	text = strings.TrimPrefix(text, "\ufeff")
+	if !strings.HasPrefix(text, frontMatterFence+"\n") {
+		return doc, ErrNoFrontMatter
+	}
+	rest := "\n" + text[len(frontMatterFence)+1:]
+	end := strings.Index(rest, "\n"+frontMatterFence+"\n")
+	if end < 0 {
+		if !strings.HasSuffix(rest, "\n"+frontMatterFence) {
+			return doc, errors.New("front matter is not closed by ---")
+		}
+		end = len(rest) - len(frontMatterFence) - 1
+	}
+
+	if err := yaml.Unmarshal([]byte(rest[:end]), &doc.PostFrontMatter); err != nil {
+		return doc, fmt.Errorf("invalid front matter: %w", err)
+	}
+	doc.Content = strings.TrimLeft(rest[end+len(frontMatterFence)+1:], "\n")
+
+	return doc, doc.validate()
+}
+
+func (doc *PostDocument) validate() error {
+	doc.Title = strings.TrimSpace(doc.Title)
+	if doc.Title == "" {
+		return errors.New("title is required")
+	}
+	doc.Slug = Slugify(doc.Slug)
+
+	doc.Visibility = models.Visibility(strings.ToLower(string(doc.Visibility)))
+	switch doc.Visibility {
+	case "":
+		doc.Visibility = models.VisibilityPublic
+	case models.VisibilityPublic, models.VisibilityPrivate, models.VisibilityPremium:
+	default:
+		return fmt.Errorf("unknown visibility %q", doc.Visibility)
+	}
+
+	doc.Type = models.PostType(strings.ToLower(string(doc.Type)))
+	switch doc.Type {
+	case "":
+		doc.Type = models.PostTypeIdea
+	case models.PostTypeIdea, models.PostTypeTrade:
+	default:
+		return fmt.Errorf("unknown type %q", doc.Type)
+	}
+	if doc.Trade != nil && doc.Type != models.PostTypeTrade {
+		return errors.New("trade is only allowed on trade posts")
+	}
+
+	tags := []string{}
+	for _, tag := range doc.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	doc.Tags = tags
+	return nil
+}
+
+// NewPostDocument is the file form of post, written by author.
+func NewPostDocument(post models.Post, author string) PostDocument {
+	doc := PostDocument{
+		PostFrontMatter: PostFrontMatter{
+			Title:       post.Title,
+			Slug:        post.Slug,
+			Author:      author,
+			Tags:        post.Tags,
+			Visibility:  post.Visibility,
+			Type:        post.Type,
+			ScheduledAt: post.ScheduledAt,
+			PublishedAt: post.PublishedAt,
+		},
+		Content: post.Content,
+	}
+	if t := post.Trade; t != nil {
+		doc.Trade = &TradeSetup{
+			Instrument: t.Instrument,
+			Direction:  t.Direction,
+			Entry:      t.Entry,
+			Stop:       t.Stop,
+			Targets:    t.Targets,
+			Timeframe:  t.Timeframe,
+			Expiry:     t.Expiry,
+		}
+	}
+	return doc
+}
+
+// Markdown renders doc as a post file that ParsePostMarkdown reads back.
+func (doc PostDocument) Markdown() ([]byte, error) {
+	meta, err := yaml.Marshal(doc.PostFrontMatter)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(frontMatterFence + "\n")
+	buf.Write(meta)
+	buf.WriteString(frontMatterFence + "\n\n")
+	buf.WriteString(doc.Content)
+	if !strings.HasSuffix(doc.Content, "\n") {
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// Update returns the changes that bring post in line with doc, or nil when
+// they already agree. Only the text, tags, visibility and schedule are
+// synced; the type of a post and its trade stay as they are.
+func (doc PostDocument) Update(post models.Post) (bson.M, error) {
+	if doc.Type != post.Type {
+		return nil, fmt.Errorf("cannot change type from %s to %s", post.Type, doc.Type)
+	}
+
+	set := bson.M{}
+	unset := bson.M{}
+	if doc.Title != post.Title {
+		set["title"] = doc.Title
+	}
+	if strings.TrimRight(doc.Content, "\n") != strings.TrimRight(post.Content, "\n") {
+		set["content"] = doc.Content
+	}
+	if strings.Join(doc.Tags, "\x00") != strings.Join(post.Tags, "\x00") {
+		set["tags"] = doc.Tags
+	}
+	if doc.Visibility != post.Visibility {
+		set["visibility"] = doc.Visibility
+	}
+	switch {
+	case doc.ScheduledAt == nil && post.ScheduledAt != nil:
+		unset["scheduled_at"] = ""
+	case doc.ScheduledAt != nil && (post.ScheduledAt == nil || !doc.ScheduledAt.Equal(*post.ScheduledAt)):
+		set["scheduled_at"] = *doc.ScheduledAt
+	}
+	if len(set) == 0 && len(unset) == 0 {
+		return nil, nil
+	}
+
+	update := bson.M{"$set": set, "$inc": bson.M{"version": 1}}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return update, nil
+}
+
+// NewTrade turns the setup into a pending trade, checking it like a trade
+// submitted through the API.
+func (s TradeSetup) NewTrade() (*models.Trade, error) {
+	trade := &models.Trade{
+		Instrument: s.Instrument,
+		Direction:  s.Direction,
+		Entry:      s.Entry,
+		Stop:       s.Stop,
+		Targets:    s.Targets,
+		Timeframe:  s.Timeframe,
+		Expiry:     s.Expiry,
+	}
+	trade.Normalize()
+	if err := trade.Validate(); err != nil {
+		return nil, err
+	}
+	trade.Reset()
+	return trade, nil
+}
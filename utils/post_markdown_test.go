@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"go-backend/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestParsePostMarkdown(t *testing.T) {
+	doc, err := ParsePostMarkdown([]byte("---\r\n" +
+		"title: \" Gold breaks out \"\r\n" +
+		"slug: Gold Breaks Out\r\n" +
+		"tags: [gold, ' macro', '']\r\n" +
+		"visibility: Premium\r\n" +
+		"scheduled_at: 2026-05-01T09:00:00Z\r\n" +
+		"---\r\n" +
+		"\r\n" +
+		"Body with a --- rule.\r\n"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "Gold breaks out", doc.Title)
+	assert.Equal(t, "gold-breaks-out", doc.Slug)
+	assert.Equal(t, []string{"gold", "macro"}, doc.Tags)
+	assert.Equal(t, models.VisibilityPremium, doc.Visibility)
+	assert.Equal(t, models.PostTypeIdea, doc.Type, "defaults to idea")
+	assert.True(t, doc.ScheduledAt.Equal(time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "Body with a --- rule.\n", doc.Content)
+
+	empty, err := ParsePostMarkdown([]byte("---\ntitle: Only metadata\n---"))
+	assert.NoError(t, err)
+	assert.Equal(t, models.VisibilityPublic, empty.Visibility)
+	assert.Empty(t, empty.Content)
+}
+
+func TestParsePostMarkdown_Invalid(t *testing.T) {
+	cases := map[string]string{
+		"no front matter": "# Just markdown\n",
+		"not closed":      "---\ntitle: Open\n",
+		"no title":        "---\ntags: [a]\n---\nBody\n",
+		"bad visibility":  "---\ntitle: T\nvisibility: friends\n---\n",
+		"bad type":        "---\ntitle: T\ntype: poll\n---\n",
+		"stray trade":     "---\ntitle: T\ntrade: {instrument: AAPL}\n---\n",
+		"bad yaml":        "---\ntitle: [unclosed\n---\n",
+	}
+	for name, input := range cases {
+		_, err := ParsePostMarkdown([]byte(input))
+		assert.Error(t, err, name)
+	}
+	_, err := ParsePostMarkdown([]byte("no fence"))
+	assert.ErrorIs(t, err, ErrNoFrontMatter)
+}
+
+func TestPostDocument_RoundTrip(t *testing.T) {
+	published := time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC)
+	post := models.Post{
+		Title:       "Short EURUSD: the range is over",
+		Slug:        "short-eurusd",
+		Content:     "Entry at the top of the range.",
+		Tags:        []string{"fx"},
+		Visibility:  models.VisibilityPublic,
+		Type:        models.PostTypeTrade,
+		PublishedAt: &published,
+		Trade: &models.Trade{
+			Instrument: "EURUSD", Direction: models.TradeDirectionShort,
+			Entry: 1.1, Stop: 1.12, Targets: []float64{1.05, 1.02}, Timeframe: "swing",
+			Status: models.TradeStatusOpen,
+		},
+	}
+
+	data, err := NewPostDocument(post, "jane").Markdown()
+	if !assert.NoError(t, err) {
+		return
+	}
+	doc, err := ParsePostMarkdown(data)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, post.Title, doc.Title)
+	assert.Equal(t, post.Slug, doc.Slug)
+	assert.Equal(t, "jane", doc.Author)
+	assert.Equal(t, post.Tags, doc.Tags)
+	assert.Equal(t, post.Type, doc.Type)
+	assert.True(t, doc.PublishedAt.Equal(published))
+	assert.Equal(t, post.Content+"\n", doc.Content)
+
+	trade, err := doc.Trade.NewTrade()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []float64{1.05, 1.02}, trade.Targets)
+	assert.Equal(t, models.TradeStatusPending, trade.Status, "lifecycle is not carried over")
+}
+
+func TestPostDocument_Update(t *testing.T) {
+	at := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	post := models.Post{
+		Title: "Gold", Content: "Body", Tags: []string{"gold"},
+		Visibility: models.VisibilityPublic, Type: models.PostTypeIdea, ScheduledAt: &at,
+	}
+	doc := NewPostDocument(post, "")
+	doc.Content += "\n"
+
+	update, err := doc.Update(post)
+	assert.NoError(t, err)
+	assert.Nil(t, update, "a trailing newline is not a change")
+
+	doc.Title = "Gold, revisited"
+	doc.Tags = []string{"gold", "macro"}
+	doc.ScheduledAt = nil
+	update, err = doc.Update(post)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, bson.M{"title": "Gold, revisited", "tags": []string{"gold", "macro"}}, update["$set"])
+	assert.Equal(t, bson.M{"scheduled_at": ""}, update["$unset"])
+	assert.Equal(t, bson.M{"version": 1}, update["$inc"])
+
+	doc.Type = models.PostTypeTrade
+	_, err = doc.Update(post)
+	assert.Error(t, err, "the type of a post is kept")
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"go-backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func signedParams(t *testing.T, link string) (string, string) {
+	t.Helper()
+	u, err := url.Parse(link)
+	assert.NoError(t, err)
+	return u.Query().Get("expires"), u.Query().Get("sig")
+}
+
+func TestSignMediaURL(t *testing.T) {
+	t.Setenv("MEDIA_URL_SECRET", "test-secret")
+	now := time.Now()
+	link := SignMediaURL("owner/a.mp4", now.Add(time.Hour))
+	expires, sig := signedParams(t, link)
+
+	assert.True(t, VerifyMediaURL("owner/a.mp4", expires, sig, now))
+	assert.False(t, VerifyMediaURL("owner/b.mp4", expires, sig, now), "signature is bound to the key")
+	assert.False(t, VerifyMediaURL("owner/a.mp4", expires, sig, now.Add(2*time.Hour)), "expired")
+	assert.False(t, VerifyMediaURL("owner/a.mp4", "9999999999", sig, now), "expiry cannot be extended")
+}
+
+func TestVerifyMediaURL_NoSecret(t *testing.T) {
+	t.Setenv("MEDIA_URL_SECRET", "")
+	t.Setenv("JWT_SECRET", "")
+	assert.ErrorIs(t, CheckMediaURLSecret(), ErrNoMediaURLSecret)
+
+	now := time.Now()
+	expires, sig := signedParams(t, SignMediaURL("owner/a.mp4", now.Add(time.Hour)))
+	assert.False(t, VerifyMediaURL("owner/a.mp4", expires, sig, now))
+	assert.False(t, VerifyMediaURL("owner/a.mp4", expires, "", now))
+
+	t.Setenv("JWT_SECRET", "jwt-secret")
+	assert.NoError(t, CheckMediaURLSecret())
+}
+
+func TestSignPostMedia(t *testing.T) {
+	t.Setenv("MEDIA_URL_SECRET", "test-secret")
+	media := models.Media{
+		Key:      "owner/a.png",
+		URL:      mediaURL("owner/a.png"),
+		Variants: []models.MediaVariant{{Name: "thumb", Key: "owner/a/thumb.webp", URL: mediaURL("owner/a/thumb.webp")}},
+	}
+	post := models.Post{
+		MediaURLs: []string{media.Variants[0].URL, "https://example.com/chart.png"},
+		Media:     []models.Media{media},
+	}
+
+	SignPostMedia(&post, time.Now().Add(time.Hour))
+
+	assert.Contains(t, post.MediaURLs[0], "sig=")
+	assert.Equal(t, "https://example.com/chart.png", post.MediaURLs[1])
+	assert.Contains(t, post.Media[0].URL, "sig=")
+	assert.Contains(t, post.Media[0].Variants[0].URL, "sig=")
+
+	assert.Equal(t, []string{mediaURL("owner/a/thumb.webp"), "https://example.com/chart.png"},
+		CanonicalMediaURLs(post.MediaURLs))
+}
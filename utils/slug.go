@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"go-backend/config"
+	"go-backend/models"
+	"strings"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/text/unicode/norm"
+)
+
+const maxSlugLength = 80
+
+// slugAttempts bounds how often WriteWithSlug picks a new slug after losing
+// it to a concurrent writer.
+const slugAttempts = 5
+
+// Slugify turns a title into a lowercase, ASCII, dash separated slug.
+// Accents are folded ("Café" -> "cafe") and everything else that is not a
+// letter or digit becomes a single dash.
+func Slugify(s string) string {
+	var b strings.Builder
+	dash := false
+
+	for _, r := range norm.NFKD.String(strings.ToLower(s)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// combining accent left over from NFKD
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(r)
+			dash = false
+		case b.Len() > 0 && !dash:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+
+	slug := strings.TrimRight(b.String(), "-")
+	if len(slug) > maxSlugLength {
+		slug = strings.TrimRight(slug[:maxSlugLength], "-")
+	}
+	return slug
+}
+
+// UniqueSlug returns base, or base with the first free numeric suffix
+// ("base-2", "base-3", ...) according to taken. fallback is used when base
+// is empty, e.g. for titles made only of symbols.
+func UniqueSlug(ctx context.Context, base, fallback string, taken func(ctx context.Context, slug string) (bool, error)) (string, error) {
+	if base == "" {
+		base = fallback
+	}
+
+	candidate := base
+	for i := 2; ; i++ {
+		used, err := taken(ctx, candidate)
+		if err != nil {
+			return "", err
+		}
+		if !used {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// UniquePostSlug derives a slug from title that no other post uses, either
+// as its current slug or in its history.
+func UniquePostSlug(ctx context.Context, title string, postID primitive.ObjectID) (string, error) {
+	return UniqueSlug(ctx, Slugify(title), "post", func(ctx context.Context, slug string) (bool, error) {
+		filter := bson.M{
+			"_id": bson.M{"$ne": postID},
+			"$or": []bson.M{{"slug": slug}, {"slug_history": slug}},
+		}
+		n, err := config.PostCollection.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+		return n > 0, err
+	})
+}
+
+// UniqueHandle derives a handle no other user has from the user's requested
+// handle, their name or their email address.
+func UniqueHandle(ctx context.Context, user *models.User) (string, error) {
+	base := Slugify(user.Handle)
+	if base == "" {
+		base = Slugify(user.Name)
+	}
+	if base == "" {
+		base = Slugify(strings.Split(user.Email, "@")[0])
+	}
+
+	return UniqueSlug(ctx, base, "user", func(ctx context.Context, handle string) (bool, error) {
+		n, err := config.UserCollection.CountDocuments(ctx, bson.M{"handle": handle}, options.Count().SetLimit(1))
+		return n > 0, err
+	})
+}
+
+// WriteWithSlug runs write with the slug next picks. Slugs are checked
+// before they are written, so another writer can take the same one in
+// between; the unique index then rejects write and it is retried with a
+// fresh slug, up to slugAttempts times.
+func WriteWithSlug(ctx context.Context, next func(ctx context.Context) (string, error), write func(slug string) error) error {
+	var err error
+	for i := 0; i < slugAttempts; i++ {
+		var slug string
+		if slug, err = next(ctx); err != nil {
+			return err
+		}
+		if err = write(slug); !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+	}
+	return err
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestSlugify(t *testing.T) {
+	cases := map[string]string{
+		"Long AAPL into Earnings!":  "long-aapl-into-earnings",
+		"  Café -- Crème Brûlée  ":  "cafe-creme-brulee",
+		"BTC/USD: 4h breakout (v2)": "btc-usd-4h-breakout-v2",
+		"$$$":                       "",
+		"Ünïcödé Ticker — déjà vu":  "unicode-ticker-deja-vu",
+	}
+	for in, want := range cases {
+		assert.Equal(t, want, Slugify(in), in)
+	}
+}
+
+func TestSlugify_Truncates(t *testing.T) {
+	slug := Slugify(strings.Repeat("breakout ", 20))
+	assert.LessOrEqual(t, len(slug), maxSlugLength)
+	assert.NotEqual(t, '-', slug[len(slug)-1])
+}
+
+func TestUniqueSlug(t *testing.T) {
+	taken := map[string]bool{"long-aapl": true, "long-aapl-2": true}
+	lookup := func(_ context.Context, s string) (bool, error) { return taken[s], nil }
+
+	slug, err := UniqueSlug(context.Background(), "long-aapl", "post", lookup)
+	assert.NoError(t, err)
+	assert.Equal(t, "long-aapl-3", slug)
+
+	slug, err = UniqueSlug(context.Background(), "", "post", lookup)
+	assert.NoError(t, err)
+	assert.Equal(t, "post", slug)
+}
+
+func TestWriteWithSlug(t *testing.T) {
+	ctx := context.Background()
+	duplicate := mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}}
+
+	var picked, written []string
+	next := func(context.Context) (string, error) {
+		slug := fmt.Sprintf("gold-%d", len(picked)+1)
+		picked = append(picked, slug)
+		return slug, nil
+	}
+	err := WriteWithSlug(ctx, next, func(slug string) error {
+		written = append(written, slug)
+		if len(written) < 3 {
+			return duplicate // taken by a concurrent writer
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"gold-1", "gold-2", "gold-3"}, written)
+
+	picked, written = nil, nil
+	err = WriteWithSlug(ctx, next, func(string) error { return duplicate })
+	assert.True(t, mongo.IsDuplicateKeyError(err))
+	assert.Len(t, picked, slugAttempts, "gives up eventually")
+
+	other := errors.New("boom")
+	picked, written = nil, nil
+	assert.ErrorIs(t, WriteWithSlug(ctx, next, func(slug string) error {
+		written = append(written, slug)
+		return other
+	}), other)
+	assert.Len(t, written, 1, "other errors are not retried")
+}
@@ -0,0 +1,579 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The static site is a read-only mirror of public posts: a page per post,
+// tag and author, a paged index, a sitemap and feeds. Every page carries a
+// fingerprint of what it shows, and a manifest of the last build lets the
+// next one skip pages whose fingerprint has not changed.
+
+const (
+	sitePageSize     = 20
+	siteFeedLimit    = 50
+	siteManifestFile = ".sitegen.json"
+)
+
+// SiteAuthor is an author as shown on the static site.
+type SiteAuthor struct {
+	ID     primitive.ObjectID
+	Name   string
+	Handle string
+}
+
+func (a SiteAuthor) Path() string {
+	if a.Handle != "" {
+		return "/authors/" + a.Handle + "/"
+	}
+	return "/authors/" + a.ID.Hex() + "/"
+}
+
+// SitePost is a public post with its authors resolved.
+type SitePost struct {
+	models.Post
+	Author    SiteAuthor
+	CoAuthors []SiteAuthor
+}
+
+func (p SitePost) Path() string {
+	if p.Slug != "" {
+		return "/posts/" + p.Slug + "/"
+	}
+	return "/posts/" + p.ID.Hex() + "/"
+}
+
+// Date is when the post was published.
+func (p SitePost) Date() time.Time {
+	if p.PublishedAt != nil {
+		return *p.PublishedAt
+	}
+	return p.CreatedAt
+}
+
+// stamp identifies the version of the post and everything about it that a
+// page may show.
+func (p SitePost) stamp() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s:%d:%d:%s:%s", p.ID.Hex(), p.Version, p.UpdatedAt.UnixNano(), p.Author.Name, p.Author.Path())
+	for _, a := range p.CoAuthors {
+		fmt.Fprintf(&b, ":%s:%s", a.Name, a.Path())
+	}
+	return b.String()
+}
+
+// SiteTag links a tag to its page.
+type SiteTag struct {
+	Name string
+	Path string
+}
+
+func TagPath(tag string) string {
+	slug := Slugify(tag)
+	if slug == "" {
+		// Tags with no letters or digits, such as "..", still need a folder
+		// name that is safe on disk and in a URL.
+		sum := sha256.Sum256([]byte(strings.ToLower(tag)))
+		slug = "t-" + hex.EncodeToString(sum[:])[:12]
+	}
+	return "/tags/" + slug + "/"
+}
+
+// SitePostPage is the data of the post.html template.
+type SitePostPage struct {
+	Site    *StaticSite
+	Post    SitePost
+	Tags    []SiteTag
+	Content models.RenderedContent
+	HTML    template.HTML // sanitized content
+}
+
+// SiteListPage is the data of the list.html template, used for the index,
+// tag and author pages.
+type SiteListPage struct {
+	Site        *StaticSite
+	Title       string
+	Description string
+	Items       []SiteListItem
+	Page        int
+	Pages       int
+	PrevPath    string
+	NextPath    string
+}
+
+type SiteListItem struct {
+	SitePost
+	Excerpt string
+}
+
+// SitePage is one file of the site. Render is only called when the page has
+// to be written.
+type SitePage struct {
+	Path        string // URL path, e.g. /posts/gold/ or /sitemap.xml
+	Fingerprint string
+	LastMod     time.Time // for the sitemap
+	Render      func() ([]byte, error)
+}
+
+// File is where the page is written below the output directory.
+func (p SitePage) File() string {
+	path := strings.TrimPrefix(p.Path, "/")
+	if path == "" || strings.HasSuffix(path, "/") {
+		path += "index.html"
+	}
+	return filepath.FromSlash(path)
+}
+
+// StaticSite renders the pages of the site from the post.html and list.html
+// templates.
+type StaticSite struct {
+	Title       string
+	Description string
+	BaseURL     string
+
+	templates    *template.Template
+	templateHash string
+	render       func(string) (models.RenderedContent, error)
+	rendered     map[primitive.ObjectID]models.RenderedContent
+}
+
+// NewStaticSite parses the *.html templates of fsys.
+func NewStaticSite(title, description, baseURL string, fsys fs.FS) (*StaticSite, error) {
+	templates, err := template.ParseFS(fsys, "*.html")
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range []string{"post.html", "list.html"} {
+		if templates.Lookup(name) == nil {
+			return nil, fmt.Errorf("template %s is missing", name)
+		}
+	}
+
+	names, err := fs.Glob(fsys, "*.html")
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(h, "%s:%d:", name, len(data))
+		h.Write(data)
+	}
+
+	return &StaticSite{
+		Title:        title,
+		Description:  description,
+		BaseURL:      strings.TrimRight(baseURL, "/"),
+		templates:    templates,
+		templateHash: hex.EncodeToString(h.Sum(nil)),
+		render:       RenderContent,
+		rendered:     map[primitive.ObjectID]models.RenderedContent{},
+	}, nil
+}
+
+// URL makes path absolute.
+func (s *StaticSite) URL(path string) string {
+	return s.BaseURL + path
+}
+
+func (s *StaticSite) content(p SitePost) (models.RenderedContent, error) {
+	if rc, ok := s.rendered[p.ID]; ok {
+		return rc, nil
+	}
+	rc, err := s.render(p.Content)
+	if err != nil {
+		return rc, fmt.Errorf("post %s: %w", p.ID.Hex(), err)
+	}
+	s.rendered[p.ID] = rc
+	return rc, nil
+}
+
+func (s *StaticSite) fingerprint(kind string, parts ...string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%s|%s|%s|%s", s.templateHash, s.BaseURL, s.Title, s.Description, kind)
+	for _, part := range parts {
+		fmt.Fprintf(h, "|%s", part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (s *StaticSite) execute(name string, data interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := s.templates.ExecuteTemplate(&buf, name, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Pages lays out the whole site for posts, which must all be public and
+// published.
+func (s *StaticSite) Pages(posts []SitePost) []SitePage {
+	posts = append([]SitePost(nil), posts...)
+	sort.SliceStable(posts, func(i, j int) bool { return posts[i].Date().After(posts[j].Date()) })
+
+	pages := []SitePage{}
+	for _, p := range posts {
+		pages = append(pages, s.postPage(p))
+	}
+	pages = append(pages, s.listPages("/", s.Title, s.Description, posts)...)
+
+	tagNames := map[string]string{}
+	tagged := map[string][]SitePost{}
+	authors := map[string]SiteAuthor{}
+	byAuthor := map[string][]SitePost{}
+	for _, p := range posts {
+		seen := map[string]bool{}
+		for _, tag := range p.Tags {
+			path := TagPath(tag)
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+			if _, ok := tagNames[path]; !ok {
+				tagNames[path] = tag
+			}
+			tagged[path] = append(tagged[path], p)
+		}
+		for _, a := range append([]SiteAuthor{p.Author}, p.CoAuthors...) {
+			authors[a.Path()] = a
+			byAuthor[a.Path()] = append(byAuthor[a.Path()], p)
+		}
+	}
+	for _, path := range sortedKeys(tagged) {
+		tag := tagNames[path]
+		pages = append(pages, s.listPages(path, "Posts tagged "+tag, "", tagged[path])...)
+	}
+	for _, path := range sortedKeys(byAuthor) {
+		name := authors[path].Name
+		pages = append(pages, s.listPages(path, "Posts by "+name, "", byAuthor[path])...)
+	}
+
+	pages = append(pages, s.feedPages(posts)...)
+	return append(pages, s.sitemapPage(pages))
+}
+
+func (s *StaticSite) postPage(p SitePost) SitePage {
+	return SitePage{
+		Path:        p.Path(),
+		Fingerprint: s.fingerprint("post", p.stamp()),
+		LastMod:     p.UpdatedAt,
+		Render: func() ([]byte, error) {
+			rc, err := s.content(p)
+			if err != nil {
+				return nil, err
+			}
+			page := SitePostPage{Site: s, Post: p, Content: rc, HTML: template.HTML(rc.ContentHTML)}
+			for _, tag := range p.Tags {
+				page.Tags = append(page.Tags, SiteTag{Name: tag, Path: TagPath(tag)})
+			}
+			return s.execute("post.html", page)
+		},
+	}
+}
+
+// listPages pages through posts under base: base itself, then base/page/2/
+// and so on.
+func (s *StaticSite) listPages(base, title, description string, posts []SitePost) []SitePage {
+	pageCount := (len(posts) + sitePageSize - 1) / sitePageSize
+	if pageCount == 0 {
+		pageCount = 1
+	}
+	pagePath := func(n int) string {
+		if n == 1 {
+			return base
+		}
+		return fmt.Sprintf("%spage/%d/", base, n)
+	}
+
+	pages := make([]SitePage, 0, pageCount)
+	for n := 1; n <= pageCount; n++ {
+		start := (n - 1) * sitePageSize
+		end := start + sitePageSize
+		if end > len(posts) {
+			end = len(posts)
+		}
+		chunk := posts[start:end]
+
+		page := SiteListPage{Site: s, Title: title, Description: description, Page: n, Pages: pageCount}
+		if n > 1 {
+			page.PrevPath = pagePath(n - 1)
+		}
+		if n < pageCount {
+			page.NextPath = pagePath(n + 1)
+		}
+
+		parts := []string{title, description, fmt.Sprint(n, "/", pageCount)}
+		var lastMod time.Time
+		for _, p := range chunk {
+			parts = append(parts, p.stamp())
+			if p.UpdatedAt.After(lastMod) {
+				lastMod = p.UpdatedAt
+			}
+		}
+		pages = append(pages, SitePage{
+			Path:        pagePath(n),
+			Fingerprint: s.fingerprint("list", parts...),
+			LastMod:     lastMod,
+			Render: func() ([]byte, error) {
+				page := page
+				for _, p := range chunk {
+					rc, err := s.content(p)
+					if err != nil {
+						return nil, err
+					}
+					page.Items = append(page.Items, SiteListItem{SitePost: p, Excerpt: rc.Excerpt})
+				}
+				return s.execute("list.html", page)
+			},
+		})
+	}
+	return pages
+}
+
+// feedPages writes the latest posts as RSS, Atom and JSON Feed.
+func (s *StaticSite) feedPages(posts []SitePost) []SitePage {
+	if len(posts) > siteFeedLimit {
+		posts = posts[:siteFeedLimit]
+	}
+	parts := []string{}
+	for _, p := range posts {
+		parts = append(parts, p.stamp())
+	}
+
+	formats := []struct {
+		path  string
+		write func(*Feed) ([]byte, error)
+	}{
+		{"/feed.xml", (*Feed).RSS},
+		{"/atom.xml", (*Feed).Atom},
+		{"/feed.json", (*Feed).JSON},
+	}
+	pages := []SitePage{}
+	for _, format := range formats {
+		format := format
+		pages = append(pages, SitePage{
+			Path:        format.path,
+			Fingerprint: s.fingerprint("feed"+format.path, parts...),
+			Render: func() ([]byte, error) {
+				feed := Feed{
+					Title:       s.Title,
+					Description: s.Description,
+					Link:        s.URL("/"),
+					FeedURL:     s.URL(format.path),
+				}
+				for _, p := range posts {
+					rc, err := s.content(p)
+					if err != nil {
+						return nil, err
+					}
+					item := FeedItem{
+						ID:          p.ID.Hex(),
+						Title:       p.Title,
+						Link:        s.URL(p.Path()),
+						ContentHTML: rc.ContentHTML,
+						Summary:     rc.Excerpt,
+						Author:      p.Author.Name,
+						Tags:        p.Tags,
+						Published:   p.Date(),
+						Updated:     p.UpdatedAt,
+					}
+					for _, a := range p.CoAuthors {
+						item.CoAuthors = append(item.CoAuthors, a.Name)
+					}
+					if p.UpdatedAt.After(feed.Updated) {
+						feed.Updated = p.UpdatedAt
+					}
+					feed.Items = append(feed.Items, item)
+				}
+				return format.write(&feed)
+			},
+		})
+	}
+	return pages
+}
+
+type sitemapDoc struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// sitemapPage lists the HTML pages among pages.
+func (s *StaticSite) sitemapPage(pages []SitePage) SitePage {
+	doc := sitemapDoc{XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	parts := []string{}
+	for _, p := range pages {
+		if !strings.HasSuffix(p.Path, "/") {
+			continue
+		}
+		u := sitemapURL{Loc: s.URL(p.Path)}
+		if !p.LastMod.IsZero() {
+			u.LastMod = p.LastMod.UTC().Format(time.RFC3339)
+		}
+		doc.URLs = append(doc.URLs, u)
+		parts = append(parts, u.Loc+"@"+u.LastMod)
+	}
+	return SitePage{
+		Path:        "/sitemap.xml",
+		Fingerprint: s.fingerprint("sitemap", parts...),
+		Render:      func() ([]byte, error) { return marshalXML(doc) },
+	}
+}
+
+func sortedKeys(m map[string][]SitePost) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// SiteBuild counts what a build did.
+type SiteBuild struct {
+	Written   int
+	Unchanged int
+	Removed   int
+}
+
+type siteManifest struct {
+	BuiltAt time.Time         `json:"built_at"`
+	Pages   map[string]string `json:"pages"` // file -> fingerprint
+}
+
+// BuildSite writes pages below dir. Pages whose fingerprint matches the last
+// build are skipped unless full is set, and files of pages that are gone,
+// e.g. of unpublished posts, are removed.
+func BuildSite(dir string, pages []SitePage, full bool) (SiteBuild, error) {
+	var build SiteBuild
+	manifestPath := filepath.Join(dir, siteManifestFile)
+
+	previous := siteManifest{Pages: map[string]string{}}
+	if data, err := os.ReadFile(manifestPath); err == nil {
+		if err := json.Unmarshal(data, &previous); err != nil {
+			return build, fmt.Errorf("invalid %s: %w", siteManifestFile, err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return build, err
+	}
+
+	for file := range previous.Pages {
+		if !siteFileOK(file) {
+			return build, fmt.Errorf("invalid %s: %q is outside the site", siteManifestFile, file)
+		}
+	}
+	for _, page := range pages {
+		if !siteFileOK(page.File()) {
+			return build, fmt.Errorf("%s: path is outside the site", page.Path)
+		}
+	}
+
+	next := siteManifest{BuiltAt: time.Now().UTC(), Pages: map[string]string{}}
+	for _, page := range pages {
+		file := page.File()
+		if _, dup := next.Pages[file]; dup {
+			return build, fmt.Errorf("two pages write %s", file)
+		}
+		next.Pages[file] = page.Fingerprint
+
+		if !full && previous.Pages[file] == page.Fingerprint {
+			if _, err := os.Stat(filepath.Join(dir, file)); err == nil {
+				build.Unchanged++
+				continue
+			}
+		}
+		data, err := page.Render()
+		if err != nil {
+			return build, fmt.Errorf("%s: %w", page.Path, err)
+		}
+		if err := writeFileAtomic(filepath.Join(dir, file), data); err != nil {
+			return build, err
+		}
+		build.Written++
+	}
+
+	for file := range previous.Pages {
+		if _, ok := next.Pages[file]; ok {
+			continue
+		}
+		path := filepath.Join(dir, file)
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return build, err
+		}
+		build.Removed++
+		removeEmptyDirs(filepath.Dir(path), dir)
+	}
+
+	data, err := json.MarshalIndent(next, "", "  ")
+	if err != nil {
+		return build, err
+	}
+	return build, writeFileAtomic(manifestPath, data)
+}
+
+// siteFileOK reports whether file is a clean path below the output
+// directory, so no page or stale manifest entry can touch anything else.
+func siteFileOK(file string) bool {
+	return filepath.IsLocal(file) && filepath.Clean(file) == file
+}
+
+// removeEmptyDirs removes path and its parents below root while they are
+// empty.
+func removeEmptyDirs(path, root string) {
+	root = filepath.Clean(root)
+	for path != root && strings.HasPrefix(path, root) {
+		if os.Remove(path) != nil {
+			return
+		}
+		path = filepath.Dir(path)
+	}
+}
+
+// writeFileAtomic replaces path in one step, so a sync to the CDN never
+// picks up a half written file.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
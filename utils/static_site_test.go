@@ -0,0 +1,168 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"go-backend/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var testSiteTemplates = fstest.MapFS{
+	"post.html": {Data: []byte(`<h1>{{.Post.Title}}</h1>{{.HTML}}{{range .Tags}}<a href="{{$.Site.URL .Path}}">{{.Name}}</a>{{end}}`)},
+	"list.html": {Data: []byte(`<h1>{{.Title}}</h1>{{range .Items}}<a href="{{$.Site.URL .Path}}">{{.Title}}</a> {{.Excerpt}}{{end}}{{if .NextPath}}<a href="{{.NextPath}}">next</a>{{end}}`)},
+}
+
+func testSite(t *testing.T, renders *int) *StaticSite {
+	site, err := NewStaticSite("Ideas", "Public ideas", "https://ideas.example.com/", testSiteTemplates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	site.render = func(src string) (models.RenderedContent, error) {
+		*renders++
+		return RenderContent(src)
+	}
+	return site
+}
+
+func testSitePost(slug string, at time.Time, tags ...string) SitePost {
+	return SitePost{
+		Post: models.Post{
+			ID: primitive.NewObjectID(), Title: slug, Slug: slug, Content: "About " + slug,
+			Tags: tags, PublishedAt: &at, UpdatedAt: at,
+		},
+		Author: SiteAuthor{ID: primitive.NewObjectID(), Name: "Jane", Handle: "jane"},
+	}
+}
+
+func TestStaticSite_Pages(t *testing.T) {
+	renders := 0
+	site := testSite(t, &renders)
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	posts := []SitePost{testSitePost("gold", now, "Gold", "gold"), testSitePost("oil", now.Add(time.Hour), "energy")}
+
+	pages := site.Pages(posts)
+	paths := []string{}
+	for _, p := range pages {
+		paths = append(paths, p.Path)
+	}
+	assert.Equal(t, []string{
+		"/posts/oil/", "/posts/gold/", "/",
+		"/tags/energy/", "/tags/gold/",
+		"/authors/jane/",
+		"/feed.xml", "/atom.xml", "/feed.json", "/sitemap.xml",
+	}, paths, "tags are merged by slug")
+	assert.Equal(t, filepath.Join("posts", "oil", "index.html"), pages[0].File())
+	assert.Equal(t, "index.html", pages[2].File())
+
+	index, err := pages[2].Render()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Regexp(t, `oil.*gold`, string(index), "newest first")
+	assert.Contains(t, string(index), `href="https://ideas.example.com/posts/gold/"`)
+
+	sitemap, err := pages[len(pages)-1].Render()
+	assert.NoError(t, err)
+	assert.Contains(t, string(sitemap), "<loc>https://ideas.example.com/tags/gold/</loc>")
+	assert.NotContains(t, string(sitemap), "feed.xml")
+}
+
+func TestStaticSite_ListPaging(t *testing.T) {
+	renders := 0
+	site := testSite(t, &renders)
+	posts := []SitePost{}
+	start := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < sitePageSize+1; i++ {
+		posts = append(posts, testSitePost(primitive.NewObjectID().Hex(), start.Add(time.Duration(i)*time.Minute)))
+	}
+
+	pages := site.listPages("/", "All", "", posts)
+	if !assert.Len(t, pages, 2) {
+		return
+	}
+	assert.Equal(t, "/page/2/", pages[1].Path)
+	first, err := pages[0].Render()
+	assert.NoError(t, err)
+	assert.Contains(t, string(first), `href="/page/2/"`)
+}
+
+func TestBuildSite_Incremental(t *testing.T) {
+	dir := t.TempDir()
+	renders := 0
+	site := testSite(t, &renders)
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	gold := testSitePost("gold", now, "metals")
+	oil := testSitePost("oil", now.Add(time.Hour), "energy")
+
+	build, err := BuildSite(dir, site.Pages([]SitePost{gold, oil}), false)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, SiteBuild{Written: 10}, build)
+	assert.FileExists(t, filepath.Join(dir, "posts", "gold", "index.html"))
+	assert.FileExists(t, filepath.Join(dir, "sitemap.xml"))
+
+	build, err = BuildSite(dir, testSite(t, &renders).Pages([]SitePost{gold, oil}), false)
+	assert.NoError(t, err)
+	assert.Equal(t, SiteBuild{Unchanged: 10}, build, "nothing changed")
+
+	// Editing one post rewrites its page and every page listing it.
+	renders = 0
+	gold.Title, gold.Version, gold.UpdatedAt = "Gold, revisited", 1, now.Add(2*time.Hour)
+	build, err = BuildSite(dir, testSite(t, &renders).Pages([]SitePost{gold, oil}), false)
+	assert.NoError(t, err)
+	assert.Equal(t, SiteBuild{Written: 8, Unchanged: 2}, build, "oil and its tag page are left alone")
+	assert.Equal(t, 2, renders, "markdown is rendered once per post")
+
+	// Unpublishing a post removes its pages.
+	build, err = BuildSite(dir, testSite(t, &renders).Pages([]SitePost{oil}), false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, build.Removed)
+	assert.NoDirExists(t, filepath.Join(dir, "posts", "gold"))
+	assert.NoDirExists(t, filepath.Join(dir, "tags", "metals"))
+
+	// A deleted file is written again even though it is up to date.
+	assert.NoError(t, os.Remove(filepath.Join(dir, "feed.xml")))
+	build, err = BuildSite(dir, testSite(t, &renders).Pages([]SitePost{oil}), false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, build.Written)
+
+	build, err = BuildSite(dir, testSite(t, &renders).Pages([]SitePost{oil}), true)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, build.Unchanged, "full rebuild")
+}
+
+func TestTagPath_Unsafe(t *testing.T) {
+	assert.Equal(t, "/tags/gold-silver/", TagPath("Gold & Silver"))
+	for _, tag := range []string{"..", "金", "/"} {
+		path := TagPath(tag)
+		assert.Regexp(t, `^/tags/t-[0-9a-f]{12}/$`, path, tag)
+	}
+	assert.NotEqual(t, TagPath("金"), TagPath("銀"))
+	assert.Equal(t, TagPath("Ω"), TagPath("ω"), "tags are case insensitive")
+}
+
+func TestBuildSite_RejectsPathsOutsideDir(t *testing.T) {
+	for _, path := range []string{"/tags/../", "/../x", "/posts//gold/"} {
+		dir := t.TempDir()
+		pages := []SitePage{
+			{Path: "/", Fingerprint: "a", Render: func() ([]byte, error) { return []byte("home"), nil }},
+			{Path: path, Fingerprint: "b", Render: func() ([]byte, error) { return []byte("evil"), nil }},
+		}
+		_, err := BuildSite(dir, pages, false)
+		assert.Error(t, err, path)
+		assert.NoFileExists(t, filepath.Join(dir, "index.html"), "nothing is written")
+	}
+
+	dir := t.TempDir()
+	manifest := `{"pages": {"../outside.html": "a"}}`
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, siteManifestFile), []byte(manifest), 0o644))
+	_, err := BuildSite(dir, nil, false)
+	assert.Error(t, err, "a tampered manifest cannot remove files")
+}
@@ -0,0 +1,187 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"go-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	tradeResolverInterval = 5 * time.Minute
+	tradeResolverLockKey  = "lock:trade-resolver"
+)
+
+// StartTradeResolver periodically checks open trade posts against price bars
+// from provider and closes them when a target, the stop or the expiry is hit,
+// on one replica at a time. It stops once ctx is done; the returned channel
+// is closed after any run in progress.
+func StartTradeResolver(ctx context.Context, db *mongo.Database, provider MarketDataProvider, lock Locker) <-chan struct{} {
+	return runJob(ctx, every(tradeResolverInterval), func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		WithLock(ctx, lock, tradeResolverLockKey, tradeResolverInterval, func() {
+			resolveOpenTrades(ctx, db, provider)
+		})
+	})
+}
+
+func resolveOpenTrades(ctx context.Context, db *mongo.Database, provider MarketDataProvider) {
+	postsColl := db.Collection("posts")
+
+	filter := bson.M{
+		"type":         models.PostTypeTrade,
+		"status":       "published",
+		"trade.status": bson.M{"$in": []models.TradeStatus{models.TradeStatusPending, models.TradeStatusOpen}},
+	}
+
+	cursor, err := postsColl.Find(ctx, filter)
+	if err != nil {
+		log.Printf("Failed to load open trades: %v", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	resolved := 0
+	for cursor.Next(ctx) {
+		var post models.Post
+		if err := cursor.Decode(&post); err != nil || post.Trade == nil {
+			continue
+		}
+
+		since := post.CreatedAt
+		if post.PublishedAt != nil {
+			since = *post.PublishedAt
+		}
+		if post.Trade.CheckedThrough != nil {
+			since = *post.Trade.CheckedThrough
+		}
+
+		now := time.Now()
+		bars, err := provider.Bars(ctx, post.Trade.Instrument, since, now)
+		if err != nil && !errors.Is(err, ErrUnknownInstrument) {
+			log.Printf("Failed to load bars for %s: %v", post.Trade.Instrument, err)
+			continue
+		}
+
+		prevStatus, prevEvents := post.Trade.Status, len(post.Trade.Events)
+		if !ResolveTrade(post.Trade, bars, now) {
+			continue
+		}
+
+		// The whole trade is replaced, so only write it while no fill or
+		// close was recorded since it was read; those win.
+		filter := bson.M{"_id": post.ID, "trade.status": prevStatus}
+		filter["trade.events."+strconv.Itoa(prevEvents)] = bson.M{"$exists": false}
+		_, err = postsColl.UpdateOne(ctx, filter,
+			bson.M{"$set": bson.M{"trade": post.Trade, "updated_at": now}, "$inc": bson.M{"version": 1}},
+		)
+		if err != nil {
+			log.Printf("Failed to update trade %s: %v", post.ID.Hex(), err)
+			continue
+		}
+		if post.Trade.Status == models.TradeStatusClosed {
+			resolved++
+		}
+	}
+
+	if resolved > 0 {
+		log.Printf("✅ Resolved %d trade post(s) from market data", resolved)
+	}
+}
+
+// ResolveTrade walks bars in order and applies fills, stop outs, target hits
+// and expiry to t. It reports whether t changed.
+//
+// When a bar touches both the stop and a target the stop wins, since bars do
+// not tell which came first. On the bar that fills the entry only the stop is
+// checked for the same reason.
+func ResolveTrade(t *models.Trade, bars []Bar, now time.Time) bool {
+	if t.Status == models.TradeStatusClosed {
+		return false
+	}
+
+	changed := false
+	var lastClose *float64
+
+	for _, bar := range bars {
+		if t.Expiry != nil && bar.Time.After(*t.Expiry) {
+			break
+		}
+
+		switch t.Status {
+		case models.TradeStatusPending:
+			if bar.Low <= t.Entry && t.Entry <= bar.High {
+				entry := t.Entry
+				t.Fills = append(t.Fills, models.TradeFill{Price: entry, Quantity: 1, FilledAt: bar.Time})
+				t.Events = append(t.Events, models.TradeEvent{Type: models.TradeEventFilled, Price: &entry, Source: models.TradeSourceMarketData, At: bar.Time})
+				t.Status = models.TradeStatusOpen
+
+				if stopTouched(t, bar) {
+					stop := t.Stop
+					t.Close(models.TradeOutcomeStopped, &stop, bar.Time, models.TradeSourceMarketData)
+				}
+			}
+		case models.TradeStatusOpen:
+			if stopTouched(t, bar) {
+				stop := t.Stop
+				t.Close(models.TradeOutcomeStopped, &stop, bar.Time, models.TradeSourceMarketData)
+			} else if target, ok := furthestTarget(t, bar); ok {
+				t.Close(models.TradeOutcomeTargetHit, &target, bar.Time, models.TradeSourceMarketData)
+			}
+		}
+
+		closePrice := bar.Close
+		lastClose = &closePrice
+		checked := bar.Time
+		t.CheckedThrough = &checked
+		changed = true
+
+		if t.Status == models.TradeStatusClosed {
+			return true
+		}
+	}
+
+	if t.Expiry != nil && !now.Before(*t.Expiry) {
+		var exitPrice *float64
+		if t.Status == models.TradeStatusOpen {
+			exitPrice = lastClose
+		}
+		t.Close(models.TradeOutcomeExpired, exitPrice, *t.Expiry, models.TradeSourceMarketData)
+		return true
+	}
+
+	return changed
+}
+
+func stopTouched(t *models.Trade, bar Bar) bool {
+	if t.Direction == models.TradeDirectionShort {
+		return bar.High >= t.Stop
+	}
+	return bar.Low <= t.Stop
+}
+
+// furthestTarget returns the most distant target reached within bar.
+func furthestTarget(t *models.Trade, bar Bar) (float64, bool) {
+	best, found := 0.0, false
+	for _, target := range t.Targets {
+		var reached, further bool
+		if t.Direction == models.TradeDirectionShort {
+			reached = bar.Low <= target
+			further = !found || target < best
+		} else {
+			reached = bar.High >= target
+			further = !found || target > best
+		}
+		if reached && further {
+			best, found = target, true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var barStart = time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC)
+
+func bar(hour int, open, high, low, close float64) Bar {
+	return Bar{Time: barStart.Add(time.Duration(hour) * time.Hour), Open: open, High: high, Low: low, Close: close}
+}
+
+func pendingLong() *models.Trade {
+	return &models.Trade{
+		Instrument: "AAPL",
+		Direction:  models.TradeDirectionLong,
+		Entry:      100,
+		Stop:       95,
+		Targets:    []float64{110, 120},
+		Status:     models.TradeStatusPending,
+	}
+}
+
+func TestResolveTrade_FillThenTarget(t *testing.T) {
+	trade := pendingLong()
+	bars := []Bar{
+		bar(0, 102, 103, 101, 102), // entry not touched
+		bar(1, 101, 102, 99, 100),  // fills at 100
+		bar(2, 100, 112, 100, 111), // first target
+	}
+
+	assert.True(t, ResolveTrade(trade, bars, barStart.Add(3*time.Hour)))
+	assert.Equal(t, models.TradeStatusClosed, trade.Status)
+	assert.Equal(t, models.TradeOutcomeTargetHit, trade.Outcome)
+	assert.Equal(t, 110.0, *trade.ExitPrice)
+	assert.Len(t, trade.Events, 2)
+	assert.Equal(t, models.TradeSourceMarketData, trade.Events[1].Source)
+}
+
+func TestResolveTrade_StopWinsOnAmbiguousBar(t *testing.T) {
+	trade := pendingLong()
+	trade.Status = models.TradeStatusOpen
+
+	ResolveTrade(trade, []Bar{bar(0, 100, 125, 90, 100)}, barStart.Add(time.Hour))
+
+	assert.Equal(t, models.TradeOutcomeStopped, trade.Outcome)
+	assert.Equal(t, 95.0, *trade.ExitPrice)
+}
+
+func TestResolveTrade_Expires(t *testing.T) {
+	trade := pendingLong()
+	expiry := barStart.Add(90 * time.Minute)
+	trade.Expiry = &expiry
+
+	bars := []Bar{bar(0, 102, 103, 101, 102), bar(1, 102, 104, 101, 103), bar(2, 100, 112, 99, 111)}
+	ResolveTrade(trade, bars, barStart.Add(3*time.Hour))
+
+	assert.Equal(t, models.TradeOutcomeExpired, trade.Outcome)
+	assert.Nil(t, trade.ExitPrice)
+	assert.Empty(t, trade.Fills)
+}
+
+func TestResolveTrade_NoBarsNoChange(t *testing.T) {
+	trade := pendingLong()
+	assert.False(t, ResolveTrade(trade, nil, time.Now()))
+}
+
+func TestCSVMarketData_Bars(t *testing.T) {
+	dir := t.TempDir()
+	csv := strings.Join([]string{
+		"time,open,high,low,close",
+		"2026-03-02T16:00:00Z,3,4,2,3",
+		"2026-03-02T14:00:00Z,1,2,0.5,1.5",
+		"1772463600,2,3,1,2", // 2026-03-02T15:00:00Z
+	}, "\n")
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "AAPL.csv"), []byte(csv), 0o644))
+
+	provider := NewCSVMarketData(dir)
+	bars, err := provider.Bars(context.Background(), "aapl", barStart, barStart.Add(2*time.Hour))
+
+	assert.NoError(t, err)
+	assert.Len(t, bars, 2)
+	assert.Equal(t, barStart.Add(time.Hour), bars[0].Time)
+	assert.Equal(t, 4.0, bars[1].High)
+
+	_, err = provider.Bars(context.Background(), "MSFT", barStart, barStart.Add(time.Hour))
+	assert.ErrorIs(t, err, ErrUnknownInstrument)
+}
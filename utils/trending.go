@@ -0,0 +1,226 @@
+package utils
+
+import (
+	"context"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"go-backend/models"
+
+	"github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Trending ranks published posts by recent activity, each hour of it counting
+// half as much per TrendingHalfLife of age. The ranking is recomputed in the
+// background into Redis sorted sets, one for all posts and one per post type,
+// so listing trending posts never scans the posts collection. Views are the
+// only activity recorded so far; posts have no reactions or comments yet.
+
+const (
+	trendingKey      = "trending:posts"
+	trendingLockKey  = "lock:trending"
+	trendingInterval = 10 * time.Minute
+	trendingWindow   = 72 * time.Hour
+	trendingHalfLife = 12 * time.Hour
+	trendingSize     = 1000 // posts kept per ranking
+)
+
+// TrendingWeights says how much each kind of activity adds to a score.
+type TrendingWeights struct {
+	Views   float64
+	Uniques float64
+}
+
+// DefaultTrendingWeights favours distinct readers over raw views so reloading
+// a page does little.
+var DefaultTrendingWeights = TrendingWeights{Views: 0.25, Uniques: 1}
+
+// TrendingActivity is what happened to a post during one hour starting at At.
+type TrendingActivity struct {
+	At      time.Time `bson:"start"`
+	Views   int64     `bson:"views"`
+	Uniques int64     `bson:"uniques"`
+}
+
+// TrendingScore sums weighted activity, decayed by its age at now.
+func TrendingScore(activity []TrendingActivity, weights TrendingWeights, halfLife time.Duration, now time.Time) float64 {
+	score := 0.0
+	for _, a := range activity {
+		age := now.Sub(a.At.Add(30 * time.Minute)) // middle of the hour
+		if age < 0 {
+			age = 0
+		}
+		points := weights.Views*float64(a.Views) + weights.Uniques*float64(a.Uniques)
+		score += points * math.Exp2(-age.Hours()/halfLife.Hours())
+	}
+	return score
+}
+
+func trendingKeyFor(postType models.PostType) string {
+	if postType == "" {
+		return trendingKey
+	}
+	return trendingKey + ":" + string(postType)
+}
+
+// rankTrending scores the posts in activity that appear in types and returns
+// the sorted set members per ranking key, best first and capped to size.
+func rankTrending(activity map[primitive.ObjectID][]TrendingActivity, types map[primitive.ObjectID]models.PostType,
+	weights TrendingWeights, halfLife time.Duration, now time.Time, size int) map[string][]*redis.Z {
+	rankings := map[string][]*redis.Z{}
+	for id, postType := range types {
+		score := TrendingScore(activity[id], weights, halfLife, now)
+		if score <= 0 {
+			continue
+		}
+		z := &redis.Z{Score: score, Member: id.Hex()}
+		rankings[trendingKey] = append(rankings[trendingKey], z)
+		if postType != "" {
+			rankings[trendingKeyFor(postType)] = append(rankings[trendingKeyFor(postType)], z)
+		}
+	}
+	for key, members := range rankings {
+		sort.Slice(members, func(i, j int) bool {
+			if members[i].Score != members[j].Score {
+				return members[i].Score > members[j].Score
+			}
+			return members[i].Member.(string) < members[j].Member.(string)
+		})
+		if len(members) > size {
+			rankings[key] = members[:size]
+		}
+	}
+	return rankings
+}
+
+// TrendingRanker recomputes and serves the trending rankings.
+type TrendingRanker struct {
+	Client   *redis.Client
+	Stats    *mongo.Collection
+	Posts    *mongo.Collection
+	Weights  TrendingWeights
+	HalfLife time.Duration
+	Window   time.Duration // activity older than this is ignored
+	Lock     Locker
+	Now      func() time.Time
+}
+
+// Trending is the process wide ranker, configured in main.
+var Trending *TrendingRanker
+
+func NewTrendingRanker(client *redis.Client, db *mongo.Database) *TrendingRanker {
+	t := &TrendingRanker{
+		Client:   client,
+		Stats:    db.Collection("post_stats"),
+		Posts:    db.Collection("posts"),
+		Weights:  DefaultTrendingWeights,
+		HalfLife: trendingHalfLife,
+		Window:   trendingWindow,
+		Lock:     &RedisLock{Client: client},
+		Now:      time.Now,
+	}
+	if d, err := time.ParseDuration(os.Getenv("TRENDING_HALF_LIFE")); err == nil && d > 0 {
+		t.HalfLife = d
+	}
+	return t
+}
+
+// Recompute rebuilds every ranking from the hourly view stats and returns
+// how many posts were ranked.
+func (t *TrendingRanker) Recompute(ctx context.Context) (int, error) {
+	now := t.Now()
+	cursor, err := t.Stats.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"granularity": models.StatsHourly, "start": bson.M{"$gte": now.Add(-t.Window)}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      "$post_id",
+			"activity": bson.M{"$push": bson.M{"start": "$start", "views": "$views", "uniques": "$uniques"}},
+		}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	var rows []struct {
+		PostID   primitive.ObjectID `bson:"_id"`
+		Activity []TrendingActivity `bson:"activity"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return 0, err
+	}
+
+	activity := make(map[primitive.ObjectID][]TrendingActivity, len(rows))
+	ids := make([]primitive.ObjectID, 0, len(rows))
+	for _, row := range rows {
+		activity[row.PostID] = row.Activity
+		ids = append(ids, row.PostID)
+	}
+
+	// Only posts that are still published and not hidden are ranked.
+	types := map[primitive.ObjectID]models.PostType{}
+	if len(ids) > 0 {
+		cursor, err = t.Posts.Find(ctx, bson.M{"_id": bson.M{"$in": ids}, "status": "published", "hidden": bson.M{"$ne": true}},
+			options.Find().SetProjection(bson.M{"_id": 1, "type": 1}))
+		if err != nil {
+			return 0, err
+		}
+		var posts []models.Post
+		if err := cursor.All(ctx, &posts); err != nil {
+			return 0, err
+		}
+		for _, p := range posts {
+			types[p.ID] = p.Type
+		}
+	}
+
+	rankings := rankTrending(activity, types, t.Weights, t.HalfLife, now, trendingSize)
+
+	// Replace all rankings in one transaction so readers never see a
+	// half-written set.
+	_, err = t.Client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.Del(ctx, trendingKey, trendingKeyFor(models.PostTypeIdea), trendingKeyFor(models.PostTypeTrade))
+		for key, members := range rankings {
+			p.ZAdd(ctx, key, members...)
+		}
+		return nil
+	})
+	return len(rankings[trendingKey]), err
+}
+
+// Ranked returns the IDs of all ranked trending posts, optionally of one post
+// type, best first. Rankings are capped at trendingSize, so callers can filter
+// the whole ranking before paging it.
+func (t *TrendingRanker) Ranked(ctx context.Context, postType models.PostType) ([]primitive.ObjectID, error) {
+	members, err := t.Client.ZRevRange(ctx, trendingKeyFor(postType), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(members))
+	for _, member := range members {
+		if id, err := primitive.ObjectIDFromHex(member); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// StartTrendingJob ranks posts on startup and then every few minutes, on one
+// replica at a time, until ctx is done. The returned channel is closed after
+// any ranking in progress.
+func StartTrendingJob(ctx context.Context, t *TrendingRanker) <-chan struct{} {
+	return runJob(ctx, immediately(every(trendingInterval)), func() {
+		ctx, cancel := context.WithTimeout(context.Background(), trendingInterval)
+		defer cancel()
+		WithLock(ctx, t.Lock, trendingLockKey, trendingInterval, func() {
+			if _, err := t.Recompute(ctx); err != nil {
+				log.Printf("Failed to rank trending posts: %v", err)
+			}
+		})
+	})
+}
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"go-backend/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestTrendingScore_Decays(t *testing.T) {
+	now := time.Date(2026, 3, 4, 12, 30, 0, 0, time.UTC)
+	weights := TrendingWeights{Uniques: 1}
+	fresh := []TrendingActivity{{At: now.Add(-30 * time.Minute), Uniques: 100}}
+	old := []TrendingActivity{{At: now.Add(-12*time.Hour - 30*time.Minute), Uniques: 100}}
+
+	assert.InDelta(t, 100, TrendingScore(fresh, weights, 12*time.Hour, now), 0.001)
+	assert.InDelta(t, 50, TrendingScore(old, weights, 12*time.Hour, now), 0.001)
+	assert.Zero(t, TrendingScore(nil, weights, 12*time.Hour, now))
+}
+
+func TestRankTrending(t *testing.T) {
+	now := time.Now()
+	hot, steady, idle, gone := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	activity := map[primitive.ObjectID][]TrendingActivity{
+		hot:    {{At: now, Uniques: 50}},
+		steady: {{At: now.Add(-48 * time.Hour), Uniques: 100}},
+		gone:   {{At: now, Uniques: 500}}, // no longer published
+	}
+	types := map[primitive.ObjectID]models.PostType{
+		hot:    models.PostTypeTrade,
+		steady: models.PostTypeIdea,
+		idle:   models.PostTypeIdea,
+	}
+
+	rankings := rankTrending(activity, types, DefaultTrendingWeights, 12*time.Hour, now, 10)
+
+	all := rankings[trendingKey]
+	assert.Len(t, all, 2, "posts without activity or no longer published are not ranked")
+	assert.Equal(t, hot.Hex(), all[0].Member)
+	assert.Equal(t, steady.Hex(), all[1].Member)
+	assert.Len(t, rankings[trendingKeyFor(models.PostTypeTrade)], 1)
+	assert.Len(t, rankings[trendingKeyFor(models.PostTypeIdea)], 1)
+
+	capped := rankTrending(activity, types, DefaultTrendingWeights, 12*time.Hour, now, 1)
+	assert.Len(t, capped[trendingKey], 1)
+}
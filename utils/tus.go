@@ -0,0 +1,422 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// TusVersion is the tus protocol version the upload endpoints speak.
+const TusVersion = "1.0.0"
+
+const (
+	tusUploadTTL    = 24 * time.Hour
+	tusStateGrace   = time.Hour // state outlives expiry so the sweeper can find its parts
+	tusExpiringKey  = "tus:expiring"
+	tusExpiryTicker = 15 * time.Minute
+	tusLockTTL      = time.Minute // renewed while a request holds it
+)
+
+var (
+	ErrTusNotFound       = errors.New("upload not found")
+	ErrTusOffsetMismatch = errors.New("upload offset does not match")
+	ErrTusLocked         = errors.New("upload is being written by another request")
+	ErrTusComplete       = errors.New("upload is already complete")
+)
+
+// TusUpload is the progress of a resumable upload. The bytes received so far
+// are kept as numbered parts in the blob store, so any replica can continue
+// or assemble the upload.
+type TusUpload struct {
+	ID        string            `json:"id"`
+	OwnerID   string            `json:"owner_id"`
+	Length    int64             `json:"length"`
+	Offset    int64             `json:"offset"`
+	Parts     int               `json:"parts"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	ExpiresAt time.Time         `json:"expires_at"`
+	MediaID   string            `json:"media_id,omitempty"` // set once assembled
+}
+
+func (u *TusUpload) Complete() bool { return u.Offset == u.Length }
+
+// TusStateStore persists upload progress.
+type TusStateStore interface {
+	Save(ctx context.Context, u *TusUpload) error
+	// Load returns ErrTusNotFound for unknown uploads.
+	Load(ctx context.Context, id string) (*TusUpload, error)
+	Delete(ctx context.Context, id string) error
+	// Expired lists uploads whose ExpiresAt is before now.
+	Expired(ctx context.Context, now time.Time) ([]string, error)
+}
+
+// RedisTusState keeps upload progress in Redis, shared by all replicas.
+type RedisTusState struct {
+	Client *redis.Client
+}
+
+func tusStateKey(id string) string { return "tus:upload:" + id }
+
+func (s *RedisTusState) Save(ctx context.Context, u *TusUpload) error {
+	data, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	ttl := time.Until(u.ExpiresAt) + tusStateGrace
+	_, err = s.Client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.Set(ctx, tusStateKey(u.ID), data, ttl)
+		p.ZAdd(ctx, tusExpiringKey, &redis.Z{Score: float64(u.ExpiresAt.Unix()), Member: u.ID})
+		return nil
+	})
+	return err
+}
+
+func (s *RedisTusState) Load(ctx context.Context, id string) (*TusUpload, error) {
+	data, err := s.Client.Get(ctx, tusStateKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrTusNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var u TusUpload
+	if err := json.Unmarshal(data, &u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (s *RedisTusState) Delete(ctx context.Context, id string) error {
+	_, err := s.Client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.Del(ctx, tusStateKey(id))
+		p.ZRem(ctx, tusExpiringKey, id)
+		return nil
+	})
+	return err
+}
+
+func (s *RedisTusState) Expired(ctx context.Context, now time.Time) ([]string, error) {
+	return s.Client.ZRangeByScore(ctx, tusExpiringKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(now.Unix(), 10),
+	}).Result()
+}
+
+// TusUploads implements the storage side of tus: creation, appending chunks,
+// assembly, termination and expiration.
+type TusUploads struct {
+	State TusStateStore
+	Blobs BlobStore
+	Lock  Locker // serializes appends and assembly per upload; nil disables
+	TTL   time.Duration
+	Now   func() time.Time
+}
+
+// Tus is the process wide upload service, configured in main.
+var Tus *TusUploads
+
+func NewTusUploads(client *redis.Client, blobs BlobStore) *TusUploads {
+	return &TusUploads{
+		State: &RedisTusState{Client: client},
+		Blobs: blobs,
+		Lock:  &RedisLock{Client: client},
+		TTL:   tusUploadTTL,
+		Now:   time.Now,
+	}
+}
+
+func tusPartKey(id string, n int) string {
+	return fmt.Sprintf("tus/%s/%06d", id, n)
+}
+
+func (t *TusUploads) Create(ctx context.Context, ownerID string, length int64, metadata map[string]string) (*TusUpload, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	now := t.Now()
+	u := &TusUpload{
+		ID:        hex.EncodeToString(buf),
+		OwnerID:   ownerID,
+		Length:    length,
+		Metadata:  metadata,
+		CreatedAt: now,
+		ExpiresAt: now.Add(t.TTL),
+	}
+	return u, t.State.Save(ctx, u)
+}
+
+// Get returns the upload if it exists, has not expired and belongs to
+// ownerID. Other owners get ErrTusNotFound so ids cannot be probed.
+func (t *TusUploads) Get(ctx context.Context, id, ownerID string) (*TusUpload, error) {
+	u, err := t.State.Load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if u.OwnerID != ownerID || !t.Now().Before(u.ExpiresAt) {
+		return nil, ErrTusNotFound
+	}
+	return u, nil
+}
+
+// Append stores the bytes of r as the next part of u, provided offset is
+// where the upload currently stands. size is the length of the chunk, -1
+// when unknown. At most the remaining length is read. Each successful chunk
+// extends the expiry.
+func (t *TusUploads) Append(ctx context.Context, u *TusUpload, offset int64, r io.Reader, size int64) error {
+	release, err := t.hold(ctx, u)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if u.Complete() {
+		return ErrTusComplete
+	}
+	if offset != u.Offset {
+		return ErrTusOffsetMismatch
+	}
+
+	remaining := u.Length - u.Offset
+	if size > remaining {
+		size = remaining
+	}
+	counted := &countingReader{r: io.LimitReader(r, remaining)}
+	key := tusPartKey(u.ID, u.Parts)
+	if err := t.Blobs.Put(ctx, key, counted, size, "application/octet-stream"); err != nil {
+		t.Blobs.Delete(ctx, key)
+		return err
+	}
+	if counted.n == 0 {
+		t.Blobs.Delete(ctx, key)
+		return nil
+	}
+
+	u.Parts++
+	u.Offset += counted.n
+	u.ExpiresAt = t.Now().Add(t.TTL)
+	return t.State.Save(ctx, u)
+}
+
+// Assemble runs build over the bytes of the complete upload u while holding
+// its lock, so concurrent requests that both saw the last chunk arrive turn
+// it into media only once. build returns the ID of the media it stored,
+// which is recorded with Finish. If u was assembled meanwhile, it is
+// reloaded and build is not run.
+func (t *TusUploads) Assemble(ctx context.Context, u *TusUpload, build func(io.Reader) (string, error)) error {
+	release, err := t.hold(ctx, u)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if u.MediaID != "" {
+		return nil
+	}
+	if !u.Complete() {
+		return ErrTusOffsetMismatch
+	}
+
+	parts := t.Reader(ctx, u)
+	mediaID, err := build(parts)
+	parts.Close()
+	if err != nil {
+		return err
+	}
+	if err := t.Finish(ctx, u, mediaID); err != nil {
+		log.Printf("Failed to finish upload %s: %v", u.ID, err)
+	}
+	return nil
+}
+
+// hold takes the lock of u, if uploads are locked, and reloads u since
+// another request may have changed it in the meantime. The lock is renewed
+// while held, so a slow chunk or assembly does not lose it.
+func (t *TusUploads) hold(ctx context.Context, u *TusUpload) (func(), error) {
+	release := func() {}
+	if t.Lock != nil {
+		var ok bool
+		var err error
+		release, ok, err = HoldLock(ctx, t.Lock, "lock:tus:"+u.ID, tusLockTTL)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrTusLocked
+		}
+	}
+
+	fresh, err := t.State.Load(ctx, u.ID)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	*u = *fresh
+	return release, nil
+}
+
+// Reader streams the parts of u in order.
+func (t *TusUploads) Reader(ctx context.Context, u *TusUpload) io.ReadCloser {
+	return &tusPartsReader{ctx: ctx, uploads: t, upload: u}
+}
+
+// Finish records the media the upload was assembled into and drops its
+// parts. The state is kept until expiry so HEAD keeps answering.
+func (t *TusUploads) Finish(ctx context.Context, u *TusUpload, mediaID string) error {
+	t.deleteParts(ctx, u)
+	u.MediaID = mediaID
+	u.Parts = 0
+	return t.State.Save(ctx, u)
+}
+
+// Terminate deletes the upload and everything received so far.
+func (t *TusUploads) Terminate(ctx context.Context, u *TusUpload) error {
+	t.deleteParts(ctx, u)
+	return t.State.Delete(ctx, u.ID)
+}
+
+func (t *TusUploads) deleteParts(ctx context.Context, u *TusUpload) {
+	for n := 0; n < u.Parts; n++ {
+		if err := t.Blobs.Delete(ctx, tusPartKey(u.ID, n)); err != nil {
+			log.Printf("Failed to delete upload part %s: %v", tusPartKey(u.ID, n), err)
+		}
+	}
+}
+
+// ExpireOnce removes uploads that passed their expiry with their parts.
+func (t *TusUploads) ExpireOnce(ctx context.Context) (int, error) {
+	ids, err := t.State.Expired(ctx, t.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, id := range ids {
+		u, err := t.State.Load(ctx, id)
+		if errors.Is(err, ErrTusNotFound) {
+			t.State.Delete(ctx, id)
+			continue
+		}
+		if err != nil {
+			return expired, err
+		}
+		if t.Now().Before(u.ExpiresAt) {
+			continue // extended since it was listed
+		}
+		if err := t.Terminate(ctx, u); err != nil {
+			return expired, err
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// StartTusExpiryJob periodically removes expired uploads until ctx is done.
+// The returned channel is closed once the job has stopped, after any run in
+// progress.
+func StartTusExpiryJob(ctx context.Context, uploads *TusUploads) <-chan struct{} {
+	return runJob(ctx, every(tusExpiryTicker), func() {
+		ctx, cancel := context.WithTimeout(context.Background(), tusExpiryTicker)
+		defer cancel()
+		WithLock(ctx, uploads.Lock, "lock:tus-expiry", tusExpiryTicker, func() {
+			logTusExpiry(uploads.ExpireOnce(ctx))
+		})
+	})
+}
+
+func logTusExpiry(n int, err error) {
+	if err != nil {
+		log.Printf("Failed to expire uploads: %v", err)
+	}
+	if n > 0 {
+		log.Printf("✅ Removed %d expired upload(s)", n)
+	}
+}
+
+// ParseTusMetadata decodes an Upload-Metadata header: comma separated
+// "key base64value" pairs, where the value may be omitted.
+func ParseTusMetadata(header string) (map[string]string, error) {
+	meta := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, encoded, _ := strings.Cut(pair, " ")
+		if key == "" {
+			return nil, errors.New("invalid Upload-Metadata")
+		}
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, errors.New("invalid Upload-Metadata value for " + key)
+		}
+		meta[key] = string(value)
+	}
+	return meta, nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// tusPartsReader opens each part only when the previous one is exhausted.
+type tusPartsReader struct {
+	ctx     context.Context
+	uploads *TusUploads
+	upload  *TusUpload
+	next    int
+	current io.ReadCloser
+}
+
+func (r *tusPartsReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if r.next >= r.upload.Parts {
+				return 0, io.EOF
+			}
+			part, err := r.uploads.Blobs.Open(r.ctx, tusPartKey(r.upload.ID, r.next))
+			if err != nil {
+				return 0, err
+			}
+			r.current = part
+			r.next++
+		}
+
+		n, err := r.current.Read(p)
+		if errors.Is(err, io.EOF) {
+			r.current.Close()
+			r.current = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (r *tusPartsReader) Close() error {
+	if r.current != nil {
+		return r.current.Close()
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package utils
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryTusState struct {
+	mu      sync.Mutex
+	uploads map[string]TusUpload
+}
+
+func (s *memoryTusState) Save(_ context.Context, u *TusUpload) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.uploads == nil {
+		s.uploads = map[string]TusUpload{}
+	}
+	s.uploads[u.ID] = *u
+	return nil
+}
+
+func (s *memoryTusState) Load(_ context.Context, id string) (*TusUpload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.uploads[id]
+	if !ok {
+		return nil, ErrTusNotFound
+	}
+	return &u, nil
+}
+
+func (s *memoryTusState) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.uploads, id)
+	return nil
+}
+
+func (s *memoryTusState) Expired(_ context.Context, now time.Time) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ids []string
+	for id, u := range s.uploads {
+		if u.ExpiresAt.Before(now) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func newTestTus(now time.Time) (*TusUploads, *MemoryBlobStore) {
+	blobs := NewMemoryBlobStore()
+	return &TusUploads{
+		State: &memoryTusState{},
+		Blobs: blobs,
+		TTL:   time.Hour,
+		Now:   func() time.Time { return now },
+	}, blobs
+}
+
+func TestTusUploads_ChunksAssembleInOrder(t *testing.T) {
+	ctx := context.Background()
+	tus, blobs := newTestTus(time.Now())
+
+	u, err := tus.Create(ctx, "owner", 11, map[string]string{"filename": "chart.mp4"})
+	assert.NoError(t, err)
+
+	assert.NoError(t, tus.Append(ctx, u, 0, strings.NewReader("hello "), 6))
+	assert.ErrorIs(t, tus.Append(ctx, u, 0, strings.NewReader("again"), -1), ErrTusOffsetMismatch)
+
+	// Resume from the stored offset; extra bytes past Upload-Length are ignored.
+	resumed, err := tus.Get(ctx, u.ID, "owner")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(6), resumed.Offset)
+	assert.NoError(t, tus.Append(ctx, resumed, 6, strings.NewReader("world!!!"), 8))
+	assert.True(t, resumed.Complete())
+	assert.ErrorIs(t, tus.Append(ctx, resumed, 11, strings.NewReader("x"), -1), ErrTusComplete)
+
+	r := tus.Reader(ctx, resumed)
+	data, err := io.ReadAll(r)
+	r.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, "hello world", string(data))
+
+	assert.NoError(t, tus.Finish(ctx, resumed, "media-id"))
+	assert.Empty(t, blobs.Keys())
+	done, _ := tus.Get(ctx, u.ID, "owner")
+	assert.Equal(t, "media-id", done.MediaID)
+}
+
+func TestTusUploads_AssembleOnce(t *testing.T) {
+	ctx := context.Background()
+	tus, blobs := newTestTus(time.Now())
+
+	u, _ := tus.Create(ctx, "owner", 5, nil)
+	stale := *u
+	assert.NoError(t, tus.Append(ctx, u, 0, strings.NewReader("hello"), 5))
+	stale.Offset = u.Offset // a second request that also saw the last chunk
+
+	builds := 0
+	build := func(r io.Reader) (string, error) {
+		builds++
+		data, err := io.ReadAll(r)
+		assert.Equal(t, "hello", string(data))
+		return "media-id", err
+	}
+	assert.NoError(t, tus.Assemble(ctx, u, build))
+	assert.NoError(t, tus.Assemble(ctx, &stale, build))
+	assert.Equal(t, 1, builds)
+	assert.Equal(t, "media-id", stale.MediaID)
+	assert.Empty(t, blobs.Keys())
+}
+
+// sizeRecordingBlobs remembers the sizes blobs were stored with.
+type sizeRecordingBlobs struct {
+	*MemoryBlobStore
+	sizes []int64
+}
+
+func (b *sizeRecordingBlobs) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	b.sizes = append(b.sizes, size)
+	return b.MemoryBlobStore.Put(ctx, key, r, size, contentType)
+}
+
+func TestTusUploads_PartSize(t *testing.T) {
+	ctx := context.Background()
+	tus, _ := newTestTus(time.Now())
+	blobs := &sizeRecordingBlobs{MemoryBlobStore: NewMemoryBlobStore()}
+	tus.Blobs = blobs
+
+	u, _ := tus.Create(ctx, "owner", 10, nil)
+	assert.NoError(t, tus.Append(ctx, u, 0, strings.NewReader("abc"), 3))
+	assert.NoError(t, tus.Append(ctx, u, 3, strings.NewReader("de"), -1))
+	assert.NoError(t, tus.Append(ctx, u, 5, strings.NewReader("fghijklm"), 8))
+	assert.Equal(t, []int64{3, -1, 5}, blobs.sizes, "chunk sizes are passed on, capped at what is left")
+	assert.True(t, u.Complete())
+}
+
+func TestTusUploads_OwnerAndTermination(t *testing.T) {
+	ctx := context.Background()
+	tus, blobs := newTestTus(time.Now())
+
+	u, _ := tus.Create(ctx, "owner", 5, nil)
+	assert.NoError(t, tus.Append(ctx, u, 0, strings.NewReader("ab"), -1))
+
+	_, err := tus.Get(ctx, u.ID, "someone-else")
+	assert.ErrorIs(t, err, ErrTusNotFound)
+
+	assert.NoError(t, tus.Terminate(ctx, u))
+	assert.Empty(t, blobs.Keys())
+	_, err = tus.Get(ctx, u.ID, "owner")
+	assert.ErrorIs(t, err, ErrTusNotFound)
+}
+
+func TestTusUploads_Expiration(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	tus, blobs := newTestTus(now)
+
+	stale, _ := tus.Create(ctx, "owner", 5, nil)
+	assert.NoError(t, tus.Append(ctx, stale, 0, strings.NewReader("ab"), -1))
+
+	tus.Now = func() time.Time { return now.Add(30 * time.Minute) }
+	fresh, _ := tus.Create(ctx, "owner", 5, nil)
+
+	tus.Now = func() time.Time { return now.Add(61 * time.Minute) }
+	_, err := tus.Get(ctx, stale.ID, "owner")
+	assert.ErrorIs(t, err, ErrTusNotFound)
+
+	n, err := tus.ExpireOnce(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Empty(t, blobs.Keys())
+	_, err = tus.Get(ctx, fresh.ID, "owner")
+	assert.NoError(t, err)
+}
+
+func TestParseTusMetadata(t *testing.T) {
+	meta, err := ParseTusMetadata("filename Y2hhcnQubXA0,is_private")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"filename": "chart.mp4", "is_private": ""}, meta)
+
+	_, err = ParseTusMetadata("filename not-base64!")
+	assert.Error(t, err)
+}